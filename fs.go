@@ -13,6 +13,19 @@ var (
 	ErrCrossedBoundary = errors.New("chroot boundary crossed")
 )
 
+// Common errors returned by the filesystems, they are the same errors as the
+// ones from the os package, so they can be checked with os.IsNotExist and
+// friends as well.
+var (
+	ErrNotExist   = os.ErrNotExist
+	ErrExist      = os.ErrExist
+	ErrPermission = os.ErrPermission
+	// ErrWriteOnly is returned when reading from a file opened with
+	// os.O_WRONLY, wrapped in an *os.PathError along with the file name. It's
+	// os.ErrPermission, so it's matched by os.IsPermission.
+	ErrWriteOnly = os.ErrPermission
+)
+
 // Capability holds the supported features of a billy filesystem. This does
 // not mean that the capability has to be supported by the underlying storage.
 // For example, a billy filesystem may support WriteCapability but the
@@ -32,6 +45,14 @@ const (
 	TruncateCapability
 	// LockCapability is the ability to lock a file.
 	LockCapability
+	// SymlinkCapability means that symlinks can be created and read.
+	SymlinkCapability
+	// ChrootCapability is the ability to change the root of the fs, with
+	// Chroot.
+	ChrootCapability
+	// TempFileCapability is the ability to create temporary files, with
+	// TempFile.
+	TempFileCapability
 
 	// DefaultCapabilities lists all capable features supported by filesystems
 	// without Capability interface. This list should not be changed until a
@@ -43,7 +64,8 @@ const (
 	// AllCapabilities lists all capable features.
 	AllCapabilities Capability = WriteCapability | ReadCapability |
 		ReadAndWriteCapability | SeekCapability | TruncateCapability |
-		LockCapability
+		LockCapability | SymlinkCapability | ChrootCapability |
+		TempFileCapability
 )
 
 // Filesystem abstract the operations in a storage-agnostic interface.
@@ -100,6 +122,113 @@ type TempFile interface {
 	TempFile(dir, prefix string) (File, error)
 }
 
+// TempFileSuffix abstract the creation of temporary files with a given suffix
+// in a storage-agnostic interface. It is optional, filesystems supporting it
+// implement it along with the TempFile interface.
+type TempFileSuffix interface {
+	// TempFileSuffix creates a new temporary file in the directory dir with a
+	// name beginning with prefix and ending with suffix, opens the file for
+	// reading and writing, and returns the resulting File. The same rules of
+	// TempFile apply to dir and to the uniqueness of the name.
+	TempFileSuffix(dir, prefix, suffix string) (File, error)
+}
+
+// AnonTempFile abstract the creation of anonymous temporary files in a
+// storage-agnostic interface. It is optional, not every filesystem supports
+// it.
+type AnonTempFile interface {
+	// AnonTempFile creates a new temporary file, opened for reading and
+	// writing, that is not linked to any path of the filesystem, so it can't
+	// be reached by any other means than the returned File. The content of the
+	// file is discarded once it is closed. The name of the file has no meaning.
+	AnonTempFile() (File, error)
+}
+
+// Exchange abstract the atomic swap of two paths in a storage-agnostic
+// interface. It is optional, not every filesystem supports it.
+type Exchange interface {
+	// Exchange swaps atomically the files, or directories, at the paths a and
+	// b, so a holds what was at b and vice versa. Both paths must exist.
+	Exchange(a, b string) error
+}
+
+// Chmod abstract the change of the mode of the files in a storage-agnostic
+// interface. It is optional, filesystems not implementing the whole Change
+// interface may still implement it.
+type Chmod interface {
+	// Chmod changes the mode of the named file to mode. If the file is a
+	// symbolic link, it changes the mode of the link's target.
+	Chmod(name string, mode os.FileMode) error
+}
+
+// Chtimes abstract the change of the times of the files in a storage-agnostic
+// interface. It is optional, filesystems not implementing the whole Change
+// interface may still implement it.
+type Chtimes interface {
+	// Chtimes changes the access and modification times of the named file,
+	// following symbolic links. The filesystems not keeping the access time
+	// ignore atime.
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// BirthTime abstract the reading of the birth time of the files, the time
+// when they were created, in a storage-agnostic interface. It is optional,
+// for the filesystems not able to provide it through the FileInfo returned by
+// Stat, eg.: since reading it takes an additional call.
+type BirthTime interface {
+	// BirthTime returns the birth time of the named file, following symbolic
+	// links. It returns ErrNotSupported if the birth time isn't known.
+	BirthTime(filename string) (time.Time, error)
+}
+
+// Link abstract the creation of hard links in a storage-agnostic interface.
+// It is optional, not every filesystem supports it.
+type Link interface {
+	// Link creates newname as a hard link to the oldname file, both names
+	// refer to the same content, so the changes done through one are seen
+	// through the other, and removing one keeps the content of the other.
+	Link(oldname, newname string) error
+}
+
+// AccessHint tells how a file is going to be accessed, so the filesystem can
+// optimize the reads, eg.: reading ahead.
+type AccessHint int
+
+const (
+	// NormalHint means that there is no particular access pattern.
+	NormalHint AccessHint = iota
+	// SequentialHint means that the file is going to be read sequentially,
+	// from the start to the end.
+	SequentialHint
+	// RandomHint means that the file is going to be read in random order.
+	RandomHint
+	// WillNeedHint means that the content of the file is going to be read
+	// soon.
+	WillNeedHint
+)
+
+// OpenFileHint abstract the opening of files along with an AccessHint in a
+// storage-agnostic interface. It is optional, util.OpenFileHint falls back
+// to OpenFile when a filesystem doesn't implement it.
+type OpenFileHint interface {
+	// OpenFileHint opens the named file as OpenFile does, advising the
+	// filesystem of how it is going to be accessed. The hint is advisory,
+	// failing to apply it doesn't fail the call.
+	OpenFileHint(filename string, flag int, perm os.FileMode, hint AccessHint) (File, error)
+}
+
+// TempFileTracker abstract the tracking of the temporary files, to remove the
+// ones left behind, eg.: after a crash. It is optional, not every filesystem
+// supports it.
+type TempFileTracker interface {
+	// TempFiles returns the names of the files created through TempFile, and
+	// TempFileSuffix, that still exist.
+	TempFiles() []string
+	// CleanupTempFiles removes the files created through TempFile, and
+	// TempFileSuffix, that still exist, returning the first error found.
+	CleanupTempFiles() error
+}
+
 // Dir abstract the dir related operations in a storage-agnostic interface as
 // an extension to the Basic interface.
 type Dir interface {
@@ -175,6 +304,48 @@ type File interface {
 	Unlock() error
 	// Truncate the file.
 	Truncate(size int64) error
+	// Sync commits the content of the file to stable storage, like fsync. It
+	// is a no-op on the filesystems without stable storage.
+	Sync() error
+}
+
+// BuffersWriter is implemented by the files able to write several buffers in
+// a single operation, eg.: with writev. It is optional, util.WriteBuffers
+// falls back to sequential writes when a file doesn't implement it.
+type BuffersWriter interface {
+	// WriteBuffers writes the content of bufs, in order, at the current
+	// position of the file, returning the number of bytes written.
+	WriteBuffers(bufs [][]byte) (int64, error)
+}
+
+// Aborter is implemented by the files able to discard what was written through
+// them since opened, instead of committing it on Close, as the ones buffering
+// the writes or writing to a temporary file, like the ones from
+// util.CreateAtomic. The files from memfs and osfs don't implement it, their
+// writes are committed as they are done.
+type Aborter interface {
+	// Abort discards what was written and closes the file.
+	Abort() error
+}
+
+// Dirtier is implemented by the files buffering the writes, as the ones of a
+// write-back cache, to tell the ones holding writes not yet persisted to the
+// underlying storage, eg.: to flush only those. The files from memfs and osfs
+// don't implement it, their writes go straight through, so they are never
+// dirty.
+type Dirtier interface {
+	// Dirty returns true if there are writes not yet persisted, until they
+	// are flushed.
+	Dirty() bool
+}
+
+// Grower is implemented by the files able to reserve up front the room for
+// the content about to be written, as the ones from memfs, so writing it
+// sequentially doesn't reallocate the content.
+type Grower interface {
+	// Grow reserves the room to write another n bytes past the end of the
+	// file, without changing its size.
+	Grow(n int)
 }
 
 // Capable interface can return the available features of a filesystem.
@@ -184,14 +355,29 @@ type Capable interface {
 }
 
 // Capabilities returns the features supported by a filesystem. If the FS
-// does not implement Capable interface it returns all features.
+// does not implement Capable interface it returns DefaultCapabilities, along
+// with SymlinkCapability, ChrootCapability and TempFileCapability if it
+// implements the Symlink, Chroot and TempFile interfaces.
 func Capabilities(fs Basic) Capability {
 	capable, ok := fs.(Capable)
-	if !ok {
-		return DefaultCapabilities
+	if ok {
+		return capable.Capabilities()
+	}
+
+	caps := DefaultCapabilities
+	if _, ok := fs.(Symlink); ok {
+		caps |= SymlinkCapability
+	}
+
+	if _, ok := fs.(Chroot); ok {
+		caps |= ChrootCapability
+	}
+
+	if _, ok := fs.(TempFile); ok {
+		caps |= TempFileCapability
 	}
 
-	return capable.Capabilities()
+	return caps
 }
 
 // CapabilityCheck tests the filesystem for the provided capabilities and
@@ -37,4 +37,8 @@ func (s *FSSuite) TestCapabilities(c *C) {
 
 	dummy := new(test.BasicMock)
 	c.Assert(Capabilities(dummy), Equals, DefaultCapabilities)
+
+	symlink := new(test.SymlinkMock)
+	c.Assert(Capabilities(symlink), Equals, DefaultCapabilities|SymlinkCapability)
+	c.Assert(CapabilityCheck(symlink, SymlinkCapability|ChrootCapability), Equals, false)
 }
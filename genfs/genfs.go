@@ -0,0 +1,397 @@
+// Package genfs provides a read-only billy filesystem, where the content of
+// the files is generated on demand.
+package genfs // import "github.com/go-git/go-billy/v5/genfs"
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/helper/chroot"
+)
+
+const separator = filepath.Separator
+
+var (
+	errIsDir   = errors.New("is a directory")
+	errNotDir  = errors.New("not a directory")
+	errNotLink = errors.New("not a link")
+)
+
+// Generator returns the content of a file.
+type Generator func() ([]byte, error)
+
+// Options holds the optional settings of a generated filesystem.
+type Options struct {
+	// Cache keeps the content returned by the generators, so each generator
+	// is called once at most, instead of on every Open and Stat.
+	Cache bool
+	// SizeHints holds the sizes of the files, by path, reported by Stat and
+	// ReadDir. The generator of the files without a hint is called to know
+	// its size.
+	SizeHints map[string]int64
+}
+
+// Gen is a read-only filesystem where the content of each file is returned
+// by a Generator. The directories are implied by the paths of the files.
+type Gen struct {
+	entries  map[string]Generator
+	sizes    map[string]int64
+	children map[string]map[string]bool
+	modTime  time.Time
+
+	cache  bool
+	m      sync.Mutex
+	cached map[string][]byte
+}
+
+// New returns a new read-only filesystem, with a file for each entry of the
+// given map. The content of the file is generated, calling the function of
+// the entry, every time the file is opened or stated.
+func New(entries map[string]func() ([]byte, error)) billy.Filesystem {
+	return NewWithOptions(entries, Options{})
+}
+
+// NewWithOptions returns a new read-only filesystem like New, with the given
+// options.
+func NewWithOptions(entries map[string]func() ([]byte, error), o Options) billy.Filesystem {
+	fs := &Gen{
+		entries:  make(map[string]Generator, len(entries)),
+		sizes:    make(map[string]int64, len(o.SizeHints)),
+		children: map[string]map[string]bool{"": {}},
+		modTime:  time.Now(),
+		cache:    o.Cache,
+		cached:   make(map[string][]byte),
+	}
+
+	for path, g := range entries {
+		path = clean(path)
+		fs.entries[path] = g
+		fs.addParents(path)
+	}
+
+	for path, size := range o.SizeHints {
+		fs.sizes[clean(path)] = size
+	}
+
+	return chroot.New(fs, string(separator))
+}
+
+func (fs *Gen) addParents(path string) {
+	for path != "" {
+		dir := parent(path)
+		if _, ok := fs.children[dir]; !ok {
+			fs.children[dir] = make(map[string]bool)
+		}
+
+		fs.children[dir][filepath.Base(path)] = true
+		path = dir
+	}
+}
+
+func (fs *Gen) Create(filename string) (billy.File, error) {
+	return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Gen) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *Gen) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrReadOnly}
+	}
+
+	path := clean(filename)
+	if _, ok := fs.children[path]; ok {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: errIsDir}
+	}
+
+	content, err := fs.generate(path)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: err}
+	}
+
+	return &file{
+		name:   filename,
+		Reader: bytes.NewReader(content),
+	}, nil
+}
+
+func (fs *Gen) generate(path string) ([]byte, error) {
+	g, ok := fs.entries[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	if !fs.cache {
+		return g()
+	}
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	if content, ok := fs.cached[path]; ok {
+		return content, nil
+	}
+
+	content, err := g()
+	if err != nil {
+		return nil, err
+	}
+
+	fs.cached[path] = content
+	return content, nil
+}
+
+func (fs *Gen) Stat(filename string) (os.FileInfo, error) {
+	path := clean(filename)
+	if _, ok := fs.children[path]; ok {
+		return fs.dirInfo(path), nil
+	}
+
+	fi, err := fs.fileInfo(path)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: filename, Err: err}
+	}
+
+	return fi, nil
+}
+
+func (fs *Gen) dirInfo(path string) os.FileInfo {
+	return &fileInfo{
+		name:    name(path),
+		mode:    os.ModeDir | 0555,
+		modTime: fs.modTime,
+	}
+}
+
+func (fs *Gen) fileInfo(path string) (os.FileInfo, error) {
+	size, ok := fs.sizes[path]
+	if !ok {
+		content, err := fs.generate(path)
+		if err != nil {
+			return nil, err
+		}
+
+		size = int64(len(content))
+	}
+
+	return &fileInfo{
+		name:    name(path),
+		size:    size,
+		mode:    0444,
+		modTime: fs.modTime,
+	}, nil
+}
+
+func (fs *Gen) Lstat(filename string) (os.FileInfo, error) {
+	return fs.Stat(filename)
+}
+
+func (fs *Gen) ReadDir(path string) ([]os.FileInfo, error) {
+	dir := clean(path)
+	children, ok := fs.children[dir]
+	if !ok {
+		err := os.ErrNotExist
+		if _, isFile := fs.entries[dir]; isFile {
+			err = errNotDir
+		}
+
+		return nil, &os.PathError{Op: "readdirent", Path: path, Err: err}
+	}
+
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var entries []os.FileInfo
+	for _, name := range names {
+		child := filepath.Join(dir, name)
+		if _, ok := fs.children[child]; ok {
+			entries = append(entries, fs.dirInfo(child))
+			continue
+		}
+
+		fi, err := fs.fileInfo(child)
+		if err != nil {
+			return nil, &os.PathError{Op: "stat", Path: child, Err: err}
+		}
+
+		entries = append(entries, fi)
+	}
+
+	return entries, nil
+}
+
+func (fs *Gen) Rename(from, to string) error {
+	return &os.LinkError{Op: "rename", Old: from, New: to, Err: billy.ErrReadOnly}
+}
+
+func (fs *Gen) Remove(filename string) error {
+	return &os.PathError{Op: "remove", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Gen) MkdirAll(filename string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Gen) TempFile(dir, prefix string) (billy.File, error) {
+	return nil, &os.PathError{Op: "createtemp", Path: dir, Err: billy.ErrReadOnly}
+}
+
+func (fs *Gen) Symlink(target, link string) error {
+	return &os.LinkError{Op: "symlink", Old: target, New: link, Err: billy.ErrReadOnly}
+}
+
+func (fs *Gen) Readlink(link string) (string, error) {
+	if _, err := fs.Stat(link); err != nil {
+		return "", err
+	}
+
+	return "", &os.PathError{Op: "readlink", Path: link, Err: errNotLink}
+}
+
+func (fs *Gen) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Gen) Capabilities() billy.Capability {
+	return billy.ReadCapability |
+		billy.SeekCapability
+}
+
+type file struct {
+	*bytes.Reader
+	name     string
+	isClosed bool
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.Reader.Read(b)
+}
+
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.Reader.ReadAt(b, off)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.Reader.Seek(offset, whence)
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: billy.ErrReadOnly}
+}
+
+func (f *file) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.name, Err: billy.ErrReadOnly}
+}
+
+// Sync is a no-op, the file being read-only.
+func (f *file) Sync() error {
+	return nil
+}
+
+func (f *file) Close() error {
+	if f.isClosed {
+		return os.ErrClosed
+	}
+
+	f.isClosed = true
+	return nil
+}
+
+// Lock is a no-op in genfs.
+func (f *file) Lock() error {
+	return nil
+}
+
+// Unlock is a no-op in genfs.
+func (f *file) Unlock() error {
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.mode.IsDir()
+}
+
+func (*fileInfo) Sys() interface{} {
+	return nil
+}
+
+// clean returns the path relative to the root, the root being "".
+func clean(path string) string {
+	path = filepath.Clean(filepath.FromSlash(path))
+	path = strings.TrimPrefix(path, string(separator))
+	if path == "." {
+		return ""
+	}
+
+	return path
+}
+
+func parent(path string) string {
+	dir := filepath.Dir(path)
+	if dir == "." {
+		return ""
+	}
+
+	return dir
+}
+
+func name(path string) string {
+	if path == "" {
+		return string(separator)
+	}
+
+	return filepath.Base(path)
+}
@@ -0,0 +1,170 @@
+package genfs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type GenSuite struct {
+	calls map[string]int
+}
+
+var _ = Suite(&GenSuite{})
+
+func (s *GenSuite) SetUpTest(c *C) {
+	s.calls = make(map[string]int)
+}
+
+func (s *GenSuite) entries() map[string]func() ([]byte, error) {
+	gen := func(name, content string) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			s.calls[name]++
+			return []byte(content), nil
+		}
+	}
+
+	return map[string]func() ([]byte, error){
+		"foo":         gen("foo", "foo content"),
+		"qux/bar":     gen("bar", "bar content"),
+		"/qux/baz/qu": gen("qu", "qu content"),
+		"fail": func() ([]byte, error) {
+			return nil, errors.New("generator failure")
+		},
+	}
+}
+
+func (s *GenSuite) TestOpen(c *C) {
+	fs := New(s.entries())
+
+	s.assertContent(c, fs, "foo", "foo content")
+	s.assertContent(c, fs, "foo", "foo content")
+	s.assertContent(c, fs, "/qux/bar", "bar content")
+	s.assertContent(c, fs, "qux/baz/qu", "qu content")
+	c.Assert(s.calls["foo"], Equals, 2)
+
+	_, err := fs.Open("missing")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = fs.Open("fail")
+	c.Assert(err, ErrorMatches, ".*generator failure")
+
+	_, err = fs.Open("qux")
+	c.Assert(err, NotNil)
+}
+
+func (s *GenSuite) TestCache(c *C) {
+	fs := NewWithOptions(s.entries(), Options{Cache: true})
+
+	s.assertContent(c, fs, "foo", "foo content")
+	s.assertContent(c, fs, "foo", "foo content")
+
+	fi, err := fs.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(11))
+	c.Assert(s.calls["foo"], Equals, 1)
+}
+
+func (s *GenSuite) TestStat(c *C) {
+	fs := NewWithOptions(s.entries(), Options{
+		SizeHints: map[string]int64{"qux/bar": 42},
+	})
+
+	fi, err := fs.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "foo")
+	c.Assert(fi.Size(), Equals, int64(11))
+	c.Assert(fi.IsDir(), Equals, false)
+	c.Assert(s.calls["foo"], Equals, 1)
+
+	fi, err = fs.Stat("qux/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(42))
+	c.Assert(s.calls["bar"], Equals, 0)
+
+	fi, err = fs.Stat("qux/baz")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+
+	_, err = fs.Stat("qux/missing")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *GenSuite) TestReadDir(c *C) {
+	fs := New(s.entries())
+
+	fis, err := fs.ReadDir("qux")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 2)
+	c.Assert(fis[0].Name(), Equals, "bar")
+	c.Assert(fis[0].Size(), Equals, int64(11))
+	c.Assert(fis[1].Name(), Equals, "baz")
+	c.Assert(fis[1].IsDir(), Equals, true)
+
+	fis, err = fs.ReadDir("qux/baz")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 1)
+
+	_, err = fs.ReadDir("missing")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = fs.ReadDir("foo")
+	c.Assert(err, NotNil)
+}
+
+func (s *GenSuite) TestChroot(c *C) {
+	fs, err := New(s.entries()).Chroot("qux")
+	c.Assert(err, IsNil)
+
+	s.assertContent(c, fs, "baz/qu", "qu content")
+}
+
+func (s *GenSuite) TestReadOnly(c *C) {
+	fs := New(s.entries())
+
+	_, err := fs.Create("new")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	_, err = fs.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = util.WriteFile(fs, "foo", []byte("bar"), 0644)
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = fs.Rename("foo", "bar")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = fs.Remove("foo")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = fs.MkdirAll("dir", 0755)
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = fs.Symlink("foo", "link")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	f, err := fs.Open("foo")
+	c.Assert(err, IsNil)
+
+	_, err = f.Write([]byte("bar"))
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *GenSuite) assertContent(c *C, fs billy.Filesystem, filename, expected string) {
+	f, err := fs.Open(filename)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, expected)
+	c.Assert(f.Close(), IsNil)
+}
@@ -0,0 +1,424 @@
+// Package gitfs provides a read-only billy filesystem presenting the content of
+// a git tree, eg.: the one of a commit, without checking it out.
+package gitfs // import "github.com/go-git/go-billy/v5/gitfs"
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/helper/chroot"
+)
+
+const separator = filepath.Separator
+
+// maxSymlinks is the number of symlinks followed resolving a path before
+// giving up, as on Linux.
+const maxSymlinks = 40
+
+var (
+	errIsDir        = errors.New("is a directory")
+	errNotDir       = errors.New("not a directory")
+	errNotLink      = errors.New("not a link")
+	errTooManyLinks = errors.New("too many levels of symbolic links")
+)
+
+// TreeEntry is an entry of a git tree.
+type TreeEntry struct {
+	// Name is the name of the entry in the tree.
+	Name string
+	// Mode is os.ModeDir for a tree, os.ModeSymlink for a blob holding the
+	// target of a symlink, or the permission bits of a regular blob, as 0644
+	// or 0755.
+	Mode os.FileMode
+	// Hash identifies the tree or the blob of the entry in the TreeSource.
+	Hash string
+	// Size is the size of the blob, or -1 if unknown, then the blob is read
+	// to know it.
+	Size int64
+}
+
+// TreeSource gives access to the trees and the blobs of a git repository.
+type TreeSource interface {
+	// ReadTree returns the entries of the tree with the given hash.
+	ReadTree(hash string) ([]TreeEntry, error)
+	// ReadBlob returns the content of the blob with the given hash.
+	ReadBlob(hash string) ([]byte, error)
+}
+
+// Git is a read-only filesystem presenting the content of a git tree, the
+// trees are the directories and the blobs the files, or the symlinks.
+type Git struct {
+	src     TreeSource
+	root    string
+	modTime time.Time
+}
+
+// New returns a new read-only filesystem with the content of the tree with
+// the given hash, read from src on demand.
+func New(src TreeSource, root string) billy.Filesystem {
+	fs := &Git{
+		src:     src,
+		root:    root,
+		modTime: time.Now(),
+	}
+
+	return chroot.New(fs, string(separator))
+}
+
+func (fs *Git) Create(filename string) (billy.File, error) {
+	return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Git) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *Git) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrReadOnly}
+	}
+
+	e, err := fs.resolve(filename, true)
+	if err == nil && e.Mode.IsDir() {
+		err = errIsDir
+	}
+
+	var content []byte
+	if err == nil {
+		content, err = fs.src.ReadBlob(e.Hash)
+	}
+
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: err}
+	}
+
+	return &file{
+		name:   filename,
+		Reader: bytes.NewReader(content),
+	}, nil
+}
+
+func (fs *Git) Stat(filename string) (os.FileInfo, error) {
+	return fs.stat("stat", filename, true)
+}
+
+func (fs *Git) Lstat(filename string) (os.FileInfo, error) {
+	return fs.stat("lstat", filename, false)
+}
+
+func (fs *Git) stat(op, filename string, follow bool) (os.FileInfo, error) {
+	e, err := fs.resolve(filename, follow)
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: filename, Err: err}
+	}
+
+	e.Name = name(clean(filename))
+	fi, err := fs.fileInfo(e)
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: filename, Err: err}
+	}
+
+	return fi, nil
+}
+
+func (fs *Git) ReadDir(path string) ([]os.FileInfo, error) {
+	e, err := fs.resolve(path, true)
+	if err == nil && !e.Mode.IsDir() {
+		err = errNotDir
+	}
+
+	var entries []TreeEntry
+	if err == nil {
+		entries, err = fs.src.ReadTree(e.Hash)
+	}
+
+	if err != nil {
+		return nil, &os.PathError{Op: "readdirent", Path: path, Err: err}
+	}
+
+	fis := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fi, err := fs.fileInfo(e)
+		if err != nil {
+			return nil, &os.PathError{Op: "stat", Path: fs.Join(path, e.Name), Err: err}
+		}
+
+		fis = append(fis, fi)
+	}
+
+	sort.Slice(fis, func(i, j int) bool {
+		return fis[i].Name() < fis[j].Name()
+	})
+
+	return fis, nil
+}
+
+func (fs *Git) fileInfo(e TreeEntry) (os.FileInfo, error) {
+	fi := &fileInfo{
+		name:    e.Name,
+		size:    e.Size,
+		mode:    e.Mode,
+		modTime: fs.modTime,
+	}
+
+	if e.Mode.IsDir() {
+		fi.mode, fi.size = os.ModeDir|0555, 0
+		return fi, nil
+	}
+
+	if fi.size < 0 {
+		content, err := fs.src.ReadBlob(e.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		fi.size = int64(len(content))
+	}
+
+	return fi, nil
+}
+
+func (fs *Git) Readlink(link string) (string, error) {
+	e, err := fs.resolve(link, false)
+	if err == nil && e.Mode&os.ModeSymlink == 0 {
+		err = errNotLink
+	}
+
+	var target []byte
+	if err == nil {
+		target, err = fs.src.ReadBlob(e.Hash)
+	}
+
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: link, Err: err}
+	}
+
+	return string(target), nil
+}
+
+// resolve returns the entry at the given path, following the symlinks found
+// along it, and the last element too if follow is true. The absolute targets
+// are resolved from the root of the tree.
+func (fs *Git) resolve(path string, follow bool) (TreeEntry, error) {
+	stack := []TreeEntry{{Mode: os.ModeDir, Hash: fs.root}}
+	pending := splitPath(path)
+	for links := 0; len(pending) > 0; {
+		elem := pending[0]
+		pending = pending[1:]
+
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+
+			continue
+		}
+
+		dir := stack[len(stack)-1]
+		if !dir.Mode.IsDir() {
+			return TreeEntry{}, errNotDir
+		}
+
+		e, err := fs.child(dir, elem)
+		if err != nil {
+			return TreeEntry{}, err
+		}
+
+		if e.Mode&os.ModeSymlink == 0 || (len(pending) == 0 && !follow) {
+			stack = append(stack, e)
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return TreeEntry{}, errTooManyLinks
+		}
+
+		target, err := fs.src.ReadBlob(e.Hash)
+		if err != nil {
+			return TreeEntry{}, err
+		}
+
+		t := filepath.FromSlash(string(target))
+		if strings.HasPrefix(t, string(separator)) {
+			stack = stack[:1]
+		}
+
+		pending = append(splitPath(t), pending...)
+	}
+
+	return stack[len(stack)-1], nil
+}
+
+func (fs *Git) child(dir TreeEntry, name string) (TreeEntry, error) {
+	entries, err := fs.src.ReadTree(dir.Hash)
+	if err != nil {
+		return TreeEntry{}, err
+	}
+
+	for _, e := range entries {
+		if e.Name == name {
+			return e, nil
+		}
+	}
+
+	return TreeEntry{}, os.ErrNotExist
+}
+
+func (fs *Git) Rename(from, to string) error {
+	return &os.LinkError{Op: "rename", Old: from, New: to, Err: billy.ErrReadOnly}
+}
+
+func (fs *Git) Remove(filename string) error {
+	return &os.PathError{Op: "remove", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Git) MkdirAll(filename string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Git) TempFile(dir, prefix string) (billy.File, error) {
+	return nil, &os.PathError{Op: "createtemp", Path: dir, Err: billy.ErrReadOnly}
+}
+
+func (fs *Git) Symlink(target, link string) error {
+	return &os.LinkError{Op: "symlink", Old: target, New: link, Err: billy.ErrReadOnly}
+}
+
+func (fs *Git) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Git) Capabilities() billy.Capability {
+	return billy.ReadCapability |
+		billy.SeekCapability
+}
+
+type file struct {
+	*bytes.Reader
+	name     string
+	isClosed bool
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.Reader.Read(b)
+}
+
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.Reader.ReadAt(b, off)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.Reader.Seek(offset, whence)
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: billy.ErrReadOnly}
+}
+
+func (f *file) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.name, Err: billy.ErrReadOnly}
+}
+
+// Sync is a no-op, the file being read-only.
+func (f *file) Sync() error {
+	return nil
+}
+
+func (f *file) Close() error {
+	if f.isClosed {
+		return os.ErrClosed
+	}
+
+	f.isClosed = true
+	return nil
+}
+
+// Lock is a no-op in gitfs.
+func (f *file) Lock() error {
+	return nil
+}
+
+// Unlock is a no-op in gitfs.
+func (f *file) Unlock() error {
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.mode.IsDir()
+}
+
+func (*fileInfo) Sys() interface{} {
+	return nil
+}
+
+// clean returns the path relative to the root, the root being "".
+func clean(path string) string {
+	path = filepath.Clean(filepath.FromSlash(path))
+	path = strings.TrimPrefix(path, string(separator))
+	if path == "." {
+		return ""
+	}
+
+	return path
+}
+
+func name(path string) string {
+	if path == "" {
+		return string(separator)
+	}
+
+	return filepath.Base(path)
+}
+
+func splitPath(path string) []string {
+	return strings.Split(filepath.FromSlash(path), string(separator))
+}
@@ -0,0 +1,168 @@
+package gitfs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type GitSuite struct {
+	FS billy.Filesystem
+}
+
+var _ = Suite(&GitSuite{})
+
+func (s *GitSuite) SetUpTest(c *C) {
+	s.FS = New(&source{
+		trees: map[string][]TreeEntry{
+			"root": {
+				{Name: "README", Mode: 0644, Hash: "readme", Size: -1},
+				{Name: "cmd", Mode: os.ModeDir, Hash: "cmd"},
+				{Name: "link", Mode: os.ModeSymlink, Hash: "link", Size: 11},
+				{Name: "dirlink", Mode: os.ModeSymlink, Hash: "dirlink", Size: 3},
+			},
+			"cmd": {
+				{Name: "main.go", Mode: 0755, Hash: "main", Size: 12},
+				{Name: "abs", Mode: os.ModeSymlink, Hash: "abs", Size: 7},
+			},
+		},
+		blobs: map[string]string{
+			"readme":  "hello world",
+			"main":    "package main",
+			"link":    "cmd/main.go",
+			"dirlink": "cmd",
+			"abs":     "/README",
+		},
+	}, "root")
+}
+
+func (s *GitSuite) TestOpen(c *C) {
+	s.assertContent(c, "README", "hello world")
+	s.assertContent(c, "/cmd/main.go", "package main")
+	s.assertContent(c, "link", "package main")
+	s.assertContent(c, "dirlink/main.go", "package main")
+	s.assertContent(c, "cmd/abs", "hello world")
+	s.assertContent(c, "cmd/../README", "hello world")
+
+	_, err := s.FS.Open("missing")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = s.FS.Open("README/foo")
+	c.Assert(err, NotNil)
+
+	_, err = s.FS.Open("cmd")
+	c.Assert(err, NotNil)
+}
+
+func (s *GitSuite) TestStat(c *C) {
+	fi, err := s.FS.Stat("README")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "README")
+	c.Assert(fi.Size(), Equals, int64(11))
+	c.Assert(fi.Mode(), Equals, os.FileMode(0644))
+
+	fi, err = s.FS.Stat("link")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "link")
+	c.Assert(fi.Mode(), Equals, os.FileMode(0755))
+
+	fi, err = s.FS.Lstat("link")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Mode()&os.ModeSymlink, Not(Equals), os.FileMode(0))
+
+	fi, err = s.FS.Stat("dirlink")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+
+	fi, err = s.FS.Stat("/")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+}
+
+func (s *GitSuite) TestReadDir(c *C) {
+	fis, err := s.FS.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 4)
+	c.Assert(fis[0].Name(), Equals, "README")
+	c.Assert(fis[1].Name(), Equals, "cmd")
+	c.Assert(fis[1].IsDir(), Equals, true)
+	c.Assert(fis[3].Name(), Equals, "link")
+	c.Assert(fis[3].Mode()&os.ModeSymlink, Not(Equals), os.FileMode(0))
+
+	fis, err = s.FS.ReadDir("dirlink")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 2)
+
+	_, err = s.FS.ReadDir("README")
+	c.Assert(err, NotNil)
+}
+
+func (s *GitSuite) TestReadlink(c *C) {
+	target, err := s.FS.Readlink("link")
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "cmd/main.go")
+
+	_, err = s.FS.Readlink("README")
+	c.Assert(err, NotNil)
+}
+
+func (s *GitSuite) TestReadOnly(c *C) {
+	_, err := s.FS.Create("new")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = util.WriteFile(s.FS, "README", []byte("bar"), 0644)
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = s.FS.Rename("README", "bar")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = s.FS.Remove("README")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = s.FS.MkdirAll("dir", 0755)
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	err = s.FS.Symlink("README", "new")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+}
+
+func (s *GitSuite) assertContent(c *C, filename, expected string) {
+	f, err := s.FS.Open(filename)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, expected)
+	c.Assert(f.Close(), IsNil)
+}
+
+type source struct {
+	trees map[string][]TreeEntry
+	blobs map[string]string
+}
+
+func (s *source) ReadTree(hash string) ([]TreeEntry, error) {
+	entries, ok := s.trees[hash]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return entries, nil
+}
+
+func (s *source) ReadBlob(hash string) ([]byte, error) {
+	content, ok := s.blobs[hash]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return []byte(content), nil
+}
@@ -0,0 +1,194 @@
+package auditfs
+
+import (
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// AuditEntry is the record of an operation done over the filesystem.
+type AuditEntry struct {
+	// Op is the name of the operation, as the name of the method called,
+	// eg.: "Open", "Rename" or "Close".
+	Op string
+	// Path is the path given to the operation, the name of the file for the
+	// operations done over a file.
+	Path string
+	// Target is the second path given to the operation if any, the new path
+	// on Rename and the target on Symlink.
+	Target string
+	// Err is the error returned by the operation, if any.
+	Err error
+	// BytesRead and BytesWritten are the number of bytes read and written
+	// through a file, they are only reported on Close.
+	BytesRead    int64
+	BytesWritten int64
+}
+
+// Audit is a helper that reports every operation done over the underlying
+// filesystem, and over the files opened from it, to an audit function.
+type Audit struct {
+	billy.Filesystem
+	audit func(AuditEntry)
+}
+
+// New creates a new filesystem wrapping up 'fs', every operation is reported
+// to the given audit function once done. A panic on the audit function is
+// recovered, so it never breaks the operation being audited.
+func New(fs billy.Filesystem, audit func(AuditEntry)) billy.Filesystem {
+	return &Audit{Filesystem: fs, audit: audit}
+}
+
+func (fs *Audit) Create(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Create(filename)
+	fs.record(AuditEntry{Op: "Create", Path: filename, Err: err})
+	return fs.wrapFile(f, err)
+}
+
+func (fs *Audit) Open(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Open(filename)
+	fs.record(AuditEntry{Op: "Open", Path: filename, Err: err})
+	return fs.wrapFile(f, err)
+}
+
+func (fs *Audit) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	fs.record(AuditEntry{Op: "OpenFile", Path: filename, Err: err})
+	return fs.wrapFile(f, err)
+}
+
+func (fs *Audit) TempFile(dir, prefix string) (billy.File, error) {
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+
+	path := dir
+	if err == nil {
+		path = f.Name()
+	}
+
+	fs.record(AuditEntry{Op: "TempFile", Path: path, Err: err})
+	return fs.wrapFile(f, err)
+}
+
+func (fs *Audit) Stat(filename string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Stat(filename)
+	fs.record(AuditEntry{Op: "Stat", Path: filename, Err: err})
+	return fi, err
+}
+
+func (fs *Audit) Lstat(filename string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Lstat(filename)
+	fs.record(AuditEntry{Op: "Lstat", Path: filename, Err: err})
+	return fi, err
+}
+
+func (fs *Audit) Rename(from, to string) error {
+	err := fs.Filesystem.Rename(from, to)
+	fs.record(AuditEntry{Op: "Rename", Path: from, Target: to, Err: err})
+	return err
+}
+
+func (fs *Audit) Remove(filename string) error {
+	err := fs.Filesystem.Remove(filename)
+	fs.record(AuditEntry{Op: "Remove", Path: filename, Err: err})
+	return err
+}
+
+func (fs *Audit) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.Filesystem.ReadDir(path)
+	fs.record(AuditEntry{Op: "ReadDir", Path: path, Err: err})
+	return fis, err
+}
+
+func (fs *Audit) MkdirAll(filename string, perm os.FileMode) error {
+	err := fs.Filesystem.MkdirAll(filename, perm)
+	fs.record(AuditEntry{Op: "MkdirAll", Path: filename, Err: err})
+	return err
+}
+
+func (fs *Audit) Symlink(target, link string) error {
+	err := fs.Filesystem.Symlink(target, link)
+	fs.record(AuditEntry{Op: "Symlink", Path: link, Target: target, Err: err})
+	return err
+}
+
+func (fs *Audit) Readlink(link string) (string, error) {
+	target, err := fs.Filesystem.Readlink(link)
+	fs.record(AuditEntry{Op: "Readlink", Path: link, Err: err})
+	return target, err
+}
+
+func (fs *Audit) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	fs.record(AuditEntry{Op: "Chroot", Path: path, Err: err})
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot, fs.audit), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Audit) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+func (fs *Audit) record(e AuditEntry) {
+	defer func() {
+		_ = recover()
+	}()
+
+	fs.audit(e)
+}
+
+func (fs *Audit) wrapFile(f billy.File, err error) (billy.File, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return &file{File: f, fs: fs}, nil
+}
+
+type file struct {
+	billy.File
+	fs *Audit
+
+	read    int64
+	written int64
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.read += int64(n)
+	return n, err
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(p, off)
+	f.read += int64(n)
+	return n, err
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.written += int64(n)
+	return n, err
+}
+
+func (f *file) Truncate(size int64) error {
+	err := f.File.Truncate(size)
+	f.fs.record(AuditEntry{Op: "Truncate", Path: f.Name(), Err: err})
+	return err
+}
+
+func (f *file) Close() error {
+	err := f.File.Close()
+	f.fs.record(AuditEntry{
+		Op:           "Close",
+		Path:         f.Name(),
+		Err:          err,
+		BytesRead:    f.read,
+		BytesWritten: f.written,
+	})
+
+	return err
+}
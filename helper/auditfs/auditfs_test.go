@@ -0,0 +1,65 @@
+package auditfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&AuditSuite{})
+
+type AuditSuite struct {
+	test.FilesystemSuite
+	entries []AuditEntry
+}
+
+func (s *AuditSuite) SetUpTest(c *C) {
+	s.entries = nil
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New(), func(e AuditEntry) {
+		s.entries = append(s.entries, e)
+	}))
+}
+
+func (s *AuditSuite) TestEntries(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.Open("foo")
+	c.Assert(err, IsNil)
+	_, err = ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(s.FS.Rename("foo", "bar"), IsNil)
+	_, err = s.FS.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	c.Assert(s.entries, DeepEquals, []AuditEntry{
+		{Op: "OpenFile", Path: "foo"},
+		{Op: "Close", Path: "foo", BytesWritten: 3},
+		{Op: "Open", Path: "foo"},
+		{Op: "Close", Path: "foo", BytesRead: 3},
+		{Op: "Rename", Path: "foo", Target: "bar"},
+		{Op: "Stat", Path: "foo", Err: os.ErrNotExist},
+	})
+}
+
+func (s *AuditSuite) TestAuditPanic(c *C) {
+	fs := New(memfs.New(), func(AuditEntry) {
+		panic("audit failure")
+	})
+
+	err := util.WriteFile(fs, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = fs.Stat("foo")
+	c.Assert(err, IsNil)
+}
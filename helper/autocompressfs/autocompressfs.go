@@ -0,0 +1,661 @@
+package autocompressfs
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// suffix is the one of the names of the compressed files in the underlying
+// filesystem.
+const suffix = ".gz"
+
+var (
+	errReserved  = errors.New("names ending with " + suffix + " are reserved")
+	errNotDir    = errors.New("not a directory")
+	errIsDir     = errors.New("is a directory")
+	errNegative  = errors.New("negative offset")
+	errWriteRead = errors.New("write not supported on a file opened read-only")
+)
+
+// AutoCompress is a helper that stores the files of the underlying filesystem
+// larger than a minimum size compressed with gzip, eg.: to save disk with big
+// text logs. A file named "foo" is stored as "foo.gz" once compressed, and as
+// it is otherwise, so the names ending with ".gz" are reserved for the files.
+//
+// The files are presented decompressed: Stat and ReadDir report their size
+// once decompressed, the one recorded by gzip, which is known only modulo
+// 4GiB. The files opened read-only are decompressed as read, seeking backwards
+// decompressing them again from the start. The files opened for writing are
+// decompressed at once, and compressed again once the last handle writing
+// them is closed, if larger than the minimum size. Symlinks are not supported.
+type AutoCompress struct {
+	billy.Filesystem
+	minSize int64
+
+	m       sync.Mutex
+	writers map[string]int
+}
+
+// New creates a new filesystem wrapping up 'fs', storing the files larger than
+// minSize bytes compressed.
+func New(fs billy.Filesystem, minSize int64) billy.Filesystem {
+	return &AutoCompress{Filesystem: fs, minSize: minSize, writers: make(map[string]int)}
+}
+
+func (fs *AutoCompress) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *AutoCompress) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file, a compressed one opened for writing is
+// decompressed first.
+func (fs *AutoCompress) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if fs.reserved(filename) {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: errReserved}
+	}
+
+	if _, err := fs.Filesystem.Lstat(filename); os.IsNotExist(err) && fs.isCompressed(filename) {
+		return fs.openCompressed(filename, flag)
+	}
+
+	if flag&os.O_CREATE != 0 && fs.hasFileParent(filename) {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: errNotDir}
+	}
+
+	return fs.openPlain(filename, flag, perm)
+}
+
+func (fs *AutoCompress) openCompressed(filename string, flag int) (billy.File, error) {
+	if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrExist}
+	}
+
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return fs.openReader(filename)
+	}
+
+	if err := fs.decompress(filename, flag&os.O_TRUNC != 0); err != nil {
+		return nil, err
+	}
+
+	return fs.openPlain(filename, flag, 0)
+}
+
+func (fs *AutoCompress) openPlain(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return f, nil
+	}
+
+	fs.m.Lock()
+	fs.writers[filepath.Clean(filename)]++
+	fs.m.Unlock()
+
+	return &file{File: f, fs: fs, filename: filename}, nil
+}
+
+func (fs *AutoCompress) openReader(filename string) (billy.File, error) {
+	size, err := fs.size(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fs.Filesystem.Open(filename + suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reader{File: f, name: strings.TrimSuffix(f.Name(), suffix), size: size}
+	if err := r.rewind(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (fs *AutoCompress) Stat(filename string) (os.FileInfo, error) {
+	return fs.stat(filename, fs.Filesystem.Stat)
+}
+
+func (fs *AutoCompress) Lstat(filename string) (os.FileInfo, error) {
+	return fs.stat(filename, fs.Filesystem.Lstat)
+}
+
+func (fs *AutoCompress) stat(filename string, stat func(string) (os.FileInfo, error)) (os.FileInfo, error) {
+	fi, err := stat(filename)
+	if err == nil && (fi.IsDir() || !strings.HasSuffix(filename, suffix)) {
+		return fi, nil
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	fi, err = fs.Filesystem.Stat(filename + suffix)
+	if err == nil && fi.IsDir() || os.IsNotExist(err) || strings.HasSuffix(filename, suffix) {
+		return nil, &os.PathError{Op: "stat", Path: filename, Err: os.ErrNotExist}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := fs.size(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileInfo{FileInfo: fi, name: filepath.Base(filename), size: size}, nil
+}
+
+// ReadDir reads the directory, presenting the compressed files with their
+// name and size once decompressed.
+func (fs *AutoCompress) ReadDir(path string) ([]os.FileInfo, error) {
+	entries, err := fs.Filesystem.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		plain[e.Name()] = true
+	}
+
+	var fis []os.FileInfo
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), suffix) {
+			fis = append(fis, e)
+			continue
+		}
+
+		name := strings.TrimSuffix(e.Name(), suffix)
+		if plain[name] {
+			continue
+		}
+
+		size, err := fs.size(fs.Join(path, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		fis = append(fis, &fileInfo{FileInfo: e, name: name, size: size})
+	}
+
+	sort.Slice(fis, func(i, j int) bool {
+		return fis[i].Name() < fis[j].Name()
+	})
+
+	return fis, nil
+}
+
+// Rename renames the file, compressed or not, replacing the file at to if
+// any. Directories are renamed as they are.
+func (fs *AutoCompress) Rename(from, to string) error {
+	if fs.reserved(from) || fs.reserved(to) {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: errReserved}
+	}
+
+	fi, err := fs.Filesystem.Lstat(from)
+	if err == nil {
+		if err := fs.Filesystem.Rename(from, to); err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			return nil
+		}
+
+		return fs.removeCompressed(to)
+	}
+
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	if !fs.isCompressed(from) {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrNotExist}
+	}
+
+	if fi, err := fs.Filesystem.Lstat(to); err == nil {
+		if fi.IsDir() {
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: errIsDir}
+		}
+
+		if err := fs.Filesystem.Remove(to); err != nil {
+			return err
+		}
+	}
+
+	return fs.Filesystem.Rename(from+suffix, to+suffix)
+}
+
+// Remove removes the file, compressed or not. Directories are removed as they
+// are.
+func (fs *AutoCompress) Remove(filename string) error {
+	if fs.reserved(filename) {
+		return &os.PathError{Op: "remove", Path: filename, Err: os.ErrNotExist}
+	}
+
+	fi, err := fs.Filesystem.Lstat(filename)
+	if err == nil {
+		if err := fs.Filesystem.Remove(filename); err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			return nil
+		}
+
+		return fs.removeCompressed(filename)
+	}
+
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	if !fs.isCompressed(filename) {
+		return &os.PathError{Op: "remove", Path: filename, Err: os.ErrNotExist}
+	}
+
+	return fs.Filesystem.Remove(filename + suffix)
+}
+
+// MkdirAll creates the directory and its parents, failing if any of them is a
+// compressed file.
+func (fs *AutoCompress) MkdirAll(filename string, perm os.FileMode) error {
+	if fs.isCompressed(filename) || fs.hasFileParent(filename) {
+		return &os.PathError{Op: "mkdir", Path: filename, Err: os.ErrExist}
+	}
+
+	return fs.Filesystem.MkdirAll(filename, perm)
+}
+
+func (fs *AutoCompress) TempFile(dir, prefix string) (billy.File, error) {
+	return util.TempFile(fs, dir, prefix)
+}
+
+// Symlink returns billy.ErrNotSupported, a link to a compressed file would
+// point to nothing.
+func (fs *AutoCompress) Symlink(target, link string) error {
+	return billy.ErrNotSupported
+}
+
+func (fs *AutoCompress) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot, fs.minSize), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *AutoCompress) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// reserved returns true if filename names a file ending with the suffix of
+// the compressed files, the directories may have any name.
+func (fs *AutoCompress) reserved(filename string) bool {
+	if !strings.HasSuffix(filename, suffix) {
+		return false
+	}
+
+	fi, err := fs.Filesystem.Lstat(filename)
+	return err != nil || !fi.IsDir()
+}
+
+// isCompressed returns true if filename is stored compressed.
+func (fs *AutoCompress) isCompressed(filename string) bool {
+	fi, err := fs.Filesystem.Lstat(filename + suffix)
+	return err == nil && !fi.IsDir()
+}
+
+// hasFileParent returns true if any of the parents of filename is a
+// compressed file.
+func (fs *AutoCompress) hasFileParent(filename string) bool {
+	for dir := filepath.Dir(filepath.Clean(filename)); ; dir = filepath.Dir(dir) {
+		if dir == "." || dir == string(filepath.Separator) {
+			return false
+		}
+
+		if fs.isCompressed(dir) {
+			return true
+		}
+	}
+}
+
+// size returns the size of the compressed file once decompressed, as recorded
+// by gzip at the end of it.
+func (fs *AutoCompress) size(filename string) (int64, error) {
+	f, err := fs.Filesystem.Open(filename + suffix)
+	if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	var trailer [4]byte
+	if _, err := f.Seek(-int64(len(trailer)), io.SeekEnd); err != nil {
+		return 0, err
+	}
+
+	if _, err := io.ReadFull(f, trailer[:]); err != nil {
+		return 0, err
+	}
+
+	return int64(binary.LittleEndian.Uint32(trailer[:])), nil
+}
+
+// compress compresses the file if larger than the minimum size, the plain
+// file being removed once the compressed one is written, so a crash leaves
+// it as it was.
+func (fs *AutoCompress) compress(filename string) error {
+	fi, err := fs.Filesystem.Lstat(filename)
+	if err != nil {
+		return err
+	}
+
+	if fi.Size() <= fs.minSize || !fi.Mode().IsRegular() {
+		return fs.removeCompressed(filename)
+	}
+
+	src, err := fs.Filesystem.Open(filename)
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	dst, err := fs.Filesystem.OpenFile(filename+suffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err1 := zw.Close(); err == nil {
+		err = err1
+	}
+
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Remove(filename)
+}
+
+// decompress writes the compressed file as a plain one, empty if truncate is
+// true, removing the compressed one once written. While both exist, the plain
+// one wins.
+func (fs *AutoCompress) decompress(filename string, truncate bool) error {
+	fi, err := fs.Filesystem.Lstat(filename + suffix)
+	if err != nil {
+		return err
+	}
+
+	dst, err := fs.Filesystem.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if !truncate {
+		err = fs.copyDecompressed(dst, filename)
+	}
+
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+
+	if err != nil {
+		_ = fs.Filesystem.Remove(filename)
+		return err
+	}
+
+	return fs.Filesystem.Remove(filename + suffix)
+}
+
+func (fs *AutoCompress) copyDecompressed(dst io.Writer, filename string) error {
+	src, err := fs.Filesystem.Open(filename + suffix)
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	zr, err := gzip.NewReader(src)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, zr)
+	if err1 := zr.Close(); err == nil {
+		err = err1
+	}
+
+	return err
+}
+
+// removeCompressed removes the compressed copy of filename, if any.
+func (fs *AutoCompress) removeCompressed(filename string) error {
+	if !fs.isCompressed(filename) {
+		return nil
+	}
+
+	return fs.Filesystem.Remove(filename + suffix)
+}
+
+// file is a file opened for writing, compressed when closed.
+type file struct {
+	billy.File
+	fs       *AutoCompress
+	filename string
+}
+
+// Write writes p to the file, failing with io.ErrShortWrite if the underlying
+// file writes less than len(p) bytes without an error.
+func (f *file) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+
+	return n, err
+}
+
+// Close closes the file, compressing it if larger than the minimum size and
+// no other handle is writing it.
+func (f *file) Close() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+
+	f.fs.m.Lock()
+	defer f.fs.m.Unlock()
+
+	name := filepath.Clean(f.filename)
+	if f.fs.writers[name]--; f.fs.writers[name] != 0 {
+		return nil
+	}
+
+	delete(f.fs.writers, name)
+	return f.fs.compress(f.filename)
+}
+
+// reader is a compressed file opened read-only, decompressed as read.
+type reader struct {
+	billy.File
+	name string
+	size int64
+
+	m        sync.Mutex
+	zr       *gzip.Reader
+	zpos     int64
+	position int64
+	isClosed bool
+}
+
+func (r *reader) Name() string {
+	return r.name
+}
+
+func (r *reader) Read(b []byte) (int, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	n, err := r.readAt(b, r.position)
+	r.position += int64(n)
+	return n, err
+}
+
+func (r *reader) ReadAt(b []byte, off int64) (int, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var n int
+	for n < len(b) {
+		m, err := r.readAt(b[n:], off+int64(n))
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// readAt reads from off, moving the decompression there first, from the
+// start if off is behind it.
+func (r *reader) readAt(b []byte, off int64) (int, error) {
+	if r.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	if off < 0 {
+		return 0, &os.PathError{Op: "read", Path: r.name, Err: errNegative}
+	}
+
+	if off < r.zpos {
+		if err := r.rewind(); err != nil {
+			return 0, err
+		}
+	}
+
+	if off > r.zpos {
+		n, err := io.CopyN(ioutil.Discard, r.zr, off-r.zpos)
+		r.zpos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.zr.Read(b)
+	r.zpos += int64(n)
+	return n, err
+}
+
+// rewind starts decompressing the file again from the start.
+func (r *reader) rewind() error {
+	if _, err := r.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var err error
+	if r.zr == nil {
+		r.zr, err = gzip.NewReader(r.File)
+	} else {
+		err = r.zr.Reset(r.File)
+	}
+
+	r.zpos = 0
+	return err
+}
+
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.position
+	case io.SeekEnd:
+		offset += r.size
+	case io.SeekStart:
+	default:
+		return r.position, &os.PathError{Op: "seek", Path: r.name, Err: os.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return r.position, &os.PathError{Op: "seek", Path: r.name, Err: errNegative}
+	}
+
+	r.position = offset
+	return r.position, nil
+}
+
+func (r *reader) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: r.name, Err: errWriteRead}
+}
+
+func (r *reader) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: r.name, Err: errWriteRead}
+}
+
+func (r *reader) Close() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.isClosed {
+		return os.ErrClosed
+	}
+
+	r.isClosed = true
+	return r.File.Close()
+}
+
+// fileInfo is the FileInfo of a compressed file, with its name and size once
+// decompressed.
+type fileInfo struct {
+	os.FileInfo
+	name string
+	size int64
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
@@ -0,0 +1,192 @@
+package autocompressfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&AutoCompressSuite{})
+
+type AutoCompressSuite struct {
+	test.BasicSuite
+	test.DirSuite
+	test.TempFileSuite
+	test.ChrootSuite
+
+	fs, underlying billy.Filesystem
+}
+
+func (s *AutoCompressSuite) SetUpTest(c *C) {
+	s.underlying = memfs.New()
+	s.fs = New(s.underlying, 8)
+	s.BasicSuite.FS = s.fs
+	s.DirSuite.FS = s.fs
+	s.TempFileSuite.FS = s.fs
+	s.ChrootSuite.FS = s.fs
+}
+
+const content = "0123456789abcdefghij"
+
+func (s *AutoCompressSuite) TestCompressed(c *C) {
+	c.Assert(util.WriteFile(s.fs, "foo/bar", []byte(content), 0644), IsNil)
+	c.Assert(util.WriteFile(s.fs, "foo/qux", []byte("small"), 0644), IsNil)
+
+	_, err := s.underlying.Stat("foo/bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	c.Assert(s.decompress(c, "foo/bar.gz"), Equals, content)
+
+	data, err := util.ReadFileString(s.underlying, "foo/qux")
+	c.Assert(err, IsNil)
+	c.Assert(data, Equals, "small")
+
+	data, err = util.ReadFileString(s.fs, "foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(data, Equals, content)
+
+	fi, err := s.fs.Stat("foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "bar")
+	c.Assert(fi.Size(), Equals, int64(len(content)))
+
+	entries, err := s.fs.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].Name(), Equals, "bar")
+	c.Assert(entries[0].Size(), Equals, int64(len(content)))
+	c.Assert(entries[1].Name(), Equals, "qux")
+	c.Assert(entries[1].Size(), Equals, int64(5))
+}
+
+func (s *AutoCompressSuite) TestSeekCompressed(c *C) {
+	c.Assert(util.WriteFile(s.fs, "foo", []byte(content), 0644), IsNil)
+
+	f, err := s.fs.Open("foo")
+	c.Assert(err, IsNil)
+	c.Assert(f.Name(), Equals, "foo")
+
+	buf := make([]byte, 4)
+	_, err = f.Seek(10, io.SeekStart)
+	c.Assert(err, IsNil)
+	_, err = io.ReadFull(f, buf)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf), Equals, "abcd")
+
+	_, err = f.Seek(-8, io.SeekCurrent)
+	c.Assert(err, IsNil)
+	_, err = io.ReadFull(f, buf)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf), Equals, "6789")
+
+	_, err = f.ReadAt(buf, 2)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf), Equals, "2345")
+
+	n, err := f.ReadAt(buf, 18)
+	c.Assert(err, Equals, io.EOF)
+	c.Assert(string(buf[:n]), Equals, "ij")
+
+	_, err = f.Seek(-2, io.SeekEnd)
+	c.Assert(err, IsNil)
+	rest, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(rest), Equals, "ij")
+
+	_, err = f.Write([]byte("foo"))
+	c.Assert(err, NotNil)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *AutoCompressSuite) TestAppendCompressed(c *C) {
+	c.Assert(util.WriteFile(s.fs, "foo", []byte(content), 0644), IsNil)
+
+	f, err := s.fs.OpenFile("foo", os.O_WRONLY|os.O_APPEND, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("klm"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	_, err = s.underlying.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	c.Assert(s.decompress(c, "foo.gz"), Equals, content+"klm")
+}
+
+func (s *AutoCompressSuite) TestShrinkCompressed(c *C) {
+	c.Assert(util.WriteFile(s.fs, "foo", []byte(content), 0644), IsNil)
+
+	f, err := s.fs.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(4), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	_, err = s.underlying.Stat("foo.gz")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	data, err := util.ReadFileString(s.underlying, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(data, Equals, "0123")
+}
+
+func (s *AutoCompressSuite) TestRenameRemoveCompressed(c *C) {
+	c.Assert(util.WriteFile(s.fs, "foo", []byte(content), 0644), IsNil)
+	c.Assert(util.WriteFile(s.fs, "bar", []byte("small"), 0644), IsNil)
+
+	c.Assert(s.fs.Rename("foo", "bar"), IsNil)
+
+	_, err := s.fs.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = s.underlying.Stat("bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	data, err := util.ReadFileString(s.fs, "bar")
+	c.Assert(err, IsNil)
+	c.Assert(data, Equals, content)
+
+	c.Assert(s.fs.Remove("bar"), IsNil)
+
+	entries, err := s.underlying.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+}
+
+func (s *AutoCompressSuite) TestReservedName(c *C) {
+	c.Assert(util.WriteFile(s.fs, "foo", []byte(content), 0644), IsNil)
+
+	_, err := s.fs.Create("bar.gz")
+	c.Assert(err, NotNil)
+
+	_, err = s.fs.Stat("foo.gz")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	c.Assert(s.fs.MkdirAll("qux.gz", 0755), IsNil)
+	fi, err := s.fs.Stat("qux.gz")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+}
+
+func (s *AutoCompressSuite) decompress(c *C, filename string) string {
+	data, err := util.ReadFile(s.underlying, filename)
+	c.Assert(err, IsNil)
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	var buf strings.Builder
+	_, err = io.Copy(&buf, zr)
+	c.Assert(err, IsNil)
+
+	return buf.String()
+}
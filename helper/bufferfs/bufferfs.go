@@ -0,0 +1,158 @@
+package bufferfs
+
+import (
+	"bufio"
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// DefaultSize is the size of the buffer of each file used by New.
+const DefaultSize = 64 * 1024
+
+// Buffer is a helper that buffers the writes to the files of the underlying
+// filesystem, writing them in blocks, eg.: in front of a backend where the
+// small writes are slow.
+//
+// The writes are flushed when the buffer is full, on Flush, Sync and Close,
+// and before any other operation on the same file, so they are seen by its
+// reads. Until then, they aren't seen by other files nor by Stat. The files
+// implement billy.Dirtier, telling if they hold writes not yet flushed.
+type Buffer struct {
+	billy.Filesystem
+	size int
+}
+
+// New creates a new filesystem wrapping up 'fs', buffering up to DefaultSize
+// bytes for each file opened for writing.
+func New(fs billy.Filesystem) billy.Filesystem {
+	return NewWithSize(fs, DefaultSize)
+}
+
+// NewWithSize creates a new filesystem wrapping up 'fs', buffering up to size
+// bytes for each file opened for writing.
+func NewWithSize(fs billy.Filesystem, size int) billy.Filesystem {
+	return &Buffer{Filesystem: fs, size: size}
+}
+
+func (fs *Buffer) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *Buffer) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return f, nil
+	}
+
+	return fs.wrapFile(f), nil
+}
+
+func (fs *Buffer) TempFile(dir, prefix string) (billy.File, error) {
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.wrapFile(f), nil
+}
+
+func (fs *Buffer) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithSize(chroot, fs.size), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Buffer) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+func (fs *Buffer) wrapFile(f billy.File) billy.File {
+	return &file{File: f, w: bufio.NewWriterSize(f, fs.size)}
+}
+
+type file struct {
+	billy.File
+	w        *bufio.Writer
+	isClosed bool
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.w.Write(p)
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if err := f.Flush(); err != nil {
+		return 0, err
+	}
+
+	return f.File.Read(p)
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.Flush(); err != nil {
+		return 0, err
+	}
+
+	return f.File.ReadAt(p, off)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if err := f.Flush(); err != nil {
+		return 0, err
+	}
+
+	return f.File.Seek(offset, whence)
+}
+
+func (f *file) Truncate(size int64) error {
+	if err := f.Flush(); err != nil {
+		return err
+	}
+
+	return f.File.Truncate(size)
+}
+
+// Dirty implements the billy.Dirtier interface, it returns true if there are
+// writes not yet flushed to the underlying file.
+func (f *file) Dirty() bool {
+	return f.w.Buffered() != 0
+}
+
+// Flush writes the buffered writes to the underlying file.
+func (f *file) Flush() error {
+	return f.w.Flush()
+}
+
+// Sync flushes the buffered writes, and commits them to stable storage.
+func (f *file) Sync() error {
+	if err := f.Flush(); err != nil {
+		return err
+	}
+
+	return f.File.Sync()
+}
+
+// Close flushes the buffered writes and closes the file, the file is closed
+// even if the writes fail.
+func (f *file) Close() error {
+	f.isClosed = true
+	err := f.Flush()
+	if err1 := f.File.Close(); err == nil {
+		err = err1
+	}
+
+	return err
+}
@@ -0,0 +1,85 @@
+package bufferfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&BufferSuite{})
+
+type BufferSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *BufferSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(NewWithSize(memfs.New(), 4))
+}
+
+// TestTruncateSharedAcrossHandles reads what was written through another file,
+// not yet flushed.
+func (s *BufferSuite) TestTruncateSharedAcrossHandles(c *C) {
+	c.Skip("the writes aren't seen by other files until flushed")
+}
+
+func (s *BufferSuite) TestDirty(c *C) {
+	underlying := memfs.New()
+	fs := NewWithSize(underlying, 8)
+
+	f, err := fs.Create("foo")
+	c.Assert(err, IsNil)
+	c.Assert(f.(billy.Dirtier).Dirty(), Equals, false)
+
+	_, err = f.Write([]byte("foo"))
+	c.Assert(err, IsNil)
+	c.Assert(f.(billy.Dirtier).Dirty(), Equals, true)
+
+	fi, err := underlying.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(0))
+
+	c.Assert(f.(interface{ Sync() error }).Sync(), IsNil)
+	c.Assert(f.(billy.Dirtier).Dirty(), Equals, false)
+
+	fi, err = underlying.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(3))
+
+	_, err = f.Write([]byte("bar"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	content, err := util.ReadFileString(underlying, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foobar")
+
+	f, err = fs.Open("foo")
+	c.Assert(err, IsNil)
+	_, ok := f.(billy.Dirtier)
+	c.Assert(ok, Equals, false)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *BufferSuite) TestReadFlushes(c *C) {
+	fs := NewWithSize(memfs.New(), 8)
+
+	f, err := fs.OpenFile("foo", os.O_RDWR|os.O_CREATE, 0644)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("foo"))
+	c.Assert(err, IsNil)
+
+	buf := make([]byte, 3)
+	n, err := f.ReadAt(buf, 0)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf[:n]), Equals, "foo")
+	c.Assert(f.(billy.Dirtier).Dirty(), Equals, false)
+	c.Assert(f.Close(), IsNil)
+}
@@ -0,0 +1,236 @@
+package casefoldfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+const separator = string(filepath.Separator)
+
+// ErrAmbiguous is returned when a name matches, regardless of the case,
+// several entries of a directory, none of them matching exactly.
+var ErrAmbiguous = errors.New("ambiguous name, matching several entries regardless of the case")
+
+// CaseFold is a helper that presents a case-insensitive view over a
+// case-sensitive filesystem, eg.: to serve the files of a Linux store to
+// Windows clients. The names are looked up regardless of the case, matching
+// them with the entries of their directory, so "FOO/bar" opens "foo/Bar". The
+// new files and directories are created with the case given.
+//
+// A name matching exactly an entry opens it, otherwise if it matches several
+// entries differing only by case, the lookup fails with an *os.PathError
+// wrapping ErrAmbiguous.
+type CaseFold struct {
+	billy.Filesystem
+}
+
+// New creates a new filesystem wrapping up the given 'fs', looking up the
+// names regardless of the case.
+func New(fs billy.Filesystem) billy.Filesystem {
+	return &CaseFold{Filesystem: fs}
+}
+
+func (fs *CaseFold) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *CaseFold) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *CaseFold) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	p, err := fs.resolve("open", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.OpenFile(p, flag, perm)
+}
+
+func (fs *CaseFold) Stat(filename string) (os.FileInfo, error) {
+	p, err := fs.resolve("stat", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Stat(p)
+}
+
+func (fs *CaseFold) Lstat(filename string) (os.FileInfo, error) {
+	p, err := fs.resolve("lstat", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Lstat(p)
+}
+
+// Rename renames from to to, replacing the entry matching to regardless of
+// the case, if any. A name can be renamed to itself with a different case.
+func (fs *CaseFold) Rename(from, to string) error {
+	f, err := fs.resolve("rename", from)
+	if err != nil {
+		return err
+	}
+
+	t, err := fs.resolve("rename", to)
+	if err != nil {
+		return err
+	}
+
+	if t == f {
+		t = fs.Filesystem.Join(filepath.Dir(f), filepath.Base(filepath.FromSlash(to)))
+	}
+
+	return fs.Filesystem.Rename(f, t)
+}
+
+func (fs *CaseFold) Remove(filename string) error {
+	p, err := fs.resolve("remove", filename)
+	if err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Remove(p)
+}
+
+func (fs *CaseFold) TempFile(dir, prefix string) (billy.File, error) {
+	p, err := fs.resolve("tempfile", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.TempFile(p, prefix)
+}
+
+func (fs *CaseFold) ReadDir(path string) ([]os.FileInfo, error) {
+	p, err := fs.resolve("readdir", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.ReadDir(p)
+}
+
+func (fs *CaseFold) MkdirAll(filename string, perm os.FileMode) error {
+	p, err := fs.resolve("mkdir", filename)
+	if err != nil {
+		return err
+	}
+
+	return fs.Filesystem.MkdirAll(p, perm)
+}
+
+// Symlink resolves only the link, the target is stored as given.
+func (fs *CaseFold) Symlink(target, link string) error {
+	p, err := fs.resolve("symlink", link)
+	if err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Symlink(target, p)
+}
+
+func (fs *CaseFold) Readlink(link string) (string, error) {
+	p, err := fs.resolve("readlink", link)
+	if err != nil {
+		return "", err
+	}
+
+	return fs.Filesystem.Readlink(p)
+}
+
+func (fs *CaseFold) Chroot(path string) (billy.Filesystem, error) {
+	p, err := fs.resolve("chroot", path)
+	if err != nil {
+		return nil, err
+	}
+
+	chroot, err := fs.Filesystem.Chroot(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *CaseFold) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// resolve returns the given path with the case of the existing entries it
+// matches, the elements not found are kept as given. The ".." elements are
+// left to the underlying filesystem.
+func (fs *CaseFold) resolve(op, filename string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(filename))
+
+	resolved := ""
+	if strings.HasPrefix(clean, separator) {
+		resolved = separator
+	}
+
+	missing := false
+	for _, elem := range strings.Split(clean, separator) {
+		if elem == "" || elem == "." {
+			continue
+		}
+
+		if elem != ".." && !missing {
+			name, found, err := fs.match(resolved, elem)
+			if err != nil {
+				return "", &os.PathError{Op: op, Path: filename, Err: err}
+			}
+
+			elem, missing = name, !found
+		}
+
+		resolved = fs.Filesystem.Join(resolved, elem)
+	}
+
+	if resolved == "" {
+		return separator, nil
+	}
+
+	return resolved, nil
+}
+
+// match returns the name of the entry of dir matching name, exactly or else
+// regardless of the case, and if any was found.
+func (fs *CaseFold) match(dir, name string) (string, bool, error) {
+	if _, err := fs.Filesystem.Lstat(fs.Filesystem.Join(dir, name)); err == nil {
+		return name, true, nil
+	}
+
+	if dir == "" {
+		dir = separator
+	}
+
+	entries, err := fs.Filesystem.ReadDir(dir)
+	if err != nil {
+		return name, false, nil
+	}
+
+	var match string
+	for _, fi := range entries {
+		if !strings.EqualFold(fi.Name(), name) {
+			continue
+		}
+
+		if match != "" {
+			return "", false, ErrAmbiguous
+		}
+
+		match = fi.Name()
+	}
+
+	if match == "" {
+		return name, false, nil
+	}
+
+	return match, true, nil
+}
@@ -0,0 +1,86 @@
+package casefoldfs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&CaseFoldSuite{})
+
+type CaseFoldSuite struct {
+	test.FilesystemSuite
+	underlying billy.Filesystem
+}
+
+func (s *CaseFoldSuite) SetUpTest(c *C) {
+	s.underlying = memfs.New()
+	s.FilesystemSuite = test.NewFilesystemSuite(New(s.underlying))
+}
+
+func (s *CaseFoldSuite) TestLookup(c *C) {
+	err := util.WriteFile(s.underlying, "Foo/Bar", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	fi, err := s.FS.Stat("FOO/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "Bar")
+
+	content, err := util.ReadFileString(s.FS, "foo/BAR")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foo")
+}
+
+func (s *CaseFoldSuite) TestCreateKeepsCase(c *C) {
+	c.Assert(s.FS.MkdirAll("Foo", 0755), IsNil)
+	c.Assert(util.WriteFile(s.FS, "FOO/BarQux", []byte("foo"), 0644), IsNil)
+
+	_, err := s.underlying.Stat("Foo/BarQux")
+	c.Assert(err, IsNil)
+}
+
+func (s *CaseFoldSuite) TestCreateExisting(c *C) {
+	c.Assert(util.WriteFile(s.FS, "foo", []byte("foo"), 0644), IsNil)
+	c.Assert(util.WriteFile(s.FS, "FOO", []byte("bar"), 0644), IsNil)
+
+	fis, err := s.underlying.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 1)
+	c.Assert(fis[0].Name(), Equals, "foo")
+
+	content, err := util.ReadFileString(s.underlying, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "bar")
+}
+
+func (s *CaseFoldSuite) TestAmbiguous(c *C) {
+	c.Assert(util.WriteFile(s.underlying, "foo/bar", nil, 0644), IsNil)
+	c.Assert(util.WriteFile(s.underlying, "FOO/bar", nil, 0644), IsNil)
+
+	_, err := s.FS.Stat("Foo/bar")
+	c.Assert(errors.Is(err, ErrAmbiguous), Equals, true)
+
+	_, err = s.FS.Create("Foo/qux")
+	c.Assert(errors.Is(err, ErrAmbiguous), Equals, true)
+
+	_, err = s.FS.Stat("FOO/bar")
+	c.Assert(err, IsNil)
+}
+
+func (s *CaseFoldSuite) TestRenameCase(c *C) {
+	c.Assert(util.WriteFile(s.FS, "foo", []byte("foo"), 0644), IsNil)
+	c.Assert(s.FS.Rename("foo", "Foo"), IsNil)
+
+	fis, err := s.underlying.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 1)
+	c.Assert(fis[0].Name(), Equals, "Foo")
+}
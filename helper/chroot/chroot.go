@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/helper/polyfill"
@@ -17,8 +18,12 @@ type ChrootHelper struct {
 
 // New creates a new filesystem wrapping up the given 'fs'.
 // The created filesystem has its base in the given ChrootHelperectory of the
-// underlying filesystem.
+// underlying filesystem. The base is cleaned, so Root returns it normalized.
 func New(fs billy.Basic, base string) billy.Filesystem {
+	if base != "" {
+		base = filepath.Clean(base)
+	}
+
 	return &ChrootHelper{
 		underlying: polyfill.New(fs),
 		base:       base,
@@ -34,10 +39,9 @@ func (fs *ChrootHelper) underlyingPath(filename string) (string, error) {
 }
 
 func isCrossBoundaries(path string) bool {
-	path = filepath.ToSlash(path)
-	path = filepath.Clean(path)
+	path = filepath.Clean(filepath.FromSlash(path))
 
-	return strings.HasPrefix(path, ".."+string(filepath.Separator))
+	return path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator))
 }
 
 func (fs *ChrootHelper) Create(filename string) (billy.File, error) {
@@ -82,6 +86,27 @@ func (fs *ChrootHelper) OpenFile(filename string, flag int, mode os.FileMode) (b
 	return newFile(fs, f, filename), nil
 }
 
+// OpenFileHint implements the billy.OpenFileHint interface, it returns
+// billy.ErrNotSupported if the underlying filesystem doesn't implement it.
+func (fs *ChrootHelper) OpenFileHint(filename string, flag int, mode os.FileMode, hint billy.AccessHint) (billy.File, error) {
+	h, ok := fs.unwrapped().(billy.OpenFileHint)
+	if !ok {
+		return nil, billy.ErrNotSupported
+	}
+
+	fullpath, err := fs.underlyingPath(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := h.OpenFileHint(fullpath, flag, mode, hint)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFile(fs, f, filename), nil
+}
+
 func (fs *ChrootHelper) Stat(filename string) (os.FileInfo, error) {
 	fullpath, err := fs.underlyingPath(filename)
 	if err != nil {
@@ -106,6 +131,98 @@ func (fs *ChrootHelper) Rename(from, to string) error {
 	return fs.underlying.Rename(from, to)
 }
 
+// Exchange implements the billy.Exchange interface, it returns
+// billy.ErrNotSupported if the underlying filesystem doesn't implement it.
+func (fs *ChrootHelper) Exchange(a, b string) error {
+	e, ok := fs.unwrapped().(billy.Exchange)
+	if !ok {
+		return billy.ErrNotSupported
+	}
+
+	var err error
+	a, err = fs.underlyingPath(a)
+	if err != nil {
+		return err
+	}
+
+	b, err = fs.underlyingPath(b)
+	if err != nil {
+		return err
+	}
+
+	return e.Exchange(a, b)
+}
+
+// Chmod implements the billy.Chmod interface, it returns
+// billy.ErrNotSupported if the underlying filesystem doesn't implement it.
+func (fs *ChrootHelper) Chmod(name string, mode os.FileMode) error {
+	c, ok := fs.unwrapped().(billy.Chmod)
+	if !ok {
+		return billy.ErrNotSupported
+	}
+
+	fullpath, err := fs.underlyingPath(name)
+	if err != nil {
+		return err
+	}
+
+	return c.Chmod(fullpath, mode)
+}
+
+// Chtimes implements the billy.Chtimes interface, it returns
+// billy.ErrNotSupported if the underlying filesystem doesn't implement it.
+func (fs *ChrootHelper) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	c, ok := fs.unwrapped().(billy.Chtimes)
+	if !ok {
+		return billy.ErrNotSupported
+	}
+
+	fullpath, err := fs.underlyingPath(name)
+	if err != nil {
+		return err
+	}
+
+	return c.Chtimes(fullpath, atime, mtime)
+}
+
+// BirthTime implements the billy.BirthTime interface, it returns
+// billy.ErrNotSupported if the underlying filesystem doesn't implement it.
+func (fs *ChrootHelper) BirthTime(filename string) (time.Time, error) {
+	b, ok := fs.unwrapped().(billy.BirthTime)
+	if !ok {
+		return time.Time{}, billy.ErrNotSupported
+	}
+
+	fullpath, err := fs.underlyingPath(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return b.BirthTime(fullpath)
+}
+
+// Link implements the billy.Link interface, it returns billy.ErrNotSupported
+// if the underlying filesystem doesn't implement it.
+func (fs *ChrootHelper) Link(oldname, newname string) error {
+	l, ok := fs.unwrapped().(billy.Link)
+	if !ok {
+		return billy.ErrNotSupported
+	}
+
+	var err error
+	oldname, err = fs.underlyingPath(oldname)
+	if err != nil {
+		return err
+	}
+
+	newname, err = fs.underlyingPath(newname)
+	if err != nil {
+		return err
+	}
+
+	return l.Link(oldname, newname)
+}
+
 func (fs *ChrootHelper) Remove(path string) error {
 	fullpath, err := fs.underlyingPath(path)
 	if err != nil {
@@ -130,7 +247,110 @@ func (fs *ChrootHelper) TempFile(dir, prefix string) (billy.File, error) {
 		return nil, err
 	}
 
-	return newFile(fs, f, fs.Join(dir, filepath.Base(f.Name()))), nil
+	return newFile(fs, f, fs.tempFileName(dir, f.Name())), nil
+}
+
+// tempFileName returns the name of a temp file, created at dir, relative to
+// the root, based on the name returned by the underlying filesystem.
+func (fs *ChrootHelper) tempFileName(dir, underlyingName string) string {
+	name, err := filepath.Rel(fs.Root(), underlyingName)
+	if err != nil || isCrossBoundaries(name) || name == ".." {
+		return fs.Join(dir, filepath.Base(underlyingName))
+	}
+
+	return name
+}
+
+// TempFiles implements the billy.TempFileTracker interface, it returns the
+// temporary files tracked by the underlying filesystem found inside the
+// chroot, relative to it, or none if the underlying filesystem doesn't
+// implement it.
+func (fs *ChrootHelper) TempFiles() []string {
+	t, ok := fs.tempFileTracker()
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for _, name := range t.TempFiles() {
+		rel, err := filepath.Rel(fs.Root(), name)
+		if err != nil || isCrossBoundaries(rel) || rel == ".." {
+			continue
+		}
+
+		names = append(names, rel)
+	}
+
+	return names
+}
+
+// CleanupTempFiles implements the billy.TempFileTracker interface, it removes
+// the temporary files found inside the chroot, as returned by TempFiles. It
+// returns billy.ErrNotSupported if the underlying filesystem doesn't
+// implement it.
+func (fs *ChrootHelper) CleanupTempFiles() error {
+	if _, ok := fs.tempFileTracker(); !ok {
+		return billy.ErrNotSupported
+	}
+
+	var firstErr error
+	for _, name := range fs.TempFiles() {
+		if err := fs.Remove(name); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// tempFileTracker returns the underlying filesystem as a
+// billy.TempFileTracker, if it implements it.
+func (fs *ChrootHelper) tempFileTracker() (billy.TempFileTracker, bool) {
+	t, ok := fs.unwrapped().(billy.TempFileTracker)
+	return t, ok
+}
+
+// unwrapped returns the filesystem given to New, looking through the
+// polyfill, which doesn't implement the optional interfaces, to tell the ones
+// implemented by the filesystem.
+func (fs *ChrootHelper) unwrapped() billy.Basic {
+	if p, ok := fs.underlying.(*polyfill.Polyfill); ok {
+		return p.Underlying()
+	}
+
+	return fs.underlying
+}
+
+// TempFileSuffix implements the billy.TempFileSuffix interface, it returns
+// billy.ErrNotSupported if the underlying filesystem doesn't implement it.
+func (fs *ChrootHelper) TempFileSuffix(dir, prefix, suffix string) (billy.File, error) {
+	t, ok := fs.unwrapped().(billy.TempFileSuffix)
+	if !ok {
+		return nil, billy.ErrNotSupported
+	}
+
+	fullpath, err := fs.underlyingPath(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := t.TempFileSuffix(fullpath, prefix, suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFile(fs, f, fs.tempFileName(dir, f.Name())), nil
+}
+
+// AnonTempFile implements the billy.AnonTempFile interface, it returns
+// billy.ErrNotSupported if the underlying filesystem doesn't implement it.
+func (fs *ChrootHelper) AnonTempFile() (billy.File, error) {
+	t, ok := fs.unwrapped().(billy.AnonTempFile)
+	if !ok {
+		return nil, billy.ErrNotSupported
+	}
+
+	return t.AnonTempFile()
 }
 
 func (fs *ChrootHelper) ReadDir(path string) ([]os.FileInfo, error) {
@@ -200,6 +420,9 @@ func (fs *ChrootHelper) Readlink(link string) (string, error) {
 	return string(os.PathSeparator) + target, nil
 }
 
+// Chroot returns a new chroot at the given path, over the same underlying
+// filesystem, so a chroot of a chroot isn't nested but collapsed into one with
+// the joined base.
 func (fs *ChrootHelper) Chroot(path string) (billy.Filesystem, error) {
 	fullpath, err := fs.underlyingPath(path)
 	if err != nil {
@@ -209,17 +432,21 @@ func (fs *ChrootHelper) Chroot(path string) (billy.Filesystem, error) {
 	return New(fs.underlying, fullpath), nil
 }
 
+// Root returns the base of the chroot in the underlying filesystem, cleaned.
 func (fs *ChrootHelper) Root() string {
 	return fs.base
 }
 
+// Underlying returns the filesystem wrapped by the chroot, eg.: to check its
+// capabilities. Its paths aren't bound to the base of the chroot, so it's
+// meant for trusted code, already holding the chroot.
 func (fs *ChrootHelper) Underlying() billy.Basic {
 	return fs.underlying
 }
 
 // Capabilities implements the Capable interface.
 func (fs *ChrootHelper) Capabilities() billy.Capability {
-	return billy.Capabilities(fs.underlying)
+	return billy.Capabilities(fs.underlying) | billy.ChrootCapability
 }
 
 type file struct {
@@ -240,3 +467,16 @@ func newFile(fs billy.Filesystem, f billy.File, filename string) billy.File {
 func (f *file) Name() string {
 	return f.name
 }
+
+// Underlying returns the file from the underlying filesystem.
+func (f *file) Underlying() billy.File {
+	return f.File
+}
+
+// Grow implements the billy.Grower interface, forwarding the call to the
+// underlying file. It's a no-op if the underlying file doesn't implement it.
+func (f *file) Grow(n int) {
+	if g, ok := f.File.(billy.Grower); ok {
+		g.Grow(n)
+	}
+}
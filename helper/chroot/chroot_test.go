@@ -37,6 +37,19 @@ func (s *ChrootSuite) TestCreateErrCrossedBoundary(c *C) {
 	c.Assert(err, Equals, billy.ErrCrossedBoundary)
 }
 
+func (s *ChrootSuite) TestRootAndUnderlying(c *C) {
+	m := &test.BasicMock{}
+
+	fs := New(m, "/foo/").(*ChrootHelper)
+	c.Assert(fs.Root(), Equals, "/foo")
+
+	_, err := fs.Underlying().Create("../bar")
+	c.Assert(err, IsNil)
+
+	c.Assert(m.CreateArgs, HasLen, 1)
+	c.Assert(m.CreateArgs[0], Equals, "../bar")
+}
+
 func (s *ChrootSuite) TestLeadingPeriodsPathNotCrossedBoundary(c *C) {
 	m := &test.BasicMock{}
 
@@ -170,8 +183,9 @@ func (s *ChrootSuite) TestTempFile(c *C) {
 	m := &test.TempFileMock{}
 
 	fs := New(m, "/foo")
-	_, err := fs.TempFile("bar", "qux")
+	f, err := fs.TempFile("bar", "qux")
 	c.Assert(err, IsNil)
+	c.Assert(f.Name(), Equals, filepath.Join("bar", "quxtemp"))
 
 	c.Assert(m.TempFileArgs, HasLen, 1)
 	c.Assert(m.TempFileArgs[0], Equals, [2]string{"/foo/bar", "qux"})
@@ -193,6 +207,36 @@ func (s *ChrootSuite) TestTempFileWithBasic(c *C) {
 	c.Assert(err, Equals, billy.ErrNotSupported)
 }
 
+// chmodMock is a billy.Basic implementing billy.Chmod, polyfilled by New.
+type chmodMock struct {
+	test.BasicMock
+	ChmodArgs []string
+}
+
+func (fs *chmodMock) Chmod(name string, mode os.FileMode) error {
+	fs.ChmodArgs = append(fs.ChmodArgs, name)
+	return nil
+}
+
+func (s *ChrootSuite) TestChmod(c *C) {
+	m := &chmodMock{}
+
+	fs := New(m, "/foo")
+	c.Assert(fs.(billy.Chmod).Chmod("bar", 0600), IsNil)
+	c.Assert(m.ChmodArgs, DeepEquals, []string{"/foo/bar"})
+
+	err := fs.(billy.Exchange).Exchange("bar", "qux")
+	c.Assert(err, Equals, billy.ErrNotSupported)
+}
+
+func (s *ChrootSuite) TestChmodWithBasic(c *C) {
+	m := &test.BasicMock{}
+
+	fs := New(m, "/foo")
+	err := fs.(billy.Chmod).Chmod("bar", 0600)
+	c.Assert(err, Equals, billy.ErrNotSupported)
+}
+
 func (s *ChrootSuite) TestReadDir(c *C) {
 	m := &test.DirMock{}
 
@@ -364,5 +408,6 @@ func testCapabilities(c *C, basic billy.Basic) {
 	fs := New(basic, "/foo")
 	capabilities := billy.Capabilities(fs)
 
-	c.Assert(capabilities, Equals, baseCapabilities)
+	// The chroot helper is able to chroot whatever the underlying is.
+	c.Assert(capabilities, Equals, baseCapabilities|billy.ChrootCapability)
 }
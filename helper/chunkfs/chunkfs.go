@@ -0,0 +1,650 @@
+package chunkfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+var (
+	errIsDir     = errors.New("is a directory")
+	errNegative  = errors.New("negative offset")
+	errWriteRead = errors.New("write not supported on a file opened read-only")
+	errBadSize   = errors.New("chunk size must be positive")
+)
+
+// Chunk is a helper that stores every file of the underlying filesystem split
+// in chunks of a fixed size, eg.: over an object store limiting the size of
+// the objects. A file named "foo" is stored as "foo.000", "foo.001", and so
+// on, every chunk but the last one holding exactly the chunk size.
+//
+// The files are presented as a whole: reading, writing, seeking and truncating
+// them cross the chunks boundaries, and Stat and ReadDir report the size of
+// the whole file. The directories are stored as they are. A file is made of
+// several chunks, so renaming or removing it is not atomic. Symlinks are not
+// supported.
+type Chunk struct {
+	billy.Filesystem
+	size int64
+}
+
+// New creates a new filesystem wrapping up 'fs', storing the files in chunks
+// of up to size bytes. It panics if size isn't positive.
+func New(fs billy.Filesystem, size int64) billy.Filesystem {
+	if size <= 0 {
+		panic(errBadSize)
+	}
+
+	return &Chunk{Filesystem: fs, size: size}
+}
+
+func (fs *Chunk) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *Chunk) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *Chunk) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if fi, err := fs.Filesystem.Stat(filename); err == nil && fi.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: errIsDir}
+	}
+
+	var first billy.File
+	var err error
+	if flag&os.O_CREATE != 0 {
+		first, err = fs.Filesystem.OpenFile(chunkName(filename, 0), os.O_WRONLY|os.O_CREATE|flag&os.O_EXCL, perm)
+	} else {
+		first, err = fs.Filesystem.Open(chunkName(filename, 0))
+	}
+
+	if os.IsNotExist(err) {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the name of the first chunk is the one given by the underlying
+	// filesystem, eg.: relative to its root, the chunks are named after it.
+	name := strings.TrimSuffix(first.Name(), chunkName("", 0))
+	if err := first.Close(); err != nil {
+		return nil, err
+	}
+
+	fi, err := fs.Filesystem.Stat(chunkName(name, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	f := &file{fs: fs, name: name, flag: flag, perm: fi.Mode().Perm()}
+	if flag&os.O_TRUNC != 0 {
+		if err := fs.truncate(name, 0, f.perm); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func (fs *Chunk) Stat(filename string) (os.FileInfo, error) {
+	return fs.stat(filename, fs.Filesystem.Stat)
+}
+
+func (fs *Chunk) Lstat(filename string) (os.FileInfo, error) {
+	return fs.stat(filename, fs.Filesystem.Lstat)
+}
+
+func (fs *Chunk) stat(filename string, stat func(string) (os.FileInfo, error)) (os.FileInfo, error) {
+	fi, err := fs.fileInfo(filename)
+	if err == nil || !os.IsNotExist(err) {
+		return fi, err
+	}
+
+	fi, err = stat(filename)
+	if err == nil && fi.IsDir() || err != nil && !os.IsNotExist(err) {
+		return fi, err
+	}
+
+	return nil, &os.PathError{Op: "stat", Path: filename, Err: os.ErrNotExist}
+}
+
+// fileInfo returns the FileInfo of the whole file, made of all its chunks.
+func (fs *Chunk) fileInfo(filename string) (os.FileInfo, error) {
+	chunks, err := fs.chunks(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := &fileInfo{name: filepath.Base(filename), mode: chunks[0].Mode()}
+	for _, c := range chunks {
+		fi.size += c.Size()
+		if c.ModTime().After(fi.modTime) {
+			fi.modTime = c.ModTime()
+		}
+	}
+
+	return fi, nil
+}
+
+// chunks returns the FileInfo of the chunks of the file, in order.
+func (fs *Chunk) chunks(filename string) ([]os.FileInfo, error) {
+	var chunks []os.FileInfo
+	for i := 0; ; i++ {
+		fi, err := fs.Filesystem.Stat(chunkName(filename, i))
+		if os.IsNotExist(err) && i > 0 {
+			return chunks, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		chunks = append(chunks, fi)
+	}
+}
+
+func (fs *Chunk) ReadDir(path string) ([]os.FileInfo, error) {
+	entries, err := fs.Filesystem.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fis []os.FileInfo
+	for _, e := range entries {
+		if e.IsDir() {
+			fis = append(fis, e)
+			continue
+		}
+
+		name, i, ok := parseChunkName(e.Name())
+		if !ok || i != 0 {
+			continue
+		}
+
+		fi, err := fs.fileInfo(fs.Join(path, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		fis = append(fis, fi)
+	}
+
+	sort.Slice(fis, func(i, j int) bool {
+		return fis[i].Name() < fis[j].Name()
+	})
+
+	return fis, nil
+}
+
+// Rename renames the file chunk by chunk, replacing the file at to if any.
+// Directories are renamed at once.
+func (fs *Chunk) Rename(from, to string) error {
+	chunks, err := fs.chunks(from)
+	if err != nil {
+		return fs.Filesystem.Rename(from, to)
+	}
+
+	if fi, err := fs.Filesystem.Stat(to); err == nil && fi.IsDir() {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: errIsDir}
+	}
+
+	if err := fs.removeChunks(to); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for i := range chunks {
+		if err := fs.Filesystem.Rename(chunkName(from, i), chunkName(to, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove removes the file chunk by chunk, from the last one. Directories are
+// removed at once.
+func (fs *Chunk) Remove(filename string) error {
+	err := fs.removeChunks(filename)
+	if os.IsNotExist(err) {
+		return fs.Filesystem.Remove(filename)
+	}
+
+	return err
+}
+
+func (fs *Chunk) removeChunks(filename string) error {
+	chunks, err := fs.chunks(filename)
+	if err != nil {
+		return err
+	}
+
+	for i := len(chunks) - 1; i >= 0; i-- {
+		if err := fs.Filesystem.Remove(chunkName(filename, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MkdirAll creates the directory and its parents, failing if any of them is a
+// file.
+func (fs *Chunk) MkdirAll(filename string, perm os.FileMode) error {
+	for dir := filepath.Clean(filename); ; dir = filepath.Dir(dir) {
+		if _, err := fs.Filesystem.Stat(chunkName(dir, 0)); err == nil {
+			return &os.PathError{Op: "mkdir", Path: filename, Err: os.ErrExist}
+		}
+
+		if parent := filepath.Dir(dir); parent == dir || parent == "." {
+			break
+		}
+	}
+
+	return fs.Filesystem.MkdirAll(filename, perm)
+}
+
+func (fs *Chunk) TempFile(dir, prefix string) (billy.File, error) {
+	return util.TempFile(fs, dir, prefix)
+}
+
+// Symlink returns billy.ErrNotSupported, a link to a file would point to no
+// chunk of it.
+func (fs *Chunk) Symlink(target, link string) error {
+	return billy.ErrNotSupported
+}
+
+func (fs *Chunk) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot, fs.size), nil
+}
+
+// Capabilities implements the Capable interface, the ones of the underlying
+// filesystem without SymlinkCapability, since Symlink always fails.
+func (fs *Chunk) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem) &^ billy.SymlinkCapability
+}
+
+// fileSize returns the size of the whole file.
+func (fs *Chunk) fileSize(filename string) (int64, error) {
+	fi, err := fs.fileInfo(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	return fi.Size(), nil
+}
+
+// truncate changes the size of the file, removing the chunks past the new
+// size, or filling the chunks up to it with zeros.
+func (fs *Chunk) truncate(filename string, size int64, perm os.FileMode) error {
+	chunks, err := fs.chunks(filename)
+	if err != nil {
+		return err
+	}
+
+	need := int((size + fs.size - 1) / fs.size)
+	if need == 0 {
+		need = 1
+	}
+
+	for i := len(chunks) - 1; i >= need; i-- {
+		if err := fs.Filesystem.Remove(chunkName(filename, i)); err != nil {
+			return err
+		}
+	}
+
+	start := len(chunks) - 1
+	if start >= need {
+		start = need - 1
+	}
+
+	for i := start; i < need; i++ {
+		csize := size - int64(i)*fs.size
+		if csize > fs.size {
+			csize = fs.size
+		}
+
+		if i < len(chunks) && chunks[i].Size() == csize {
+			continue
+		}
+
+		if err := fs.truncateChunk(chunkName(filename, i), csize, perm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fs *Chunk) truncateChunk(name string, size int64, perm os.FileMode) error {
+	f, err := fs.Filesystem.OpenFile(name, os.O_WRONLY|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+
+	err = f.Truncate(size)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func chunkName(filename string, i int) string {
+	return fmt.Sprintf("%s.%03d", filename, i)
+}
+
+// parseChunkName returns the name of the file and the index of the chunk with
+// the given name.
+func parseChunkName(name string) (string, int, bool) {
+	dot := strings.LastIndexByte(name, '.')
+	if dot < 0 || len(name)-dot-1 < 3 {
+		return "", 0, false
+	}
+
+	i, err := strconv.Atoi(name[dot+1:])
+	if err != nil || i < 0 || chunkName(name[:dot], i) != name {
+		return "", 0, false
+	}
+
+	return name[:dot], i, true
+}
+
+type file struct {
+	fs       *Chunk
+	name     string
+	flag     int
+	perm     os.FileMode
+	position int64
+	isClosed bool
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	n, err := f.ReadAt(b, f.position)
+	f.position += int64(n)
+
+	if err == io.EOF && n != 0 {
+		err = nil
+	}
+
+	return n, err
+}
+
+// ReadAt reads the chunks covering len(b) bytes from off, one by one.
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == os.O_WRONLY {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: billy.ErrWriteOnly}
+	}
+
+	if off < 0 {
+		return 0, &os.PathError{Op: "readat", Path: f.name, Err: errNegative}
+	}
+
+	var n int
+	for n < len(b) {
+		i, inner, want := f.span(off+int64(n), len(b)-n)
+		c, err := f.fs.Filesystem.Open(chunkName(f.name, i))
+		if os.IsNotExist(err) {
+			return n, io.EOF
+		}
+
+		if err != nil {
+			return n, err
+		}
+
+		m, err := c.ReadAt(b[n:n+want], inner)
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+
+		n += m
+		if m < want {
+			return n, io.EOF
+		}
+
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// span returns the index of the chunk holding off, the offset inside of it,
+// and how many of the n bytes from off it holds.
+func (f *file) span(off int64, n int) (int, int64, int) {
+	i, inner := off/f.fs.size, off%f.fs.size
+	if rest := f.fs.size - inner; int64(n) > rest {
+		n = int(rest)
+	}
+
+	return int(i), inner, n
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	switch whence {
+	case io.SeekCurrent:
+		f.position += offset
+	case io.SeekStart:
+		f.position = offset
+	case io.SeekEnd:
+		size, err := f.fs.fileSize(f.name)
+		if err != nil {
+			return f.position, err
+		}
+
+		f.position = size + offset
+	default:
+		return f.position, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+
+	return f.position, nil
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	if f.flag&os.O_APPEND != 0 {
+		size, err := f.fs.fileSize(f.name)
+		if err != nil {
+			return 0, err
+		}
+
+		f.position = size
+	}
+
+	n, err := f.writeAt(p, f.position)
+	f.position += int64(n)
+	return n, err
+}
+
+// writeAt writes p at off chunk by chunk, filling the gap from the end of the
+// file with zeros first.
+func (f *file) writeAt(p []byte, off int64) (int, error) {
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: errWriteRead}
+	}
+
+	if off < 0 {
+		return 0, &os.PathError{Op: "writeat", Path: f.name, Err: errNegative}
+	}
+
+	size, err := f.fs.fileSize(f.name)
+	if err != nil {
+		return 0, err
+	}
+
+	if off > size {
+		if err := f.fs.truncate(f.name, off, f.perm); err != nil {
+			return 0, err
+		}
+	}
+
+	var n int
+	for n < len(p) {
+		i, inner, want := f.span(off+int64(n), len(p)-n)
+		m, err := f.writeChunk(i, p[n:n+want], inner)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+func (f *file) writeChunk(i int, p []byte, off int64) (int, error) {
+	c, err := f.fs.Filesystem.OpenFile(chunkName(f.name, i), os.O_WRONLY|os.O_CREATE, f.perm)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if _, err = c.Seek(off, io.SeekStart); err == nil {
+		n, err = c.Write(p)
+	}
+
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+
+	if cerr := c.Close(); err == nil {
+		err = cerr
+	}
+
+	return n, err
+}
+
+func (f *file) Truncate(size int64) error {
+	if f.isClosed {
+		return os.ErrClosed
+	}
+
+	if size < 0 {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: os.ErrInvalid}
+	}
+
+	return f.fs.truncate(f.name, size, f.perm)
+}
+
+func (f *file) Close() error {
+	if f.isClosed {
+		return os.ErrClosed
+	}
+
+	f.isClosed = true
+	return nil
+}
+
+// Sync commits the content of all the chunks of the file to stable storage.
+func (f *file) Sync() error {
+	if f.isClosed {
+		return os.ErrClosed
+	}
+
+	size, err := f.fs.fileSize(f.name)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; int64(i)*f.fs.size < size; i++ {
+		if err := f.syncChunk(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *file) syncChunk(i int) error {
+	c, err := f.fs.Filesystem.OpenFile(chunkName(f.name, i), os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = c.Sync()
+	if cerr := c.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Lock is a no-op in chunkfs.
+func (f *file) Lock() error {
+	return nil
+}
+
+// Unlock is a no-op in chunkfs.
+func (f *file) Unlock() error {
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return false
+}
+
+func (*fileInfo) Sys() interface{} {
+	return nil
+}
@@ -0,0 +1,116 @@
+package chunkfs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&ChunkSuite{})
+
+type ChunkSuite struct {
+	test.BasicSuite
+	test.DirSuite
+	test.TempFileSuite
+	test.ChrootSuite
+
+	fs, underlying billy.Filesystem
+}
+
+func (s *ChunkSuite) SetUpTest(c *C) {
+	s.underlying = memfs.New()
+	s.fs = New(s.underlying, 4)
+	s.BasicSuite.FS = s.fs
+	s.DirSuite.FS = s.fs
+	s.TempFileSuite.FS = s.fs
+	s.ChrootSuite.FS = s.fs
+}
+
+func (s *ChunkSuite) TestChunks(c *C) {
+	fs := s.fs
+	c.Assert(util.WriteFile(fs, "foo/bar", []byte("0123456789"), 0644), IsNil)
+
+	for name, content := range map[string]string{
+		"foo/bar.000": "0123",
+		"foo/bar.001": "4567",
+		"foo/bar.002": "89",
+	} {
+		data, err := util.ReadFileString(s.underlying, name)
+		c.Assert(err, IsNil)
+		c.Assert(data, Equals, content)
+	}
+
+	fi, err := fs.Stat("foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "bar")
+	c.Assert(fi.Size(), Equals, int64(10))
+
+	entries, err := fs.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Assert(entries[0].Name(), Equals, "bar")
+	c.Assert(entries[0].Size(), Equals, int64(10))
+}
+
+func (s *ChunkSuite) TestAcrossChunks(c *C) {
+	fs := s.fs
+	f, err := fs.Create("foo")
+	c.Assert(err, IsNil)
+
+	_, err = f.Write([]byte("0123456789"))
+	c.Assert(err, IsNil)
+
+	buf := make([]byte, 5)
+	n, err := f.ReadAt(buf, 3)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf[:n]), Equals, "34567")
+
+	_, err = f.Seek(6, io.SeekStart)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("ABCDEF"))
+	c.Assert(err, IsNil)
+
+	_, err = f.Seek(14, io.SeekStart)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("Z"))
+	c.Assert(err, IsNil)
+
+	_, err = f.Seek(0, io.SeekStart)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "012345ABCDEF\x00\x00Z")
+
+	c.Assert(f.Truncate(5), IsNil)
+	_, err = s.underlying.Stat("foo.002")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	c.Assert(f.Truncate(9), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(fs.Rename("foo", "bar"), IsNil)
+	content, err := util.ReadFileString(fs, "bar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "01234\x00\x00\x00\x00")
+
+	c.Assert(fs.Remove("bar"), IsNil)
+	entries, err := s.underlying.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+}
+
+func (s *ChunkSuite) TestCapabilities(c *C) {
+	c.Assert(billy.CapabilityCheck(s.underlying, billy.SymlinkCapability), Equals, true)
+	c.Assert(billy.CapabilityCheck(s.fs, billy.SymlinkCapability), Equals, false)
+	c.Assert(billy.CapabilityCheck(s.fs, billy.ReadAndWriteCapability), Equals, true)
+}
@@ -0,0 +1,191 @@
+package debouncefs
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// The changes reported as events.
+const (
+	OpCreate = "create"
+	OpWrite  = "write"
+	OpRemove = "remove"
+)
+
+// Event is a change of a file or a directory.
+type Event struct {
+	// Op is the change, one of the Op constants.
+	Op string
+	// Path is the path changed, joined to the path given to Watch.
+	Path string
+}
+
+// Watch watches the tree rooted at path for changes, delivering them in
+// batches, eg.: to rebuild once after a burst of changes instead of once per
+// change. The events are held until no change is seen for window, then
+// delivered at once, sorted by path, with a single event per path: a file
+// created and then written is reported as created, and a file created and
+// then removed isn't reported at all.
+//
+// The changes are detected by comparing the tree every quarter of window, so
+// any billy.Filesystem can be watched. The changes to the content of the
+// directories aren't reported for the directories themselves.
+//
+// Calling the returned function stops watching, closing the channel and
+// releasing the resources, the events not yet delivered are dropped.
+func Watch(fs billy.Filesystem, path string, window time.Duration) (<-chan []Event, func()) {
+	w := &watcher{
+		fs:     fs,
+		path:   path,
+		window: window,
+		c:      make(chan []Event),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go w.run(w.snapshot())
+
+	var once sync.Once
+	return w.c, func() {
+		once.Do(func() { close(w.stop) })
+		<-w.done
+	}
+}
+
+type watcher struct {
+	fs     billy.Filesystem
+	path   string
+	window time.Duration
+
+	c    chan []Event
+	stop chan struct{}
+	done chan struct{}
+}
+
+// state is what is compared to detect the changes of a file.
+type state struct {
+	mode    os.FileMode
+	size    int64
+	modTime time.Time
+}
+
+// run compares the tree with prev, its first snapshot, until stopped.
+func (w *watcher) run(prev map[string]state) {
+	defer close(w.done)
+	defer close(w.c)
+
+	interval := w.window / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := make(map[string]string)
+	var last time.Time
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			cur := w.snapshot()
+			if changed := diff(prev, cur, pending); changed {
+				last = now
+			}
+
+			prev = cur
+			if len(pending) == 0 || now.Sub(last) < w.window {
+				continue
+			}
+
+			select {
+			case w.c <- batch(pending):
+				pending = make(map[string]string)
+			case <-w.stop:
+				return
+			}
+		}
+	}
+}
+
+// snapshot returns the state of all the files of the tree, an empty one if
+// it can't be read.
+func (w *watcher) snapshot() map[string]state {
+	files := make(map[string]state)
+	_ = util.Walk(w.fs, w.path, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		s := state{mode: fi.Mode()}
+		if !fi.IsDir() {
+			s.size, s.modTime = fi.Size(), fi.ModTime()
+		}
+
+		files[path] = s
+		return nil
+	})
+
+	return files
+}
+
+// diff merges the changes from prev to cur into pending, returning true if
+// there are any.
+func diff(prev, cur map[string]state, pending map[string]string) bool {
+	var changed bool
+	for path, s := range cur {
+		p, ok := prev[path]
+		switch {
+		case !ok:
+			merge(pending, path, OpCreate)
+		case p.mode != s.mode || p.size != s.size || !p.modTime.Equal(s.modTime):
+			merge(pending, path, OpWrite)
+		default:
+			continue
+		}
+
+		changed = true
+	}
+
+	for path := range prev {
+		if _, ok := cur[path]; !ok {
+			merge(pending, path, OpRemove)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// merge adds the change of path to pending, deduplicating it with the one
+// already pending, if any.
+func merge(pending map[string]string, path, op string) {
+	switch prev := pending[path]; {
+	case prev == OpCreate && op == OpWrite:
+	case prev == OpCreate && op == OpRemove:
+		delete(pending, path)
+	case prev == OpRemove && op == OpCreate:
+		pending[path] = OpWrite
+	default:
+		pending[path] = op
+	}
+}
+
+func batch(pending map[string]string) []Event {
+	events := make([]Event, 0, len(pending))
+	for path, op := range pending {
+		events = append(events, Event{Op: op, Path: path})
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+
+	return events
+}
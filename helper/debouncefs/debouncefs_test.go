@@ -0,0 +1,72 @@
+package debouncefs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&DebounceSuite{})
+
+type DebounceSuite struct {
+	fs billy.Filesystem
+}
+
+const window = 50 * time.Millisecond
+
+func (s *DebounceSuite) SetUpTest(c *C) {
+	s.fs = memfs.New()
+	c.Assert(util.WriteFile(s.fs, "foo/bar", []byte("foo"), 0644), IsNil)
+}
+
+func (s *DebounceSuite) TestBurst(c *C) {
+	events, stop := Watch(s.fs, "foo", window)
+	defer stop()
+
+	for i := 0; i < 5; i++ {
+		c.Assert(util.WriteFile(s.fs, "foo/qux", []byte{byte(i)}, 0644), IsNil)
+		c.Assert(util.WriteFile(s.fs, "foo/bar", []byte{byte(i)}, 0644), IsNil)
+		time.Sleep(window / 5)
+	}
+
+	c.Assert(util.WriteFile(s.fs, "foo/tmp", nil, 0644), IsNil)
+	c.Assert(s.fs.Remove("foo/tmp"), IsNil)
+
+	c.Assert(receive(c, events), DeepEquals, []Event{
+		{Op: OpWrite, Path: "foo/bar"},
+		{Op: OpCreate, Path: "foo/qux"},
+	})
+
+	c.Assert(s.fs.Remove("foo/bar"), IsNil)
+	c.Assert(receive(c, events), DeepEquals, []Event{
+		{Op: OpRemove, Path: "foo/bar"},
+	})
+}
+
+func (s *DebounceSuite) TestStop(c *C) {
+	events, stop := Watch(s.fs, "foo", window)
+	stop()
+	stop()
+
+	c.Assert(util.WriteFile(s.fs, "foo/qux", nil, 0644), IsNil)
+
+	_, ok := <-events
+	c.Assert(ok, Equals, false)
+}
+
+func receive(c *C, events <-chan []Event) []Event {
+	select {
+	case batch := <-events:
+		return batch
+	case <-time.After(20 * window):
+		c.Fatal("no events delivered")
+		return nil
+	}
+}
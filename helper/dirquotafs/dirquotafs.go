@@ -0,0 +1,493 @@
+package dirquotafs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+const separator = string(filepath.Separator)
+
+// DirQuota is a helper that limits the bytes held by some directories of the
+// underlying filesystem, eg.: the one of every tenant of a multi-tenant
+// system. The writes, truncates and renames growing the content of a directory
+// past its quota fail with an *os.PathError wrapping ENOSPC, like a full disk,
+// while the other directories are unaffected. Removing or truncating the files
+// credits their bytes back.
+//
+// The content already held by a directory is accounted on its first change,
+// walking it. The writes to the files of a directory with a quota are
+// serialized to keep the accounting exact, the other ones aren't, while the
+// renames and removals wait for all the writes in progress. The writes
+// through a file open before it's renamed are charged to the directories
+// holding its new name, and the ones after it's removed aren't charged.
+type DirQuota struct {
+	billy.Filesystem
+	a      *accounting
+	prefix string
+}
+
+type accounting struct {
+	// m is held for reading by the opens, writes and truncates, along with
+	// the locks of the quotas changed, and for writing by the changes moving
+	// bytes between the directories, as renames and removals.
+	m      sync.RWMutex
+	fs     billy.Filesystem
+	quotas map[string]*quota
+
+	// open holds the open files by path, to follow their renames and
+	// removals, protected by openM.
+	open  map[string]map[*file]struct{}
+	openM sync.Mutex
+}
+
+// quota holds the limit of a directory and the bytes it holds, its lock
+// serializing the writes to its files.
+type quota struct {
+	m     sync.Mutex
+	limit int64
+	// used holds the bytes held by the directory, once known.
+	used  int64
+	known bool
+}
+
+// New creates a new filesystem wrapping up 'fs', limiting the bytes held by
+// each directory in quotas, by path relative to the root of fs. A file in
+// nested directories with quotas is accounted in all of them.
+func New(fs billy.Filesystem, quotas map[string]int64) billy.Filesystem {
+	a := &accounting{
+		fs:     fs,
+		quotas: make(map[string]*quota, len(quotas)),
+		open:   make(map[string]map[*file]struct{}),
+	}
+
+	for dir, limit := range quotas {
+		a.quotas[clean(dir)] = &quota{limit: limit}
+	}
+
+	return &DirQuota{Filesystem: fs, a: a}
+}
+
+func (fs *DirQuota) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *DirQuota) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *DirQuota) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if flag&os.O_TRUNC == 0 {
+		fs.a.m.RLock()
+		defer fs.a.m.RUnlock()
+
+		f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+
+		return fs.wrapFile(f, filename, flag), nil
+	}
+
+	fs.a.m.RLock()
+	defer fs.a.m.RUnlock()
+	defer fs.a.lock(fs.path(filename))()
+
+	deltas := fs.a.deltas(fs.path(filename), -fs.fileSize(filename))
+	if err := fs.a.check(deltas); err != nil {
+		return nil, err
+	}
+
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.a.add(deltas)
+	return fs.wrapFile(f, filename, flag), nil
+}
+
+func (fs *DirQuota) TempFile(dir, prefix string) (billy.File, error) {
+	fs.a.m.RLock()
+	defer fs.a.m.RUnlock()
+
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.wrapFile(f, f.Name(), os.O_RDWR|os.O_CREATE|os.O_EXCL), nil
+}
+
+// Rename renames the file or the directory, moving its bytes from the
+// directories holding from to the ones holding to, if there is room for them.
+func (fs *DirQuota) Rename(from, to string) error {
+	fs.a.m.Lock()
+	defer fs.a.m.Unlock()
+
+	size, err := treeSize(fs.Filesystem, from)
+	if err != nil {
+		return err
+	}
+
+	deltas := fs.a.deltas(fs.path(from), -size)
+	for dir, n := range fs.a.deltas(fs.path(to), size-fs.fileSize(to)) {
+		deltas[dir] += n
+	}
+
+	if err := fs.a.check(deltas); err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+
+	if err := fs.Filesystem.Rename(from, to); err != nil {
+		return err
+	}
+
+	fs.a.add(deltas)
+	fs.a.rename(fs.path(from), fs.path(to))
+	return nil
+}
+
+func (fs *DirQuota) Remove(filename string) error {
+	fs.a.m.Lock()
+	defer fs.a.m.Unlock()
+
+	deltas := fs.a.deltas(fs.path(filename), -fs.fileSize(filename))
+	if err := fs.a.check(deltas); err != nil {
+		return err
+	}
+
+	if err := fs.Filesystem.Remove(filename); err != nil {
+		return err
+	}
+
+	fs.a.add(deltas)
+	fs.a.remove(fs.path(filename))
+	return nil
+}
+
+func (fs *DirQuota) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DirQuota{
+		Filesystem: chroot,
+		a:          fs.a,
+		prefix:     fs.path(path),
+	}, nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *DirQuota) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// path returns the path relative to the root of the filesystem given to New.
+func (fs *DirQuota) path(filename string) string {
+	return clean(filepath.Join(fs.prefix, filename))
+}
+
+// fileSize returns the size of the named regular file, or 0 if it isn't one.
+func (fs *DirQuota) fileSize(filename string) int64 {
+	fi, err := fs.Filesystem.Lstat(filename)
+	if err != nil || !fi.Mode().IsRegular() {
+		return 0
+	}
+
+	return fi.Size()
+}
+
+// wrapFile wraps f, open at filename, keeping track of it. It must be called
+// with m held, along with the opening of f, so its renames are followed.
+func (fs *DirQuota) wrapFile(f billy.File, filename string, flag int) billy.File {
+	qf := &file{File: f, a: fs.a, name: filename, path: fs.path(filename), flag: flag}
+
+	fs.a.openM.Lock()
+	defer fs.a.openM.Unlock()
+
+	if fs.a.open[qf.path] == nil {
+		fs.a.open[qf.path] = make(map[*file]struct{})
+	}
+
+	fs.a.open[qf.path][qf] = struct{}{}
+	return qf
+}
+
+// rename moves the open files at from, or inside of it, to to. It must be
+// called with m held for writing.
+func (a *accounting) rename(from, to string) {
+	a.openM.Lock()
+	defer a.openM.Unlock()
+
+	moved := make(map[string]map[*file]struct{})
+	for path, files := range a.open {
+		if path == from || strings.HasPrefix(path, from+separator) {
+			moved[path] = files
+			delete(a.open, path)
+		}
+	}
+
+	for path, files := range moved {
+		path = to + strings.TrimPrefix(path, from)
+		if a.open[path] == nil {
+			a.open[path] = make(map[*file]struct{})
+		}
+
+		for f := range files {
+			f.path = path
+			a.open[path][f] = struct{}{}
+		}
+	}
+}
+
+// remove marks the open files at path as removed. It must be called with m
+// held for writing.
+func (a *accounting) remove(path string) {
+	a.openM.Lock()
+	defer a.openM.Unlock()
+
+	for f := range a.open[path] {
+		f.removed = true
+	}
+
+	delete(a.open, path)
+}
+
+// closed forgets the open file f.
+func (a *accounting) closed(f *file) {
+	a.openM.Lock()
+	defer a.openM.Unlock()
+
+	delete(a.open[f.path], f)
+	if len(a.open[f.path]) == 0 {
+		delete(a.open, f.path)
+	}
+}
+
+// lock takes the locks of the quotas of the directories holding path, in
+// order, returning the function releasing them. It must be called with m
+// held for reading.
+func (a *accounting) lock(path string) (unlock func()) {
+	var dirs []string
+	for dir := range a.deltas(path, 0) {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		a.quotas[dir].m.Lock()
+	}
+
+	return func() {
+		for _, dir := range dirs {
+			a.quotas[dir].m.Unlock()
+		}
+	}
+}
+
+// fileSize returns the size of the regular file at path, relative to the root
+// of the filesystem given to New, or 0 if it isn't one.
+func (a *accounting) fileSize(path string) int64 {
+	fi, err := a.fs.Lstat(separator + path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return 0
+	}
+
+	return fi.Size()
+}
+
+// deltas returns the change of n bytes for every directory with a quota
+// holding path.
+func (a *accounting) deltas(path string, n int64) map[string]int64 {
+	deltas := make(map[string]int64)
+	for dir := range a.quotas {
+		if dir == "" || path == dir || strings.HasPrefix(path, dir+separator) {
+			deltas[dir] = n
+		}
+	}
+
+	return deltas
+}
+
+// check returns errNoSpace if any of the deltas exceeds the quota of its
+// directory. It must be called before the change, to account the content
+// held by the directories before it, with m held for writing or the quotas
+// locked, as add.
+func (a *accounting) check(deltas map[string]int64) error {
+	for dir, n := range deltas {
+		used := a.usage(dir)
+		if n > 0 && used+n > a.quotas[dir].limit {
+			return errNoSpace
+		}
+	}
+
+	return nil
+}
+
+func (a *accounting) add(deltas map[string]int64) {
+	for dir, n := range deltas {
+		a.quotas[dir].used = a.usage(dir) + n
+	}
+}
+
+// usage returns the bytes held by the directory, walking it on the first
+// call.
+func (a *accounting) usage(dir string) int64 {
+	q := a.quotas[dir]
+	if !q.known {
+		q.used, _ = treeSize(a.fs, separator+dir)
+		q.known = true
+	}
+
+	return q.used
+}
+
+// treeSize returns the size of the regular file at path, or of all the ones
+// inside of it if it's a directory. A missing path has size 0.
+func treeSize(fs billy.Filesystem, path string) (int64, error) {
+	fi, err := fs.Lstat(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !fi.IsDir() {
+		if !fi.Mode().IsRegular() {
+			return 0, nil
+		}
+
+		return fi.Size(), nil
+	}
+
+	entries, err := fs.ReadDir(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, e := range entries {
+		n, err := treeSize(fs, fs.Join(path, e.Name()))
+		if err != nil {
+			return 0, err
+		}
+
+		size += n
+	}
+
+	return size, nil
+}
+
+// clean returns the path relative to the root, the root being "".
+func clean(path string) string {
+	path = filepath.Clean(filepath.FromSlash(path))
+	path = strings.TrimPrefix(path, separator)
+	if path == "." {
+		return ""
+	}
+
+	return path
+}
+
+type file struct {
+	billy.File
+	a    *accounting
+	name string
+	flag int
+
+	// path is the path of the file relative to the root of the filesystem
+	// given to New, following its renames, and removed tells if it was
+	// removed, both changed with a.m held for writing.
+	path    string
+	removed bool
+}
+
+// Write writes p if the bytes written past the end of the file fit in the
+// quotas.
+func (f *file) Write(p []byte) (int, error) {
+	a := f.a
+	a.m.RLock()
+	defer a.m.RUnlock()
+
+	if f.removed {
+		return f.write(p)
+	}
+
+	defer a.lock(f.path)()
+
+	size := a.fileSize(f.path)
+	off := size
+	if f.flag&os.O_APPEND == 0 {
+		pos, err := f.File.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+
+		off = pos
+	}
+
+	growth := func(n int) int64 {
+		if end := off + int64(n); end > size {
+			return end - size
+		}
+
+		return 0
+	}
+
+	if err := a.check(a.deltas(f.path, growth(len(p)))); err != nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: err}
+	}
+
+	n, err := f.write(p)
+	a.add(a.deltas(f.path, growth(n)))
+	return n, err
+}
+
+func (f *file) write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+
+	return n, err
+}
+
+// Truncate changes the size of the file if the bytes added fit in the quotas,
+// the bytes removed are credited back.
+func (f *file) Truncate(size int64) error {
+	a := f.a
+	a.m.RLock()
+	defer a.m.RUnlock()
+
+	if f.removed {
+		return f.File.Truncate(size)
+	}
+
+	defer a.lock(f.path)()
+
+	deltas := a.deltas(f.path, size-a.fileSize(f.path))
+	if err := a.check(deltas); err != nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: err}
+	}
+
+	if err := f.File.Truncate(size); err != nil {
+		return err
+	}
+
+	a.add(deltas)
+	return nil
+}
+
+func (f *file) Close() error {
+	f.a.closed(f)
+	return f.File.Close()
+}
@@ -0,0 +1,219 @@
+package dirquotafs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&DirQuotaSuite{})
+
+type DirQuotaSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *DirQuotaSuite) SetUpTest(c *C) {
+	fs := New(memfs.New(), map[string]int64{"dir": 1 << 20})
+	s.FilesystemSuite = test.NewFilesystemSuite(fs)
+}
+
+func (s *DirQuotaSuite) TestQuota(c *C) {
+	underlying := memfs.New()
+	c.Assert(util.WriteFile(underlying, "a/existing", []byte("123"), 0644), IsNil)
+
+	fs := New(underlying, map[string]int64{"a": 10, "/b/": 5})
+
+	c.Assert(util.WriteFile(fs, "a/foo", []byte("12345"), 0644), IsNil)
+	err := util.WriteFile(fs, "a/bar", []byte("123"), 0644)
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+	c.Assert(util.WriteFile(fs, "a/bar", []byte("12"), 0644), IsNil)
+
+	c.Assert(util.WriteFile(fs, "other", make([]byte, 100), 0644), IsNil)
+	c.Assert(util.WriteFile(fs, "b/foo", []byte("12345"), 0644), IsNil)
+
+	c.Assert(fs.Remove("a/foo"), IsNil)
+	c.Assert(util.WriteFile(fs, "a/foo", []byte("1234"), 0644), IsNil)
+
+	f, err := fs.OpenFile("a/foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(1), IsNil)
+	_, err = f.Seek(0, io.SeekEnd)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("23456"))
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+	_, err = f.Write([]byte("2345"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	err = fs.Rename("b/foo", "a/moved")
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+
+	c.Assert(util.RemoveAll(fs, "a"), IsNil)
+	c.Assert(fs.Rename("b", "a/b"), IsNil)
+	c.Assert(util.WriteFile(fs, "b/foo", []byte("12345"), 0644), IsNil)
+}
+
+func (s *DirQuotaSuite) TestPartialWrite(c *C) {
+	underlying := &shortFS{Filesystem: memfs.New(), max: 2}
+	fs := New(underlying, map[string]int64{"a": 10})
+
+	f, err := fs.Create("a/foo")
+	c.Assert(err, IsNil)
+
+	n, err := f.Write([]byte("123"))
+	c.Assert(n, Equals, 2)
+	c.Assert(err, Equals, io.ErrShortWrite)
+
+	underlying.err = errors.New("write failed")
+	n, err = f.Write([]byte("345"))
+	c.Assert(n, Equals, 2)
+	c.Assert(err, Equals, underlying.err)
+	c.Assert(f.Close(), IsNil)
+
+	// only the 4 bytes written are charged.
+	underlying.max, underlying.err = 100, nil
+	err = util.WriteFile(fs, "a/bar", []byte("1234567"), 0644)
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+	c.Assert(util.WriteFile(fs, "a/bar", []byte("123456"), 0644), IsNil)
+}
+
+func (s *DirQuotaSuite) TestRenameOpenFile(c *C) {
+	fs := New(memfs.New(), map[string]int64{"a": 10, "b": 10})
+
+	f, err := fs.Create("a/foo")
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("12345"))
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Rename("a/foo", "b/foo"), IsNil)
+	_, err = f.Write([]byte("12345"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(util.WriteFile(fs, "a/bar", make([]byte, 10), 0644), IsNil)
+	err = util.WriteFile(fs, "b/bar", []byte("1"), 0644)
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+}
+
+func (s *DirQuotaSuite) TestRemoveOpenFile(c *C) {
+	fs := New(memfs.New(), map[string]int64{"a": 10})
+
+	f, err := fs.Create("a/foo")
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("12345"))
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Remove("a/foo"), IsNil)
+	_, err = f.Write([]byte("12345"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(util.WriteFile(fs, "a/bar", make([]byte, 10), 0644), IsNil)
+}
+
+func (s *DirQuotaSuite) TestWriteOtherDirNotBlocked(c *C) {
+	underlying := &blockFS{Filesystem: memfs.New(), entered: make(chan struct{}), release: make(chan struct{})}
+	fs := New(underlying, map[string]int64{"a": 10, "b": 10})
+
+	slow, err := fs.Create("a/slow")
+	c.Assert(err, IsNil)
+
+	done := make(chan error)
+	go func() {
+		_, err := slow.Write([]byte("1"))
+		done <- err
+	}()
+
+	<-underlying.entered
+	c.Assert(util.WriteFile(fs, "b/foo", []byte("1"), 0644), IsNil)
+	c.Assert(util.WriteFile(fs, "foo", []byte("1"), 0644), IsNil)
+
+	close(underlying.release)
+	c.Assert(<-done, IsNil)
+	c.Assert(slow.Close(), IsNil)
+}
+
+// blockFS is a filesystem whose files named slow block every write until
+// release is closed, closing entered once the first one starts.
+type blockFS struct {
+	billy.Filesystem
+	entered, release chan struct{}
+}
+
+func (fs *blockFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil || filepath.Base(filename) != "slow" {
+		return f, err
+	}
+
+	return &blockFile{File: f, fs: fs}, nil
+}
+
+type blockFile struct {
+	billy.File
+	fs *blockFS
+}
+
+func (f *blockFile) Write(p []byte) (int, error) {
+	close(f.fs.entered)
+	<-f.fs.release
+	return f.File.Write(p)
+}
+
+// shortFS is a filesystem whose files write at most max bytes at once,
+// failing with err, if any, when they do.
+type shortFS struct {
+	billy.Filesystem
+	max int
+	err error
+}
+
+func (fs *shortFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shortFile{File: f, fs: fs}, nil
+}
+
+type shortFile struct {
+	billy.File
+	fs *shortFS
+}
+
+func (f *shortFile) Write(p []byte) (int, error) {
+	if len(p) <= f.fs.max {
+		return f.File.Write(p)
+	}
+
+	n, err := f.File.Write(p[:f.fs.max])
+	if err != nil {
+		return n, err
+	}
+
+	return n, f.fs.err
+}
+
+func (s *DirQuotaSuite) TestChroot(c *C) {
+	fs := New(memfs.New(), map[string]int64{"a/b": 3})
+
+	chroot, err := fs.Chroot("a")
+	c.Assert(err, IsNil)
+
+	c.Assert(util.WriteFile(chroot, "b/foo", []byte("123"), 0644), IsNil)
+	err = util.WriteFile(chroot, "b/bar", []byte("1"), 0644)
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+	c.Assert(util.WriteFile(chroot, "c", []byte("1234"), 0644), IsNil)
+}
@@ -0,0 +1,9 @@
+// +build !plan9
+
+package dirquotafs
+
+import "syscall"
+
+// errNoSpace is the error returned once the quota of a directory is
+// exhausted.
+var errNoSpace error = syscall.ENOSPC
@@ -0,0 +1,7 @@
+package dirquotafs
+
+import "errors"
+
+// errNoSpace is the error returned once the quota of a directory is
+// exhausted, Plan 9 has no ENOSPC.
+var errNoSpace = errors.New("no space left on device")
@@ -0,0 +1,333 @@
+package eventlogfs
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// The operations recorded as events.
+const (
+	OpOpen     = "open"
+	OpWrite    = "write"
+	OpTruncate = "truncate"
+	OpRename   = "rename"
+	OpRemove   = "remove"
+	OpMkdirAll = "mkdirall"
+	OpSymlink  = "symlink"
+)
+
+// Event is the record of a change done over the filesystem.
+type Event struct {
+	// Op is the operation, one of the Op constants.
+	Op string
+	// Path is the path changed, relative to the root of the filesystem given
+	// to New.
+	Path string
+	// Target is the new path on OpRename and the target on OpSymlink.
+	Target string `json:",omitempty"`
+	// Flag and Mode are the flags and the mode given on OpOpen, and the mode
+	// on OpMkdirAll.
+	Flag int         `json:",omitempty"`
+	Mode os.FileMode `json:",omitempty"`
+	// Offset is where Data was written on OpWrite, -1 if unknown, then it is
+	// appended to the file.
+	Offset int64 `json:",omitempty"`
+	// Size is the size given on OpTruncate.
+	Size int64 `json:",omitempty"`
+	// Data are the bytes written on OpWrite.
+	Data []byte `json:",omitempty"`
+}
+
+// EventLog is a helper that records every change done over the underlying
+// filesystem as an Event, written to a sink, so the changes can be applied
+// to another filesystem with Replay, eg.: to replicate it.
+//
+// Each event is written to the sink once the change succeeds, as a 4 bytes
+// big endian length followed by the event encoded as JSON. If writing to the
+// sink fails, the change is done anyway and the error is returned.
+type EventLog struct {
+	billy.Filesystem
+	log    *log
+	prefix string
+}
+
+type log struct {
+	m    sync.Mutex
+	sink io.Writer
+}
+
+// New creates a new filesystem wrapping up 'fs', the changes are written
+// to sink.
+func New(fs billy.Filesystem, sink io.Writer) billy.Filesystem {
+	return &EventLog{Filesystem: fs, log: &log{sink: sink}}
+}
+
+func (fs *EventLog) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *EventLog) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag&(os.O_CREATE|os.O_TRUNC) != 0 {
+		err := fs.record(Event{Op: OpOpen, Path: filename, Flag: flag, Mode: perm})
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+
+	return fs.wrapFile(f, filename), nil
+}
+
+func (fs *EventLog) TempFile(dir, prefix string) (billy.File, error) {
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fs.record(Event{
+		Op:   OpOpen,
+		Path: f.Name(),
+		Flag: os.O_RDWR | os.O_CREATE | os.O_EXCL,
+		Mode: 0600,
+	})
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return fs.wrapFile(f, f.Name()), nil
+}
+
+func (fs *EventLog) Rename(from, to string) error {
+	if err := fs.Filesystem.Rename(from, to); err != nil {
+		return err
+	}
+
+	return fs.record(Event{Op: OpRename, Path: from, Target: to})
+}
+
+func (fs *EventLog) Remove(filename string) error {
+	if err := fs.Filesystem.Remove(filename); err != nil {
+		return err
+	}
+
+	return fs.record(Event{Op: OpRemove, Path: filename})
+}
+
+func (fs *EventLog) MkdirAll(filename string, perm os.FileMode) error {
+	if err := fs.Filesystem.MkdirAll(filename, perm); err != nil {
+		return err
+	}
+
+	return fs.record(Event{Op: OpMkdirAll, Path: filename, Mode: perm})
+}
+
+// Symlink creates the link, the target is recorded as is, so a relative
+// target is also relative once replayed.
+func (fs *EventLog) Symlink(target, link string) error {
+	if err := fs.Filesystem.Symlink(target, link); err != nil {
+		return err
+	}
+
+	return fs.record(Event{Op: OpSymlink, Path: link, Target: target})
+}
+
+func (fs *EventLog) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventLog{
+		Filesystem: chroot,
+		log:        fs.log,
+		prefix:     fs.Join(fs.prefix, path),
+	}, nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *EventLog) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// record writes the event to the sink, with the paths relative to the root of
+// the filesystem given to New.
+func (fs *EventLog) record(e Event) error {
+	e.Path = fs.Join(fs.prefix, e.Path)
+	if e.Op == OpRename {
+		e.Target = fs.Join(fs.prefix, e.Target)
+	}
+
+	return fs.log.write(&e)
+}
+
+func (l *log) write(e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	buf = append(buf, data...)
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	_, err = l.sink.Write(buf)
+	return err
+}
+
+// ReadEvent reads the next event written by an EventLog from r. It returns
+// io.EOF once there are no more events.
+func ReadEvent(r io.Reader) (*Event, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	e := &Event{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Replay applies to fs the events written by an EventLog to r, in order,
+// until the end of r. It stops at the first event failing to apply.
+func Replay(fs billy.Filesystem, r io.Reader) error {
+	for {
+		e, err := ReadEvent(r)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := apply(fs, e); err != nil {
+			return fmt.Errorf("replaying %s %s: %w", e.Op, e.Path, err)
+		}
+	}
+}
+
+func apply(fs billy.Filesystem, e *Event) error {
+	switch e.Op {
+	case OpOpen:
+		f, err := fs.OpenFile(e.Path, e.Flag, e.Mode)
+		if err != nil {
+			return err
+		}
+
+		return f.Close()
+	case OpWrite:
+		return applyWrite(fs, e)
+	case OpTruncate:
+		f, err := fs.OpenFile(e.Path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+
+		err = f.Truncate(e.Size)
+		if err1 := f.Close(); err == nil {
+			err = err1
+		}
+
+		return err
+	case OpRename:
+		return fs.Rename(e.Path, e.Target)
+	case OpRemove:
+		return fs.Remove(e.Path)
+	case OpMkdirAll:
+		return fs.MkdirAll(e.Path, e.Mode)
+	case OpSymlink:
+		return fs.Symlink(e.Target, e.Path)
+	default:
+		return fmt.Errorf("unknown operation %q", e.Op)
+	}
+}
+
+func applyWrite(fs billy.Filesystem, e *Event) error {
+	flag := os.O_WRONLY
+	if e.Offset < 0 {
+		flag |= os.O_APPEND
+	}
+
+	f, err := fs.OpenFile(e.Path, flag, 0)
+	if err != nil {
+		return err
+	}
+
+	if e.Offset >= 0 {
+		_, err = f.Seek(e.Offset, io.SeekStart)
+	}
+
+	if err == nil {
+		_, err = f.Write(e.Data)
+	}
+
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+
+	return err
+}
+
+func (fs *EventLog) wrapFile(f billy.File, filename string) billy.File {
+	return &file{File: f, fs: fs, path: filename}
+}
+
+type file struct {
+	billy.File
+	fs   *EventLog
+	path string
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if n == 0 {
+		return n, err
+	}
+
+	offset := int64(-1)
+	if pos, serr := f.File.Seek(0, io.SeekCurrent); serr == nil {
+		offset = pos - int64(n)
+	}
+
+	e := Event{Op: OpWrite, Path: f.path, Offset: offset, Data: p[:n]}
+	if rerr := f.fs.record(e); err == nil {
+		err = rerr
+	}
+
+	return n, err
+}
+
+func (f *file) Truncate(size int64) error {
+	if err := f.File.Truncate(size); err != nil {
+		return err
+	}
+
+	return f.fs.record(Event{Op: OpTruncate, Path: f.path, Size: size})
+}
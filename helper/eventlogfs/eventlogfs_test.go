@@ -0,0 +1,98 @@
+package eventlogfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&EventLogSuite{})
+
+type EventLogSuite struct {
+	test.FilesystemSuite
+	sink bytes.Buffer
+}
+
+func (s *EventLogSuite) SetUpTest(c *C) {
+	s.sink.Reset()
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New(), &s.sink))
+}
+
+func (s *EventLogSuite) TestEvents(c *C) {
+	var sink bytes.Buffer
+	fs := New(memfs.New(), &sink)
+
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+	c.Assert(fs.Rename("foo", "bar"), IsNil)
+	_, err := fs.Stat("bar")
+	c.Assert(err, IsNil)
+
+	var events []Event
+	for {
+		e, err := ReadEvent(&sink)
+		if err == io.EOF {
+			break
+		}
+
+		c.Assert(err, IsNil)
+		events = append(events, *e)
+	}
+
+	c.Assert(events, DeepEquals, []Event{
+		{Op: OpOpen, Path: "foo", Flag: os.O_WRONLY | os.O_CREATE | os.O_TRUNC, Mode: 0644},
+		{Op: OpWrite, Path: "foo", Data: []byte("foo")},
+		{Op: OpRename, Path: "foo", Target: "bar"},
+	})
+}
+
+func (s *EventLogSuite) TestReplay(c *C) {
+	var sink bytes.Buffer
+	fs := New(memfs.New(), &sink)
+
+	c.Assert(util.WriteFile(fs, "foo/bar", []byte("bar"), 0644), IsNil)
+	c.Assert(fs.MkdirAll("qux", 0755), IsNil)
+	c.Assert(fs.Symlink("../foo/bar", "qux/link"), IsNil)
+
+	f, err := fs.OpenFile("foo/bar", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Seek(1, io.SeekStart)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("AZ"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(2), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	f, err = fs.OpenFile("foo/bar", os.O_WRONLY|os.O_APPEND, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("123"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	chroot, err := fs.Chroot("foo")
+	c.Assert(err, IsNil)
+	c.Assert(util.WriteFile(chroot, "baz", []byte("baz"), 0644), IsNil)
+	c.Assert(chroot.Rename("baz", "qux"), IsNil)
+
+	c.Assert(util.WriteFile(fs, "removed", nil, 0644), IsNil)
+	c.Assert(fs.Remove("removed"), IsNil)
+
+	replica := memfs.New()
+	c.Assert(Replay(replica, &sink), IsNil)
+
+	content, err := util.ReadFileString(replica, "foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "bA123")
+
+	eq, err := util.Equal(fs, replica, "/")
+	c.Assert(err, IsNil)
+	c.Assert(eq, Equals, true)
+}
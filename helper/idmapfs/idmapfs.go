@@ -0,0 +1,256 @@
+package idmapfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// IDMap is a helper that remaps the uid and gid given to Chown and Lchown,
+// eg.: to extract an archive, as an unprivileged user, into a user namespace.
+//
+// If the underlying filesystem doesn't support ownership, Chown and Lchown
+// don't fail, the mapped ids are recorded instead and returned as a *Owner
+// by the Sys method of the FileInfo returned by Stat and Lstat.
+type IDMap struct {
+	billy.Filesystem
+	uidMap, gidMap func(int) int
+
+	m      sync.RWMutex
+	owners map[string]*Owner
+}
+
+// Owner holds the mapped ownership of a file, recorded when the underlying
+// filesystem doesn't support ownership.
+type Owner struct {
+	UID, GID int
+	// Sys is the value returned by Sys on the underlying FileInfo.
+	Sys interface{}
+}
+
+// New creates a new filesystem wrapping up 'fs', the uid and gid are mapped
+// with uidMap and gidMap respectively, a nil function keeps the id as is.
+func New(fs billy.Filesystem, uidMap, gidMap func(int) int) billy.Filesystem {
+	return &IDMap{
+		Filesystem: fs,
+		uidMap:     uidMap,
+		gidMap:     gidMap,
+		owners:     make(map[string]*Owner),
+	}
+}
+
+// Chown implements the billy.Change interface.
+func (fs *IDMap) Chown(name string, uid, gid int) error {
+	uid, gid = fs.mapIDs(uid, gid)
+	if c, ok := fs.Filesystem.(billy.Change); ok {
+		return c.Chown(name, uid, gid)
+	}
+
+	if _, err := fs.Filesystem.Stat(name); err != nil {
+		return err
+	}
+
+	fs.setOwner(fs.resolve(name), uid, gid)
+	return nil
+}
+
+// Lchown implements the billy.Change interface.
+func (fs *IDMap) Lchown(name string, uid, gid int) error {
+	uid, gid = fs.mapIDs(uid, gid)
+	if c, ok := fs.Filesystem.(billy.Change); ok {
+		return c.Lchown(name, uid, gid)
+	}
+
+	if _, err := fs.Filesystem.Lstat(name); err != nil {
+		return err
+	}
+
+	fs.setOwner(name, uid, gid)
+	return nil
+}
+
+// Chmod implements the billy.Change interface.
+func (fs *IDMap) Chmod(name string, mode os.FileMode) error {
+	if c, ok := fs.Filesystem.(billy.Change); ok {
+		return c.Chmod(name, mode)
+	}
+
+	return billy.ErrNotSupported
+}
+
+// Chtimes implements the billy.Change interface.
+func (fs *IDMap) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if c, ok := fs.Filesystem.(billy.Change); ok {
+		return c.Chtimes(name, atime, mtime)
+	}
+
+	return billy.ErrNotSupported
+}
+
+func (fs *IDMap) Stat(filename string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.fileInfo(fs.resolve(filename), fi), nil
+}
+
+func (fs *IDMap) Lstat(filename string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Lstat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.fileInfo(filename, fi), nil
+}
+
+func (fs *IDMap) Rename(from, to string) error {
+	if err := fs.Filesystem.Rename(from, to); err != nil {
+		return err
+	}
+
+	from, to = clean(from), clean(to)
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	for path := range fs.owners {
+		if isInside(path, to) {
+			delete(fs.owners, path)
+		}
+	}
+
+	moved := make(map[string]*Owner)
+	for path, o := range fs.owners {
+		if isInside(path, from) {
+			delete(fs.owners, path)
+			moved[to+path[len(from):]] = o
+		}
+	}
+
+	for path, o := range moved {
+		fs.owners[path] = o
+	}
+
+	return nil
+}
+
+func (fs *IDMap) Remove(filename string) error {
+	if err := fs.Filesystem.Remove(filename); err != nil {
+		return err
+	}
+
+	fs.m.Lock()
+	delete(fs.owners, clean(filename))
+	fs.m.Unlock()
+
+	return nil
+}
+
+func (fs *IDMap) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot, fs.uidMap, fs.gidMap), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *IDMap) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+func (fs *IDMap) mapIDs(uid, gid int) (int, int) {
+	if fs.uidMap != nil && uid != -1 {
+		uid = fs.uidMap(uid)
+	}
+
+	if fs.gidMap != nil && gid != -1 {
+		gid = fs.gidMap(gid)
+	}
+
+	return uid, gid
+}
+
+// resolve returns the target of the given path if it is a symlink, the path
+// itself otherwise.
+func (fs *IDMap) resolve(name string) string {
+	l, err := fs.Filesystem.Lstat(name)
+	if err != nil || !isSymlink(l.Mode()) {
+		return name
+	}
+
+	target, err := fs.Filesystem.Readlink(name)
+	if err != nil {
+		return name
+	}
+
+	if !filepath.IsAbs(target) {
+		target = fs.Join(filepath.Dir(name), target)
+	}
+
+	return target
+}
+
+func (fs *IDMap) setOwner(name string, uid, gid int) {
+	name = clean(name)
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	o, ok := fs.owners[name]
+	if !ok {
+		o = &Owner{UID: -1, GID: -1}
+		fs.owners[name] = o
+	}
+
+	// as os.Chown, a -1 id means not to change it.
+	if uid != -1 {
+		o.UID = uid
+	}
+
+	if gid != -1 {
+		o.GID = gid
+	}
+}
+
+func (fs *IDMap) fileInfo(name string, fi os.FileInfo) os.FileInfo {
+	fs.m.RLock()
+	defer fs.m.RUnlock()
+
+	o, ok := fs.owners[clean(name)]
+	if !ok {
+		return fi
+	}
+
+	return &fileInfo{FileInfo: fi, owner: &Owner{UID: o.UID, GID: o.GID, Sys: fi.Sys()}}
+}
+
+type fileInfo struct {
+	os.FileInfo
+	owner *Owner
+}
+
+func (fi *fileInfo) Sys() interface{} {
+	return fi.owner
+}
+
+func isSymlink(m os.FileMode) bool {
+	return m&os.ModeSymlink != 0
+}
+
+// isInside returns true if path is dir or any of its descendants.
+func isInside(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func clean(path string) string {
+	path = filepath.Clean(filepath.FromSlash(path))
+	return strings.TrimPrefix(path, string(filepath.Separator))
+}
@@ -0,0 +1,95 @@
+package idmapfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&IDMapSuite{})
+
+type IDMapSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *IDMapSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New(), offset(1000), offset(2000)))
+}
+
+func (s *IDMapSuite) TestChown(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	err = s.FS.(billy.Change).Chown("foo", 0, 0)
+	c.Assert(err, IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+
+	owner, ok := fi.Sys().(*Owner)
+	c.Assert(ok, Equals, true)
+	c.Assert(owner.UID, Equals, 1000)
+	c.Assert(owner.GID, Equals, 2000)
+}
+
+func (s *IDMapSuite) TestChownKeepID(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.FS.(billy.Change).Chown("foo", 1, 2), IsNil)
+	c.Assert(s.FS.(billy.Change).Chown("foo", -1, 3), IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	assertOwner(c, fi, 1001, 2003)
+}
+
+func (s *IDMapSuite) TestLchown(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+	c.Assert(s.FS.Symlink("foo", "link"), IsNil)
+
+	c.Assert(s.FS.(billy.Change).Lchown("link", 5, 5), IsNil)
+
+	fi, err := s.FS.Lstat("link")
+	c.Assert(err, IsNil)
+	assertOwner(c, fi, 1005, 2005)
+
+	fi, err = s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	_, ok := fi.Sys().(*Owner)
+	c.Assert(ok, Equals, false)
+}
+
+func (s *IDMapSuite) TestChownRename(c *C) {
+	err := util.WriteFile(s.FS, "foo/bar", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+	c.Assert(s.FS.(billy.Change).Chown("foo/bar", 1, 1), IsNil)
+
+	c.Assert(s.FS.Rename("foo", "qux"), IsNil)
+
+	fi, err := s.FS.Stat("qux/bar")
+	c.Assert(err, IsNil)
+	assertOwner(c, fi, 1001, 2001)
+}
+
+func assertOwner(c *C, fi os.FileInfo, uid, gid int) {
+	owner, ok := fi.Sys().(*Owner)
+	c.Assert(ok, Equals, true)
+	c.Assert(owner.UID, Equals, uid)
+	c.Assert(owner.GID, Equals, gid)
+}
+
+func offset(n int) func(int) int {
+	return func(id int) int {
+		return id + n
+	}
+}
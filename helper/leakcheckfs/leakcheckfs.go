@@ -0,0 +1,140 @@
+package leakcheckfs
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// LeakCheck is a helper that tracks the files opened through it, along with
+// the stack where each one was opened, to find the files not closed, eg.: at
+// the end of a test.
+type LeakCheck struct {
+	billy.Filesystem
+	t *tracker
+}
+
+// New creates a new filesystem wrapping up 'fs', the returned function
+// reports the files opened through the filesystem, or any chroot of it, still
+// not closed. Each report holds the name of the file and the stack where it
+// was opened, in the order the files were opened.
+func New(fs billy.Filesystem) (billy.Filesystem, func() []string) {
+	t := &tracker{open: make(map[uint64]string)}
+	return &LeakCheck{Filesystem: fs, t: t}, t.report
+}
+
+func (fs *LeakCheck) Create(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Create(filename)
+	return fs.t.track(f, err)
+}
+
+func (fs *LeakCheck) Open(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Open(filename)
+	return fs.t.track(f, err)
+}
+
+func (fs *LeakCheck) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	return fs.t.track(f, err)
+}
+
+func (fs *LeakCheck) TempFile(dir, prefix string) (billy.File, error) {
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+	return fs.t.track(f, err)
+}
+
+func (fs *LeakCheck) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LeakCheck{Filesystem: chroot, t: fs.t}, nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *LeakCheck) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+type tracker struct {
+	m    sync.Mutex
+	next uint64
+	open map[uint64]string
+}
+
+func (t *tracker) track(f billy.File, err error) (billy.File, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	id := t.next
+	t.next++
+	t.open[id] = fmt.Sprintf("%s opened at:\n%s", f.Name(), stack())
+
+	return &file{File: f, t: t, id: id}, nil
+}
+
+func (t *tracker) untrack(id uint64) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	delete(t.open, id)
+}
+
+func (t *tracker) report() []string {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	ids := make([]uint64, 0, len(t.open))
+	for id := range t.open {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var leaks []string
+	for _, id := range ids {
+		leaks = append(leaks, t.open[id])
+	}
+
+	return leaks
+}
+
+// stack returns the stack of the caller of the filesystem method.
+func stack() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(4, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+
+	return b.String()
+}
+
+type file struct {
+	billy.File
+	t    *tracker
+	id   uint64
+	once sync.Once
+}
+
+func (f *file) Close() error {
+	f.once.Do(func() { f.t.untrack(f.id) })
+	return f.File.Close()
+}
@@ -0,0 +1,50 @@
+package leakcheckfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&LeakCheckSuite{})
+
+type LeakCheckSuite struct {
+	test.FilesystemSuite
+	leaks func() []string
+}
+
+func (s *LeakCheckSuite) SetUpTest(c *C) {
+	fs, leaks := New(memfs.New())
+	s.FilesystemSuite = test.NewFilesystemSuite(fs)
+	s.leaks = leaks
+}
+
+func (s *LeakCheckSuite) TestLeaks(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+	c.Assert(s.leaks(), HasLen, 0)
+
+	f, err := s.FS.Open("foo")
+	c.Assert(err, IsNil)
+
+	qux, err := s.FS.Chroot("qux")
+	c.Assert(err, IsNil)
+	_, err = qux.Create("bar")
+	c.Assert(err, IsNil)
+
+	leaks := s.leaks()
+	c.Assert(leaks, HasLen, 2)
+	c.Assert(strings.HasPrefix(leaks[0], "foo opened at:\n"), Equals, true)
+	c.Assert(strings.Contains(leaks[0], "leakcheckfs.(*LeakCheckSuite).TestLeaks"), Equals, true)
+	c.Assert(strings.HasPrefix(leaks[1], "bar opened at:\n"), Equals, true)
+
+	c.Assert(f.Close(), IsNil)
+	c.Assert(s.leaks(), HasLen, 1)
+}
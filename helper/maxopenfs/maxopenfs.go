@@ -0,0 +1,138 @@
+package maxopenfs
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// ErrTooManyOpenFiles is returned when opening a file with the maximum number
+// of files already open, if Options.NoWait is set.
+var ErrTooManyOpenFiles = errors.New("too many open files")
+
+// Options holds the optional settings of a MaxOpen filesystem.
+type Options struct {
+	// NoWait makes opening a file fail with ErrTooManyOpenFiles when the
+	// maximum number of files is open, instead of waiting for one of them to
+	// be closed.
+	NoWait bool
+}
+
+// MaxOpen is a helper that bounds the number of files open at the same time
+// through it, eg.: to avoid exhausting the file descriptors of the process,
+// regardless of its limits, under load.
+type MaxOpen struct {
+	billy.Filesystem
+	open   chan struct{}
+	noWait bool
+}
+
+// New creates a new filesystem wrapping up 'fs', opening a file with max files
+// already open, through it or any chroot of it, waits until one is closed.
+// A max lower than 1 is taken as 1, so a file can always be open.
+func New(fs billy.Filesystem, max int) billy.Filesystem {
+	return NewWithOptions(fs, max, Options{})
+}
+
+// NewWithOptions creates a new filesystem like New, with the given options.
+func NewWithOptions(fs billy.Filesystem, max int, o Options) billy.Filesystem {
+	if max < 1 {
+		max = 1
+	}
+
+	return &MaxOpen{
+		Filesystem: fs,
+		open:       make(chan struct{}, max),
+		noWait:     o.NoWait,
+	}
+}
+
+func (fs *MaxOpen) Create(filename string) (billy.File, error) {
+	if err := fs.acquire("open", filename); err != nil {
+		return nil, err
+	}
+
+	return fs.wrapFile(fs.Filesystem.Create(filename))
+}
+
+func (fs *MaxOpen) Open(filename string) (billy.File, error) {
+	if err := fs.acquire("open", filename); err != nil {
+		return nil, err
+	}
+
+	return fs.wrapFile(fs.Filesystem.Open(filename))
+}
+
+func (fs *MaxOpen) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if err := fs.acquire("open", filename); err != nil {
+		return nil, err
+	}
+
+	return fs.wrapFile(fs.Filesystem.OpenFile(filename, flag, perm))
+}
+
+func (fs *MaxOpen) TempFile(dir, prefix string) (billy.File, error) {
+	if err := fs.acquire("createtemp", dir); err != nil {
+		return nil, err
+	}
+
+	return fs.wrapFile(fs.Filesystem.TempFile(dir, prefix))
+}
+
+func (fs *MaxOpen) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MaxOpen{Filesystem: chroot, open: fs.open, noWait: fs.noWait}, nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *MaxOpen) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// acquire takes a slot for a file to be opened, waiting for one if they are
+// all taken, unless noWait is set.
+func (fs *MaxOpen) acquire(op, path string) error {
+	if !fs.noWait {
+		fs.open <- struct{}{}
+		return nil
+	}
+
+	select {
+	case fs.open <- struct{}{}:
+		return nil
+	default:
+		return &os.PathError{Op: op, Path: path, Err: ErrTooManyOpenFiles}
+	}
+}
+
+func (fs *MaxOpen) release() {
+	<-fs.open
+}
+
+func (fs *MaxOpen) wrapFile(f billy.File, err error) (billy.File, error) {
+	if err != nil {
+		fs.release()
+		return nil, err
+	}
+
+	return &file{File: f, fs: fs}, nil
+}
+
+type file struct {
+	billy.File
+	fs   *MaxOpen
+	once sync.Once
+}
+
+// Close closes the file, its slot is released even if closing it fails.
+func (f *file) Close() error {
+	err := f.File.Close()
+	f.once.Do(f.fs.release)
+	return err
+}
@@ -0,0 +1,111 @@
+package maxopenfs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&MaxOpenSuite{})
+
+type MaxOpenSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *MaxOpenSuite) SetUpTest(c *C) {
+	fs := NewWithOptions(memfs.New(), 1000, Options{NoWait: true})
+	s.FilesystemSuite = test.NewFilesystemSuite(fs)
+}
+
+func (s *MaxOpenSuite) TestNoWait(c *C) {
+	fs := NewWithOptions(memfs.New(), 2, Options{NoWait: true})
+	chroot, err := fs.Chroot("foo")
+	c.Assert(err, IsNil)
+
+	f1, err := fs.Create("bar")
+	c.Assert(err, IsNil)
+	f2, err := chroot.Create("bar")
+	c.Assert(err, IsNil)
+
+	_, err = fs.Open("bar")
+	c.Assert(errors.Is(err, ErrTooManyOpenFiles), Equals, true)
+	_, err = chroot.TempFile("", "qux")
+	c.Assert(errors.Is(err, ErrTooManyOpenFiles), Equals, true)
+
+	c.Assert(f1.Close(), IsNil)
+	c.Assert(f1.Close(), NotNil)
+
+	f3, err := fs.Open("bar")
+	c.Assert(err, IsNil)
+
+	_, err = fs.Open("bar")
+	c.Assert(errors.Is(err, ErrTooManyOpenFiles), Equals, true)
+
+	c.Assert(f2.Close(), IsNil)
+	c.Assert(f3.Close(), IsNil)
+}
+
+func (s *MaxOpenSuite) TestMaxLowerThanOne(c *C) {
+	for _, max := range []int{0, -1} {
+		fs := NewWithOptions(memfs.New(), max, Options{NoWait: true})
+
+		f, err := fs.Create("foo")
+		c.Assert(err, IsNil)
+
+		_, err = fs.Open("foo")
+		c.Assert(errors.Is(err, ErrTooManyOpenFiles), Equals, true)
+		c.Assert(f.Close(), IsNil)
+	}
+
+	f, err := New(memfs.New(), 0).Create("foo")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MaxOpenSuite) TestFailedOpenReleases(c *C) {
+	fs := NewWithOptions(memfs.New(), 1, Options{NoWait: true})
+
+	_, err := fs.Open("missing")
+	c.Assert(err, NotNil)
+
+	f, err := fs.Create("foo")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MaxOpenSuite) TestWait(c *C) {
+	fs := New(memfs.New(), 1)
+
+	f, err := fs.Create("foo")
+	c.Assert(err, IsNil)
+
+	opened := make(chan billy.File)
+	go func() {
+		f, err := fs.Open("foo")
+		c.Check(err, IsNil)
+		opened <- f
+	}()
+
+	select {
+	case <-opened:
+		c.Fatal("opened with the maximum of files open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.Assert(f.Close(), IsNil)
+
+	select {
+	case f := <-opened:
+		c.Assert(f.Close(), IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for a file to be opened")
+	}
+}
@@ -0,0 +1,461 @@
+package mountfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/helper/chroot"
+)
+
+const separator = string(filepath.Separator)
+
+var (
+	// ErrMounted is returned by Mount when the prefix is already in use.
+	ErrMounted = errors.New("prefix already mounted")
+	// ErrCrossMount is returned by Rename when both paths belong to different
+	// mounts.
+	ErrCrossMount = errors.New("rename across mount points")
+	// ErrBusy is returned when renaming or removing a mount point.
+	ErrBusy = errors.New("mount point busy")
+)
+
+// MountFS is a filesystem composing several filesystems, each one mounted at
+// a different prefix, eg.: "/data" served by one filesystem and "/cache" by
+// another.
+//
+// Every operation is dispatched to the filesystem mounted at the longest
+// prefix matching the path, with the prefix stripped from it. The directories
+// leading to the mount points are synthesized when not served by any mount,
+// and ReadDir lists the mount points along with the entries of the directory.
+type MountFS struct {
+	m      sync.RWMutex
+	mounts map[string]billy.Filesystem
+}
+
+// New returns a new MountFS with nothing mounted on it.
+func New() *MountFS {
+	return &MountFS{mounts: make(map[string]billy.Filesystem)}
+}
+
+// Mount mounts fs at the given prefix, "/" mounts it as the root, serving
+// every path not matched by other mounts. ErrMounted is returned if the prefix
+// is already in use.
+func (fs *MountFS) Mount(prefix string, mounted billy.Filesystem) error {
+	if mounted == nil {
+		return &os.PathError{Op: "mount", Path: prefix, Err: os.ErrInvalid}
+	}
+
+	p := clean(prefix)
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	if _, ok := fs.mounts[p]; ok {
+		return &os.PathError{Op: "mount", Path: prefix, Err: ErrMounted}
+	}
+
+	fs.mounts[p] = mounted
+	return nil
+}
+
+func (fs *MountFS) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *MountFS) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *MountFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	prefix, m, path, err := fs.resolve("open", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := m.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFile(f, prefix), nil
+}
+
+func (fs *MountFS) Stat(filename string) (os.FileInfo, error) {
+	return fs.stat("stat", filename, func(m billy.Filesystem, path string) (os.FileInfo, error) {
+		return m.Stat(path)
+	})
+}
+
+func (fs *MountFS) Lstat(filename string) (os.FileInfo, error) {
+	return fs.stat("lstat", filename, func(m billy.Filesystem, path string) (os.FileInfo, error) {
+		return m.Lstat(path)
+	})
+}
+
+func (fs *MountFS) stat(
+	op, filename string,
+	stat func(billy.Filesystem, string) (os.FileInfo, error),
+) (os.FileInfo, error) {
+	p := clean(filename)
+
+	fs.m.RLock()
+	m, ok := fs.mounts[p]
+	mounted, _, path, routed := fs.match(p)
+	synthetic := fs.hasMountBelow(p)
+	fs.m.RUnlock()
+
+	// a mount point is reported with its own name, the one of the root of the
+	// mounted filesystem is meaningless here. Some filesystems, as memfs, have
+	// no root until something is created on them.
+	if ok {
+		fi, err := stat(m, separator)
+		if os.IsNotExist(err) {
+			return &dirInfo{name: name(p)}, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &fileInfo{FileInfo: fi, name: name(p)}, nil
+	}
+
+	if routed {
+		fi, err := stat(mounted, path)
+		if err == nil || !synthetic || !os.IsNotExist(err) {
+			return fi, err
+		}
+	}
+
+	if synthetic {
+		return &dirInfo{name: name(p)}, nil
+	}
+
+	return nil, &os.PathError{Op: op, Path: filename, Err: os.ErrNotExist}
+}
+
+func (fs *MountFS) Rename(from, to string) error {
+	pfrom, mfrom, oldpath, err := fs.resolve("rename", from)
+	if err != nil {
+		return err
+	}
+
+	pto, _, newpath, err := fs.resolve("rename", to)
+	if err != nil {
+		return err
+	}
+
+	if pfrom != pto {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: ErrCrossMount}
+	}
+
+	if fs.isBusy(clean(from)) || fs.isBusy(clean(to)) {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: ErrBusy}
+	}
+
+	return mfrom.Rename(oldpath, newpath)
+}
+
+func (fs *MountFS) Remove(filename string) error {
+	_, m, path, err := fs.resolve("remove", filename)
+	if err != nil {
+		return err
+	}
+
+	if fs.isBusy(clean(filename)) {
+		return &os.PathError{Op: "remove", Path: filename, Err: ErrBusy}
+	}
+
+	return m.Remove(path)
+}
+
+func (fs *MountFS) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+func (fs *MountFS) TempFile(dir, prefix string) (billy.File, error) {
+	mprefix, m, path, err := fs.resolve("tempfile", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := m.TempFile(path, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFile(f, mprefix), nil
+}
+
+// ReadDir returns the entries of the directory, the mount points found on it
+// are listed as directories, hiding any entry with the same name.
+func (fs *MountFS) ReadDir(path string) ([]os.FileInfo, error) {
+	p := clean(path)
+
+	fs.m.RLock()
+	m, _, mpath, routed := fs.match(p)
+	points := fs.mountsAt(p)
+	fs.m.RUnlock()
+
+	var entries []os.FileInfo
+	if routed {
+		var err error
+		entries, err = m.ReadDir(mpath)
+		missing := os.IsNotExist(err) && (mpath == separator || len(points) != 0)
+		if err != nil && !missing {
+			return nil, err
+		}
+	} else if len(points) == 0 {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrNotExist}
+	}
+
+	if len(points) == 0 {
+		return entries, nil
+	}
+
+	result := make([]os.FileInfo, 0, len(entries)+len(points))
+	for _, fi := range entries {
+		if _, ok := points[fi.Name()]; !ok {
+			result = append(result, fi)
+		}
+	}
+
+	for n := range points {
+		fi, err := fs.Stat(fs.Join(separator, p, n))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, fi)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name() < result[j].Name()
+	})
+
+	return result, nil
+}
+
+func (fs *MountFS) MkdirAll(filename string, perm os.FileMode) error {
+	p := clean(filename)
+
+	fs.m.RLock()
+	m, _, path, routed := fs.match(p)
+	synthetic := fs.hasMountBelow(p)
+	fs.m.RUnlock()
+
+	if !routed {
+		if synthetic {
+			return nil
+		}
+
+		return &os.PathError{Op: "mkdir", Path: filename, Err: os.ErrNotExist}
+	}
+
+	return m.MkdirAll(path, perm)
+}
+
+// Symlink creates the link on the mount serving it, the target is given as
+// is, so it is resolved by the mounted filesystem.
+func (fs *MountFS) Symlink(target, link string) error {
+	_, m, path, err := fs.resolve("symlink", link)
+	if err != nil {
+		return err
+	}
+
+	return m.Symlink(target, path)
+}
+
+func (fs *MountFS) Readlink(link string) (string, error) {
+	_, m, path, err := fs.resolve("readlink", link)
+	if err != nil {
+		return "", err
+	}
+
+	return m.Readlink(path)
+}
+
+func (fs *MountFS) Chroot(path string) (billy.Filesystem, error) {
+	return chroot.New(fs, fs.Join(separator, path)), nil
+}
+
+func (fs *MountFS) Root() string {
+	return separator
+}
+
+// Capabilities implements the Capable interface, returning the capabilities
+// supported by all the mounted filesystems.
+func (fs *MountFS) Capabilities() billy.Capability {
+	fs.m.RLock()
+	defer fs.m.RUnlock()
+
+	caps := billy.AllCapabilities
+	for _, m := range fs.mounts {
+		caps &= billy.Capabilities(m)
+	}
+
+	return caps
+}
+
+// resolve returns the mount serving the given path, with its prefix and the
+// path translated to it. An *os.PathError wrapping os.ErrNotExist is returned
+// if no mount serves it.
+func (fs *MountFS) resolve(op, path string) (string, billy.Filesystem, string, error) {
+	p := clean(path)
+
+	fs.m.RLock()
+	m, prefix, rel, ok := fs.match(p)
+	fs.m.RUnlock()
+
+	if !ok {
+		return "", nil, "", &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+
+	return prefix, m, rel, nil
+}
+
+// match returns the filesystem mounted at the longest prefix of path, along
+// with the prefix and the path relative to it. It should be called with the
+// lock held.
+func (fs *MountFS) match(path string) (billy.Filesystem, string, string, bool) {
+	for p := path; ; p = parent(p) {
+		if m, ok := fs.mounts[p]; ok {
+			return m, p, relative(p, path), true
+		}
+
+		if p == "" {
+			return nil, "", "", false
+		}
+	}
+}
+
+// mountsAt returns the names of the mount points, and the synthesized
+// directories leading to them, found directly on the given directory. It
+// should be called with the lock held.
+func (fs *MountFS) mountsAt(dir string) map[string]struct{} {
+	names := make(map[string]struct{})
+	for p := range fs.mounts {
+		if p == "" || !isInside(p, dir) || p == dir {
+			continue
+		}
+
+		rel := p
+		if dir != "" {
+			rel = p[len(dir)+1:]
+		}
+
+		names[strings.SplitN(rel, separator, 2)[0]] = struct{}{}
+	}
+
+	return names
+}
+
+// hasMountBelow returns true if there is a mount point at path or any of its
+// descendants. It should be called with the lock held.
+func (fs *MountFS) hasMountBelow(path string) bool {
+	for p := range fs.mounts {
+		if isInside(p, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isBusy returns true if the given path is a mount point, or a directory
+// leading to one.
+func (fs *MountFS) isBusy(path string) bool {
+	fs.m.RLock()
+	defer fs.m.RUnlock()
+
+	return fs.hasMountBelow(path)
+}
+
+// clean returns the given path cleaned and relative to the root, the root
+// itself is "".
+func clean(path string) string {
+	path = filepath.Clean(separator + filepath.FromSlash(path))
+	return strings.TrimPrefix(path, separator)
+}
+
+// isInside returns true if path is dir or any of its descendants, both paths
+// cleaned with clean.
+func isInside(path, dir string) bool {
+	return dir == "" || path == dir || strings.HasPrefix(path, dir+separator)
+}
+
+func parent(path string) string {
+	i := strings.LastIndex(path, separator)
+	if i < 0 {
+		return ""
+	}
+
+	return path[:i]
+}
+
+// relative returns path relative to the given prefix, the root of the mounted
+// filesystem if both are equal.
+func relative(prefix, path string) string {
+	rel := strings.TrimPrefix(path[len(prefix):], separator)
+	if rel == "" {
+		return separator
+	}
+
+	return rel
+}
+
+func name(path string) string {
+	if path == "" {
+		return separator
+	}
+
+	return filepath.Base(path)
+}
+
+type file struct {
+	billy.File
+	name string
+}
+
+func newFile(f billy.File, prefix string) billy.File {
+	return &file{
+		File: f,
+		name: filepath.Join(prefix, f.Name()),
+	}
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+// Underlying returns the file opened by the mounted filesystem.
+func (f *file) Underlying() billy.File {
+	return f.File
+}
+
+type fileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+// dirInfo describes the directories synthesized leading to the mount points.
+type dirInfo struct {
+	name string
+}
+
+func (fi *dirInfo) Name() string       { return fi.name }
+func (fi *dirInfo) Size() int64        { return 0 }
+func (fi *dirInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (fi *dirInfo) ModTime() time.Time { return time.Time{} }
+func (fi *dirInfo) IsDir() bool        { return true }
+func (fi *dirInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,156 @@
+package mountfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&MountFSSuite{})
+
+type MountFSSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *MountFSSuite) SetUpTest(c *C) {
+	fs := New()
+	c.Assert(fs.Mount("/", memfs.New()), IsNil)
+
+	s.FilesystemSuite = test.NewFilesystemSuite(fs)
+}
+
+func (s *MountFSSuite) TestLongestPrefix(c *C) {
+	fs, root, data, cache := newMounts(c)
+
+	c.Assert(util.WriteFile(fs, "/foo", []byte("root"), 0644), IsNil)
+	c.Assert(util.WriteFile(fs, "/data/foo", []byte("data"), 0644), IsNil)
+	c.Assert(util.WriteFile(fs, "/data/cache/foo", []byte("cache"), 0644), IsNil)
+
+	assertContent(c, root, "foo", "root")
+	assertContent(c, data, "foo", "data")
+	assertContent(c, cache, "foo", "cache")
+
+	_, err := root.Stat("data")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = data.Stat("cache")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *MountFSSuite) TestFileName(c *C) {
+	fs, _, _, _ := newMounts(c)
+
+	f, err := fs.Create("/data/cache/foo")
+	c.Assert(err, IsNil)
+	c.Assert(f.Name(), Equals, filepath.Join("data", "cache", "foo"))
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MountFSSuite) TestReadDirRoot(c *C) {
+	fs, root, _, _ := newMounts(c)
+	c.Assert(util.WriteFile(root, "foo", nil, 0644), IsNil)
+
+	fis, err := fs.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(names(fis), DeepEquals, []string{"data", "foo"})
+	c.Assert(fis[0].IsDir(), Equals, true)
+
+	fis, err = fs.ReadDir("/data")
+	c.Assert(err, IsNil)
+	c.Assert(names(fis), DeepEquals, []string{"cache"})
+}
+
+func (s *MountFSSuite) TestSynthesizedDir(c *C) {
+	fs := New()
+	c.Assert(fs.Mount("/var/data", memfs.New()), IsNil)
+	c.Assert(fs.Mount("/var/cache", memfs.New()), IsNil)
+
+	fis, err := fs.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(names(fis), DeepEquals, []string{"var"})
+
+	fis, err = fs.ReadDir("/var")
+	c.Assert(err, IsNil)
+	c.Assert(names(fis), DeepEquals, []string{"cache", "data"})
+
+	fi, err := fs.Stat("/var")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+	c.Assert(fi.Name(), Equals, "var")
+
+	fi, err = fs.Stat("/var/data")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+	c.Assert(fi.Name(), Equals, "data")
+
+	_, err = fs.Create("/foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = fs.ReadDir("/foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *MountFSSuite) TestMountTwice(c *C) {
+	fs := New()
+	c.Assert(fs.Mount("/data", memfs.New()), IsNil)
+
+	err := fs.Mount("data/", memfs.New())
+	c.Assert(errors.Is(err, ErrMounted), Equals, true)
+}
+
+func (s *MountFSSuite) TestRenameAcrossMounts(c *C) {
+	fs, _, _, _ := newMounts(c)
+	c.Assert(util.WriteFile(fs, "/data/foo", nil, 0644), IsNil)
+
+	err := fs.Rename("/data/foo", "/foo")
+	c.Assert(errors.Is(err, ErrCrossMount), Equals, true)
+
+	c.Assert(fs.Rename("/data/foo", "/data/bar"), IsNil)
+	_, err = fs.Stat("/data/bar")
+	c.Assert(err, IsNil)
+}
+
+func (s *MountFSSuite) TestRemoveMountPoint(c *C) {
+	fs, _, _, _ := newMounts(c)
+
+	err := fs.Remove("/data/cache")
+	c.Assert(errors.Is(err, ErrBusy), Equals, true)
+
+	err = fs.Rename("/data", "/foo")
+	c.Assert(err, NotNil)
+}
+
+func newMounts(c *C) (fs *MountFS, root, data, cache billy.Filesystem) {
+	root, data, cache = memfs.New(), memfs.New(), memfs.New()
+
+	fs = New()
+	c.Assert(fs.Mount("/", root), IsNil)
+	c.Assert(fs.Mount("/data", data), IsNil)
+	c.Assert(fs.Mount("/data/cache", cache), IsNil)
+
+	return fs, root, data, cache
+}
+
+func assertContent(c *C, fs billy.Filesystem, filename, expected string) {
+	content, err := util.ReadFileString(fs, filename)
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, expected)
+}
+
+func names(fis []os.FileInfo) []string {
+	var result []string
+	for _, fi := range fis {
+		result = append(result, fi.Name())
+	}
+
+	return result
+}
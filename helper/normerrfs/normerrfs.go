@@ -0,0 +1,124 @@
+package normerrfs
+
+import (
+	"errors"
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// NormErr is a helper that translates the errors returned by the underlying
+// filesystem to the common billy errors: billy.ErrNotExist, billy.ErrExist,
+// billy.ErrPermission, billy.ErrReadOnly and billy.ErrCrossedBoundary. Any
+// other error is returned unchanged.
+type NormErr struct {
+	billy.Filesystem
+}
+
+// New creates a new filesystem wrapping up the given 'fs'. The errors matching
+// any of the common billy errors, according to errors.Is, are replaced by it.
+// The operation and paths of *os.PathError and *os.LinkError are kept.
+func New(fs billy.Filesystem) billy.Filesystem {
+	return &NormErr{Filesystem: fs}
+}
+
+func (fs *NormErr) Create(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Create(filename)
+	return f, normalize(err)
+}
+
+func (fs *NormErr) Open(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Open(filename)
+	return f, normalize(err)
+}
+
+func (fs *NormErr) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	return f, normalize(err)
+}
+
+func (fs *NormErr) Stat(filename string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Stat(filename)
+	return fi, normalize(err)
+}
+
+func (fs *NormErr) Rename(from, to string) error {
+	return normalize(fs.Filesystem.Rename(from, to))
+}
+
+func (fs *NormErr) Remove(filename string) error {
+	return normalize(fs.Filesystem.Remove(filename))
+}
+
+func (fs *NormErr) TempFile(dir, prefix string) (billy.File, error) {
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+	return f, normalize(err)
+}
+
+func (fs *NormErr) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.Filesystem.ReadDir(path)
+	return fis, normalize(err)
+}
+
+func (fs *NormErr) MkdirAll(filename string, perm os.FileMode) error {
+	return normalize(fs.Filesystem.MkdirAll(filename, perm))
+}
+
+func (fs *NormErr) Lstat(filename string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Lstat(filename)
+	return fi, normalize(err)
+}
+
+func (fs *NormErr) Symlink(target, link string) error {
+	return normalize(fs.Filesystem.Symlink(target, link))
+}
+
+func (fs *NormErr) Readlink(link string) (string, error) {
+	target, err := fs.Filesystem.Readlink(link)
+	return target, normalize(err)
+}
+
+func (fs *NormErr) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, normalize(err)
+	}
+
+	return New(chroot), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *NormErr) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+var common = []error{
+	billy.ErrNotExist,
+	billy.ErrExist,
+	billy.ErrPermission,
+	billy.ErrReadOnly,
+	billy.ErrCrossedBoundary,
+}
+
+func normalize(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, target := range common {
+		if !errors.Is(err, target) {
+			continue
+		}
+
+		switch e := err.(type) {
+		case *os.PathError:
+			return &os.PathError{Op: e.Op, Path: e.Path, Err: target}
+		case *os.LinkError:
+			return &os.LinkError{Op: e.Op, Old: e.Old, New: e.New, Err: target}
+		default:
+			return target
+		}
+	}
+
+	return err
+}
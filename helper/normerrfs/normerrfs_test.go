@@ -0,0 +1,61 @@
+package normerrfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&NormErrSuite{})
+
+type NormErrSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *NormErrSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New()))
+}
+
+func (s *NormErrSuite) TestNormalize(c *C) {
+	cases := []struct {
+		err      error
+		expected error
+	}{
+		{nil, nil},
+		{os.ErrNotExist, billy.ErrNotExist},
+		{fmt.Errorf("wrapped: %w", billy.ErrReadOnly), billy.ErrReadOnly},
+		{
+			&os.PathError{Op: "open", Path: "foo", Err: syscall.ENOENT},
+			&os.PathError{Op: "open", Path: "foo", Err: billy.ErrNotExist},
+		},
+		{
+			&os.LinkError{Op: "rename", Old: "foo", New: "bar", Err: syscall.EEXIST},
+			&os.LinkError{Op: "rename", Old: "foo", New: "bar", Err: billy.ErrExist},
+		},
+		{
+			&os.PathError{Op: "open", Path: "foo", Err: fmt.Errorf("boundary: %w", billy.ErrCrossedBoundary)},
+			&os.PathError{Op: "open", Path: "foo", Err: billy.ErrCrossedBoundary},
+		},
+		{billy.ErrNotSupported, billy.ErrNotSupported},
+	}
+
+	for _, t := range cases {
+		c.Assert(normalize(t.err), DeepEquals, t.expected)
+	}
+}
+
+func (s *NormErrSuite) TestNotExist(c *C) {
+	_, err := s.FS.Open("foo")
+	c.Assert(errors.Is(err, billy.ErrNotExist), Equals, true)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
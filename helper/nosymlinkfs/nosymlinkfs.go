@@ -0,0 +1,187 @@
+package nosymlinkfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+var (
+	// ErrSymlinkNotSupported is returned when creating a symlink, or when a
+	// path goes through a symlink already present in the filesystem.
+	ErrSymlinkNotSupported = errors.New("symlinks not supported")
+
+	errNotLink = errors.New("not a link")
+)
+
+// NoSymlink is a helper that forbids symlinks, eg.: to extract untrusted
+// archives without the risk of escaping the destination through a symlink.
+//
+// Symlinks can't be created, and the symlinks already present in the
+// underlying filesystem are never followed: any operation on a path going
+// through one fails with ErrSymlinkNotSupported. The operations not following
+// the last element of the path, like Lstat, Remove or Rename, can still be
+// used over the symlinks themselves. The paths are checked before calling the
+// underlying filesystem, so symlinks created concurrently, by other means than
+// this filesystem, aren't detected.
+type NoSymlink struct {
+	billy.Filesystem
+}
+
+// New creates a new filesystem wrapping up the given 'fs', with the symlinks
+// forbidden.
+func New(fs billy.Filesystem) billy.Filesystem {
+	return &NoSymlink{Filesystem: fs}
+}
+
+func (fs *NoSymlink) Create(filename string) (billy.File, error) {
+	if err := fs.check("open", filename, true); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Create(filename)
+}
+
+func (fs *NoSymlink) Open(filename string) (billy.File, error) {
+	if err := fs.check("open", filename, true); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Open(filename)
+}
+
+func (fs *NoSymlink) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if err := fs.check("open", filename, true); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.OpenFile(filename, flag, perm)
+}
+
+func (fs *NoSymlink) Stat(filename string) (os.FileInfo, error) {
+	if err := fs.check("stat", filename, true); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Stat(filename)
+}
+
+func (fs *NoSymlink) Lstat(filename string) (os.FileInfo, error) {
+	if err := fs.check("lstat", filename, false); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Lstat(filename)
+}
+
+func (fs *NoSymlink) Rename(from, to string) error {
+	if err := fs.check("rename", from, false); err != nil {
+		return err
+	}
+
+	if err := fs.check("rename", to, false); err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Rename(from, to)
+}
+
+func (fs *NoSymlink) Remove(filename string) error {
+	if err := fs.check("remove", filename, false); err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Remove(filename)
+}
+
+func (fs *NoSymlink) TempFile(dir, prefix string) (billy.File, error) {
+	if err := fs.check("createtemp", dir, true); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.TempFile(dir, prefix)
+}
+
+func (fs *NoSymlink) ReadDir(path string) ([]os.FileInfo, error) {
+	if err := fs.check("readdir", path, true); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.ReadDir(path)
+}
+
+func (fs *NoSymlink) MkdirAll(filename string, perm os.FileMode) error {
+	if err := fs.check("mkdir", filename, true); err != nil {
+		return err
+	}
+
+	return fs.Filesystem.MkdirAll(filename, perm)
+}
+
+// Symlink always returns ErrSymlinkNotSupported.
+func (fs *NoSymlink) Symlink(target, link string) error {
+	return &os.LinkError{Op: "symlink", Old: target, New: link, Err: ErrSymlinkNotSupported}
+}
+
+// Readlink always fails, as if the link was not a symlink.
+func (fs *NoSymlink) Readlink(link string) (string, error) {
+	if _, err := fs.Lstat(link); err != nil {
+		return "", err
+	}
+
+	return "", &os.PathError{Op: "readlink", Path: link, Err: errNotLink}
+}
+
+func (fs *NoSymlink) Chroot(path string) (billy.Filesystem, error) {
+	if err := fs.check("chroot", path, true); err != nil {
+		return nil, err
+	}
+
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot), nil
+}
+
+// Capabilities implements the Capable interface, the ones of the underlying
+// filesystem without SymlinkCapability, since Symlink always fails.
+func (fs *NoSymlink) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem) &^ billy.SymlinkCapability
+}
+
+// check returns an error if any element of the given path is a symlink. The
+// last element is only checked if follow is true.
+func (fs *NoSymlink) check(op, path string, follow bool) error {
+	parts := strings.Split(filepath.Clean(filepath.FromSlash(path)), string(filepath.Separator))
+
+	var current string
+	for i, part := range parts {
+		if part == "" || part == "." {
+			continue
+		}
+
+		current = fs.Join(current, part)
+		if i == len(parts)-1 && !follow {
+			return nil
+		}
+
+		fi, err := fs.Filesystem.Lstat(current)
+		if err != nil {
+			// the rest of the path doesn't exist, so there are no symlinks
+			// to be followed, the error is left to the underlying
+			// filesystem.
+			return nil
+		}
+
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return &os.PathError{Op: op, Path: path, Err: ErrSymlinkNotSupported}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package nosymlinkfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&NoSymlinkSuite{})
+
+// NoSymlinkSuite runs all the suites from test, but the symlink ones.
+type NoSymlinkSuite struct {
+	test.BasicSuite
+	test.DirSuite
+	test.TempFileSuite
+	test.ChrootSuite
+
+	FS         billy.Filesystem
+	underlying billy.Filesystem
+}
+
+func (s *NoSymlinkSuite) SetUpTest(c *C) {
+	s.underlying = memfs.New()
+	s.FS = New(s.underlying)
+
+	s.BasicSuite.FS = s.FS
+	s.DirSuite.FS = s.FS
+	s.TempFileSuite.FS = s.FS
+	s.ChrootSuite.FS = s.FS
+}
+
+func (s *NoSymlinkSuite) TestSymlink(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	err = s.FS.Symlink("foo", "link")
+	c.Assert(errors.Is(err, ErrSymlinkNotSupported), Equals, true)
+
+	_, err = s.underlying.Lstat("link")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *NoSymlinkSuite) TestReadlink(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+	c.Assert(s.underlying.Symlink("foo", "link"), IsNil)
+
+	_, err = s.FS.Readlink("link")
+	c.Assert(err, NotNil)
+
+	_, err = s.FS.Readlink("foo")
+	c.Assert(err, NotNil)
+
+	_, err = s.FS.Readlink("missing")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *NoSymlinkSuite) TestExistingSymlink(c *C) {
+	err := util.WriteFile(s.FS, "dir/foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+	c.Assert(s.underlying.Symlink("dir/foo", "link"), IsNil)
+	c.Assert(s.underlying.Symlink("dir", "dirlink"), IsNil)
+
+	_, err = s.FS.Open("link")
+	c.Assert(errors.Is(err, ErrSymlinkNotSupported), Equals, true)
+
+	_, err = s.FS.Stat("link")
+	c.Assert(errors.Is(err, ErrSymlinkNotSupported), Equals, true)
+
+	_, err = s.FS.Create("dirlink/bar")
+	c.Assert(errors.Is(err, ErrSymlinkNotSupported), Equals, true)
+
+	_, err = s.FS.ReadDir("/dirlink")
+	c.Assert(errors.Is(err, ErrSymlinkNotSupported), Equals, true)
+
+	_, err = s.FS.Lstat("dirlink/foo")
+	c.Assert(errors.Is(err, ErrSymlinkNotSupported), Equals, true)
+
+	_, err = s.FS.Chroot("dirlink")
+	c.Assert(errors.Is(err, ErrSymlinkNotSupported), Equals, true)
+
+	fi, err := s.FS.Lstat("link")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Mode()&os.ModeSymlink, Not(Equals), os.FileMode(0))
+
+	c.Assert(s.FS.Remove("link"), IsNil)
+	c.Assert(util.RemoveAll(s.FS, "dirlink"), IsNil)
+
+	_, err = s.underlying.Stat("dir/foo")
+	c.Assert(err, IsNil)
+}
+
+func (s *NoSymlinkSuite) TestCapabilities(c *C) {
+	c.Assert(billy.CapabilityCheck(s.underlying, billy.SymlinkCapability), Equals, true)
+	c.Assert(billy.CapabilityCheck(s.FS, billy.SymlinkCapability), Equals, false)
+	c.Assert(billy.CapabilityCheck(s.FS, billy.ReadAndWriteCapability), Equals, true)
+}
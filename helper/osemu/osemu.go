@@ -0,0 +1,304 @@
+package osemu
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// GOOS is an operating system whose path semantics are emulated, named as in
+// runtime.GOOS.
+type GOOS string
+
+const (
+	Linux   GOOS = "linux"
+	Darwin  GOOS = "darwin"
+	Windows GOOS = "windows"
+)
+
+// ErrInvalidName is returned when a path holds a name which is invalid on the
+// emulated operating system.
+var ErrInvalidName = errors.New("invalid name for the target OS")
+
+// windowsReserved are the device names reserved on Windows, with or without
+// extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// OSEmu is a helper that emulates the path semantics of an operating system
+// over the underlying filesystem, whatever the one running, eg.: to cover the
+// Windows path handling in the tests running on Linux. It's meant to be used
+// in testing.
+//
+// For Windows, both "\" and "/" are separators, the names are case-insensitive
+// and the reserved device names, as CON or NUL, the characters <>:"|?* and the
+// names ending with a space or a dot are rejected. For Darwin, the names are
+// case-insensitive. Any other target follows Linux, where only the NUL
+// character is rejected. The invalid names fail with an *os.PathError
+// wrapping ErrInvalidName.
+//
+// The names are case-insensitive by matching them with the existing ones,
+// keeping the case given on creation. The paths returned, by Join and as the
+// names of the files, keep the separator of the underlying filesystem, and
+// the volume names aren't supported.
+type OSEmu struct {
+	billy.Filesystem
+	target GOOS
+}
+
+// New creates a new filesystem wrapping up the given 'fs', emulating the path
+// semantics of target.
+func New(fs billy.Filesystem, target GOOS) billy.Filesystem {
+	return &OSEmu{Filesystem: fs, target: target}
+}
+
+func (fs *OSEmu) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *OSEmu) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *OSEmu) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	p, err := fs.path("open", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.OpenFile(p, flag, perm)
+}
+
+func (fs *OSEmu) Stat(filename string) (os.FileInfo, error) {
+	p, err := fs.path("stat", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Stat(p)
+}
+
+func (fs *OSEmu) Lstat(filename string) (os.FileInfo, error) {
+	p, err := fs.path("lstat", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Lstat(p)
+}
+
+// Rename renames from to to, on the case-insensitive targets a name can be
+// renamed to itself with a different case.
+func (fs *OSEmu) Rename(from, to string) error {
+	f, err := fs.path("rename", from)
+	if err != nil {
+		return err
+	}
+
+	t, err := fs.path("rename", to)
+	if err != nil {
+		return err
+	}
+
+	if elems := fs.split(to); t == f && fs.foldCase() && len(elems) != 0 {
+		t = fs.Filesystem.Join(filepath.Dir(f), elems[len(elems)-1])
+	}
+
+	return fs.Filesystem.Rename(f, t)
+}
+
+func (fs *OSEmu) Remove(filename string) error {
+	p, err := fs.path("remove", filename)
+	if err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Remove(p)
+}
+
+func (fs *OSEmu) TempFile(dir, prefix string) (billy.File, error) {
+	if !fs.validName(prefix) {
+		return nil, &os.PathError{Op: "tempfile", Path: prefix, Err: ErrInvalidName}
+	}
+
+	if dir == "" {
+		return fs.Filesystem.TempFile(dir, prefix)
+	}
+
+	p, err := fs.path("tempfile", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.TempFile(p, prefix)
+}
+
+func (fs *OSEmu) ReadDir(path string) ([]os.FileInfo, error) {
+	p, err := fs.path("readdir", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.ReadDir(p)
+}
+
+func (fs *OSEmu) MkdirAll(filename string, perm os.FileMode) error {
+	p, err := fs.path("mkdir", filename)
+	if err != nil {
+		return err
+	}
+
+	return fs.Filesystem.MkdirAll(p, perm)
+}
+
+// Symlink validates only the link, the target being stored as given, with
+// the separators of the target converted.
+func (fs *OSEmu) Symlink(target, link string) error {
+	p, err := fs.path("symlink", link)
+	if err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Symlink(filepath.FromSlash(fs.toSlash(target)), p)
+}
+
+func (fs *OSEmu) Readlink(link string) (string, error) {
+	p, err := fs.path("readlink", link)
+	if err != nil {
+		return "", err
+	}
+
+	return fs.Filesystem.Readlink(p)
+}
+
+func (fs *OSEmu) Chroot(path string) (billy.Filesystem, error) {
+	p, err := fs.path("chroot", path)
+	if err != nil {
+		return nil, err
+	}
+
+	chroot, err := fs.Filesystem.Chroot(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot, fs.target), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *OSEmu) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// path validates the given path on the target, returning it as a path of the
+// underlying filesystem, with the case of the existing names if the target
+// is case-insensitive. The ".." elements are left to the underlying
+// filesystem.
+func (fs *OSEmu) path(op, filename string) (string, error) {
+	resolved := ""
+	if strings.HasPrefix(fs.toSlash(filename), "/") {
+		resolved = string(filepath.Separator)
+	}
+
+	for _, elem := range fs.split(filename) {
+		if elem != ".." {
+			if !fs.validName(elem) {
+				return "", &os.PathError{Op: op, Path: filename, Err: ErrInvalidName}
+			}
+
+			if fs.foldCase() {
+				elem = fs.existingName(resolved, elem)
+			}
+		}
+
+		resolved = fs.Filesystem.Join(resolved, elem)
+	}
+
+	if resolved == "" {
+		return string(filepath.Separator), nil
+	}
+
+	return resolved, nil
+}
+
+// split returns the elements of the given path, once cleaned.
+func (fs *OSEmu) split(filename string) []string {
+	var elems []string
+	for _, elem := range strings.Split(path.Clean(fs.toSlash(filename)), "/") {
+		if elem != "" && elem != "." {
+			elems = append(elems, elem)
+		}
+	}
+
+	return elems
+}
+
+// existingName returns the name of the entry of dir matching name regardless
+// of the case, or name if none.
+func (fs *OSEmu) existingName(dir, name string) string {
+	if _, err := fs.Filesystem.Lstat(fs.Filesystem.Join(dir, name)); err == nil {
+		return name
+	}
+
+	if dir == "" {
+		dir = string(filepath.Separator)
+	}
+
+	entries, err := fs.Filesystem.ReadDir(dir)
+	if err != nil {
+		return name
+	}
+
+	for _, fi := range entries {
+		if strings.EqualFold(fi.Name(), name) {
+			return fi.Name()
+		}
+	}
+
+	return name
+}
+
+func (fs *OSEmu) validName(name string) bool {
+	if strings.ContainsRune(name, 0) {
+		return false
+	}
+
+	if fs.target != Windows {
+		return true
+	}
+
+	for _, r := range name {
+		if r < 32 || strings.ContainsRune(`<>:"|?*`, r) {
+			return false
+		}
+	}
+
+	if strings.HasSuffix(name, " ") || strings.HasSuffix(name, ".") {
+		return false
+	}
+
+	base := strings.SplitN(name, ".", 2)[0]
+	return !windowsReserved[strings.ToUpper(strings.TrimRight(base, " "))]
+}
+
+func (fs *OSEmu) foldCase() bool {
+	return fs.target == Windows || fs.target == Darwin
+}
+
+// toSlash returns the given path using "/" as the only separator.
+func (fs *OSEmu) toSlash(p string) string {
+	if fs.target == Windows {
+		return strings.ReplaceAll(p, `\`, "/")
+	}
+
+	return p
+}
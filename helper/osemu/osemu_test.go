@@ -0,0 +1,156 @@
+package osemu
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&LinuxSuite{})
+
+type LinuxSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *LinuxSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New(), Linux))
+}
+
+func (s *LinuxSuite) TestCaseSensitive(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = s.FS.Stat("FOO")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *LinuxSuite) TestWindowsNames(c *C) {
+	for _, name := range []string{"CON", `foo\bar`, "foo:bar", "foo."} {
+		c.Assert(util.WriteFile(s.FS, name, nil, 0644), IsNil, Commentf("name %q", name))
+	}
+
+	fis, err := s.FS.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 4)
+}
+
+func (s *LinuxSuite) TestNUL(c *C) {
+	_, err := s.FS.Create("foo\x00bar")
+	c.Assert(errors.Is(err, ErrInvalidName), Equals, true)
+}
+
+var _ = Suite(&WindowsSuite{})
+
+type WindowsSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *WindowsSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New(), Windows))
+}
+
+func (s *WindowsSuite) TestInvalidNames(c *C) {
+	names := []string{
+		"CON",
+		"nul.txt",
+		`foo\COM1`,
+		"lpt9 .log",
+		"foo:bar",
+		"foo<bar",
+		"foo?",
+		"foo\x01",
+		"foo.",
+		"foo ",
+		"foo./bar",
+	}
+
+	for _, name := range names {
+		_, err := s.FS.Create(name)
+		c.Assert(errors.Is(err, ErrInvalidName), Equals, true, Commentf("name %q", name))
+
+		err = s.FS.MkdirAll(name, 0755)
+		c.Assert(errors.Is(err, ErrInvalidName), Equals, true, Commentf("name %q", name))
+
+		perr, ok := err.(*os.PathError)
+		c.Assert(ok, Equals, true)
+		c.Assert(perr.Path, Equals, name)
+	}
+
+	fis, err := s.FS.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 0)
+}
+
+func (s *WindowsSuite) TestValidNames(c *C) {
+	for _, name := range []string{"CONSOLE", "COM10", "foo.con", ".foo"} {
+		c.Assert(util.WriteFile(s.FS, name, nil, 0644), IsNil, Commentf("name %q", name))
+	}
+}
+
+func (s *WindowsSuite) TestBackslash(c *C) {
+	err := util.WriteFile(s.FS, `foo\bar`, []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	fi, err := s.FS.Stat("foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "bar")
+}
+
+func (s *WindowsSuite) TestCaseInsensitive(c *C) {
+	err := util.WriteFile(s.FS, "Foo/Bar", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	fi, err := s.FS.Stat("FOO/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "Bar")
+
+	err = util.WriteFile(s.FS, "foo/BAR", []byte("qux"), 0644)
+	c.Assert(err, IsNil)
+
+	fis, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 1)
+	c.Assert(fis[0].Name(), Equals, "Bar")
+	c.Assert(fis[0].Size(), Equals, int64(3))
+}
+
+func (s *WindowsSuite) TestRenameCase(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.FS.Rename("foo", "FOO"), IsNil)
+
+	fis, err := s.FS.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 1)
+	c.Assert(fis[0].Name(), Equals, "FOO")
+}
+
+var _ = Suite(&DarwinSuite{})
+
+type DarwinSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *DarwinSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New(), Darwin))
+}
+
+func (s *DarwinSuite) TestCaseInsensitive(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = s.FS.Stat("FOO")
+	c.Assert(err, IsNil)
+
+	_, err = s.FS.Create(`foo\bar:CON`)
+	c.Assert(err, IsNil)
+}
@@ -5,15 +5,19 @@ import (
 	"path/filepath"
 
 	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
 )
 
 // Polyfill is a helper that implements all missing method from billy.Filesystem.
+// The optional interfaces, as billy.Chmod or billy.Exchange, aren't
+// implemented, so they can be told from the underlying filesystem, see
+// Underlying.
 type Polyfill struct {
 	billy.Basic
 	c capabilities
 }
 
-type capabilities struct{ tempfile, dir, symlink, chroot bool }
+type capabilities struct{ tempfile, dir, symlink, chroot, removeall bool }
 
 // New creates a new filesystem wrapping up 'fs' the intercepts all the calls
 // made and errors if fs doesn't implement any of the billy interfaces.
@@ -28,6 +32,7 @@ func New(fs billy.Basic) billy.Filesystem {
 	_, h.c.dir = h.Basic.(billy.Dir)
 	_, h.c.symlink = h.Basic.(billy.Symlink)
 	_, h.c.chroot = h.Basic.(billy.Chroot)
+	_, h.c.removeall = h.Basic.(removerAll)
 	return h
 }
 
@@ -39,6 +44,20 @@ func (h *Polyfill) TempFile(dir, prefix string) (billy.File, error) {
 	return h.Basic.(billy.TempFile).TempFile(dir, prefix)
 }
 
+type removerAll interface {
+	RemoveAll(string) error
+}
+
+// RemoveAll removes path and any children it contains, at once if the
+// underlying filesystem supports it, or else one by one, as util.RemoveAll.
+func (h *Polyfill) RemoveAll(path string) error {
+	if !h.c.removeall {
+		return util.RemoveAll(h.Basic, path)
+	}
+
+	return h.Basic.(removerAll).RemoveAll(path)
+}
+
 func (h *Polyfill) ReadDir(path string) ([]os.FileInfo, error) {
 	if !h.c.dir {
 		return nil, billy.ErrNotSupported
@@ -28,6 +28,27 @@ func (s *PolyfillSuite) TestTempFile(c *C) {
 	c.Assert(err, Equals, billy.ErrNotSupported)
 }
 
+func (s *PolyfillSuite) TestOptionalInterfaces(c *C) {
+	var fs interface{} = s.Helper
+
+	_, ok := fs.(billy.TempFileSuffix)
+	c.Assert(ok, Equals, false)
+	_, ok = fs.(billy.AnonTempFile)
+	c.Assert(ok, Equals, false)
+	_, ok = fs.(billy.TempFileTracker)
+	c.Assert(ok, Equals, false)
+	_, ok = fs.(billy.Exchange)
+	c.Assert(ok, Equals, false)
+	_, ok = fs.(billy.Chmod)
+	c.Assert(ok, Equals, false)
+	_, ok = fs.(billy.Chtimes)
+	c.Assert(ok, Equals, false)
+	_, ok = fs.(billy.Link)
+	c.Assert(ok, Equals, false)
+	_, ok = fs.(billy.OpenFileHint)
+	c.Assert(ok, Equals, false)
+}
+
 func (s *PolyfillSuite) TestReadDir(c *C) {
 	_, err := s.Helper.ReadDir("")
 	c.Assert(err, Equals, billy.ErrNotSupported)
@@ -0,0 +1,102 @@
+package readonlyfs
+
+import (
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// writeFlags are the flags of OpenFile changing the filesystem.
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_TRUNC
+
+// ReadOnly is a helper that gives read-only access to the underlying
+// filesystem, eg.: to serve embedded assets. The reads are forwarded as they
+// are, while the changes fail with an error wrapping billy.ErrReadOnly.
+type ReadOnly struct {
+	billy.Filesystem
+}
+
+// New creates a new filesystem wrapping up 'fs', not allowing to change it.
+func New(fs billy.Filesystem) billy.Filesystem {
+	return &ReadOnly{Filesystem: fs}
+}
+
+func (fs *ReadOnly) Create(filename string) (billy.File, error) {
+	return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *ReadOnly) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file for reading, it fails if any of the flags
+// given would allow to change it.
+func (fs *ReadOnly) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if flag&writeFlags != 0 {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrReadOnly}
+	}
+
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &file{File: f}, nil
+}
+
+func (fs *ReadOnly) Rename(from, to string) error {
+	return &os.LinkError{Op: "rename", Old: from, New: to, Err: billy.ErrReadOnly}
+}
+
+func (fs *ReadOnly) Remove(filename string) error {
+	return &os.PathError{Op: "remove", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *ReadOnly) MkdirAll(filename string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *ReadOnly) TempFile(dir, prefix string) (billy.File, error) {
+	return nil, &os.PathError{Op: "createtemp", Path: dir, Err: billy.ErrReadOnly}
+}
+
+func (fs *ReadOnly) Symlink(target, link string) error {
+	return &os.LinkError{Op: "symlink", Old: target, New: link, Err: billy.ErrReadOnly}
+}
+
+func (fs *ReadOnly) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot), nil
+}
+
+// Capabilities implements the Capable interface, the ones of the underlying
+// filesystem without the ones changing it.
+func (fs *ReadOnly) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem) &^ (billy.WriteCapability |
+		billy.ReadAndWriteCapability |
+		billy.TruncateCapability |
+		billy.SymlinkCapability |
+		billy.TempFileCapability)
+}
+
+// file is a billy.File opened through ReadOnly, the writes fail even if the
+// underlying file ignores the flags it was opened with.
+type file struct {
+	billy.File
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.Name(), Err: billy.ErrReadOnly}
+}
+
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.Name(), Err: billy.ErrReadOnly}
+}
+
+func (f *file) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.Name(), Err: billy.ErrReadOnly}
+}
@@ -0,0 +1,104 @@
+package readonlyfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ReadOnlySuite struct {
+	FS billy.Filesystem
+}
+
+var _ = Suite(&ReadOnlySuite{})
+
+func (s *ReadOnlySuite) SetUpTest(c *C) {
+	underlying := memfs.New()
+	c.Assert(util.WriteFile(underlying, "dir/foo", []byte("foo"), 0644), IsNil)
+	c.Assert(underlying.Symlink("dir/foo", "link"), IsNil)
+
+	s.FS = New(underlying)
+}
+
+func (s *ReadOnlySuite) TestRead(c *C) {
+	content, err := util.ReadFileString(s.FS, "link")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foo")
+
+	f, err := s.FS.OpenFile("dir/foo", os.O_RDONLY, 0)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	fi, err := s.FS.Lstat("link")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Mode()&os.ModeSymlink, Not(Equals), os.FileMode(0))
+
+	target, err := s.FS.Readlink("link")
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "dir/foo")
+
+	entries, err := s.FS.ReadDir("dir")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+
+	chroot, err := s.FS.Chroot("dir")
+	c.Assert(err, IsNil)
+	content, err = util.ReadFileString(chroot, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foo")
+	c.Assert(chroot.Remove("foo"), NotNil)
+}
+
+func (s *ReadOnlySuite) TestWrite(c *C) {
+	_, err := s.FS.Create("bar")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	for _, flag := range []int{os.O_WRONLY, os.O_RDWR, os.O_RDONLY | os.O_CREATE, os.O_RDONLY | os.O_TRUNC} {
+		_, err = s.FS.OpenFile("dir/foo", flag, 0644)
+		c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+	}
+
+	_, err = s.FS.TempFile("", "foo")
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+
+	for _, err := range []error{
+		s.FS.Remove("dir/foo"),
+		s.FS.Rename("dir/foo", "bar"),
+		s.FS.MkdirAll("qux", 0755),
+		s.FS.Symlink("dir/foo", "qux"),
+	} {
+		c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+	}
+
+	content, err := util.ReadFileString(s.FS, "dir/foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foo")
+
+	c.Assert(billy.CapabilityCheck(s.FS, billy.WriteCapability), Equals, false)
+	c.Assert(billy.CapabilityCheck(s.FS, billy.ReadCapability), Equals, true)
+}
+
+func (s *ReadOnlySuite) TestWriteOpenFile(c *C) {
+	f, err := s.FS.Open("dir/foo")
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	_, err = f.Write([]byte("bar"))
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+	_, err = f.(io.WriterAt).WriteAt([]byte("bar"), 0)
+	c.Assert(errors.Is(err, billy.ErrReadOnly), Equals, true)
+	c.Assert(errors.Is(f.Truncate(0), billy.ErrReadOnly), Equals, true)
+
+	content, err := util.ReadFileString(s.FS, "dir/foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foo")
+}
@@ -0,0 +1,20 @@
+package scratchfs
+
+import (
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/helper/polyfill"
+	"github.com/go-git/go-billy/v5/helper/unionfs"
+	"github.com/go-git/go-billy/v5/memfs"
+)
+
+// New creates a new filesystem wrapping up 'base', overlaying an in-memory
+// scratch area over it. Every write, including removals, lands in the
+// scratch area, so the base filesystem is never modified. Reads look at the
+// scratch area first and fall back to the base.
+//
+// The scratch area is a private memfs, discarded along with the returned
+// filesystem, stacked over base with unionfs, so the names starting with
+// ".wh." are reserved.
+func New(base billy.Basic) billy.Filesystem {
+	return unionfs.New(memfs.New(), polyfill.New(base))
+}
@@ -0,0 +1,129 @@
+package scratchfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&ScratchSuite{})
+
+type ScratchSuite struct {
+	test.FilesystemSuite
+	base billy.Filesystem
+}
+
+func (s *ScratchSuite) SetUpTest(c *C) {
+	s.base = memfs.New()
+	s.FilesystemSuite = test.NewFilesystemSuite(New(s.base))
+}
+
+func (s *ScratchSuite) TestReadFromBase(c *C) {
+	err := util.WriteFile(s.base, "foo/bar", []byte("base"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.Open("foo/bar")
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "base")
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *ScratchSuite) TestWriteDoesNotModifyBase(c *C) {
+	err := util.WriteFile(s.base, "foo", []byte("base"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.OpenFile("foo", os.O_WRONLY|os.O_APPEND, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("-scratch"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	s.assertContent(c, s.FS, "foo", "base-scratch")
+	s.assertContent(c, s.base, "foo", "base")
+}
+
+func (s *ScratchSuite) TestRemoveDoesNotModifyBase(c *C) {
+	err := util.WriteFile(s.base, "foo/bar", []byte("base"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.FS.Remove("foo/bar"), IsNil)
+
+	_, err = s.FS.Stat("foo/bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	fis, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 0)
+
+	s.assertContent(c, s.base, "foo/bar", "base")
+}
+
+func (s *ScratchSuite) TestRemoveAndRecreateDir(c *C) {
+	err := util.WriteFile(s.base, "foo/bar", []byte("base"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(util.RemoveAll(s.FS, "foo"), IsNil)
+	c.Assert(s.FS.MkdirAll("foo", 0755), IsNil)
+
+	fis, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 0)
+}
+
+func (s *ScratchSuite) TestRenameFromBase(c *C) {
+	err := util.WriteFile(s.base, "foo/bar", []byte("base"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.FS.Rename("foo", "qux"), IsNil)
+
+	_, err = s.FS.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	s.assertContent(c, s.FS, "qux/bar", "base")
+	s.assertContent(c, s.base, "foo/bar", "base")
+}
+
+func (s *ScratchSuite) TestReadDirMerge(c *C) {
+	err := util.WriteFile(s.base, "foo/bar", []byte("base"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.base, "foo/qux", []byte("base"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.FS, "foo/baz", []byte("scratch"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.FS, "foo/qux", []byte("scratch"), 0644)
+	c.Assert(err, IsNil)
+
+	fis, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 3)
+	c.Assert(fis[0].Name(), Equals, "bar")
+	c.Assert(fis[1].Name(), Equals, "baz")
+	c.Assert(fis[2].Name(), Equals, "qux")
+	c.Assert(fis[2].Size(), Equals, int64(7))
+
+	fis, err = s.base.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 2)
+}
+
+func (s *ScratchSuite) assertContent(c *C, fs billy.Basic, filename, expected string) {
+	f, err := fs.Open(filename)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, expected)
+	c.Assert(f.Close(), IsNil)
+}
@@ -0,0 +1,158 @@
+package singleflightfs
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// SingleFlight is a helper that coalesces the concurrent reads of the same
+// file into a single read from the underlying filesystem, eg.: in front of a
+// slow remote backend read by many goroutines at once. The calls to Stat and
+// Lstat are coalesced too.
+//
+// The reads are coalesced by ReadFile, used by util.ReadFile, while the files
+// returned by Open are read on their own. Only the calls in flight are
+// coalesced, the results are not cached.
+type SingleFlight struct {
+	billy.Filesystem
+	g *group
+}
+
+// New creates a new filesystem wrapping up 'fs'.
+func New(fs billy.Filesystem) billy.Filesystem {
+	return &SingleFlight{Filesystem: fs, g: &group{calls: make(map[string]*call)}}
+}
+
+// ReadFile reads the named file and returns its content. The concurrent calls
+// for the same file share a single read, each caller gets its own copy of the
+// content.
+func (fs *SingleFlight) ReadFile(filename string) ([]byte, error) {
+	v, err := fs.g.do("read:"+clean(filename), func() (interface{}, error) {
+		return fs.readFile(filename)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	content := v.([]byte)
+	return append(make([]byte, 0, len(content)), content...), nil
+}
+
+func (fs *SingleFlight) readFile(filename string) ([]byte, error) {
+	f, err := fs.Filesystem.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadAll(f)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func (fs *SingleFlight) Stat(filename string) (os.FileInfo, error) {
+	v, err := fs.g.do("stat:"+clean(filename), func() (interface{}, error) {
+		return fs.Filesystem.Stat(filename)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(os.FileInfo), nil
+}
+
+func (fs *SingleFlight) Lstat(filename string) (os.FileInfo, error) {
+	v, err := fs.g.do("lstat:"+clean(filename), func() (interface{}, error) {
+		return fs.Filesystem.Lstat(filename)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(os.FileInfo), nil
+}
+
+func (fs *SingleFlight) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *SingleFlight) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// errPanicked is returned to the callers sharing a call which panicked.
+var errPanicked = errors.New("shared call panicked")
+
+// group runs a single call at a time for a given key, the callers arriving
+// while it is in flight wait for it and share its result.
+type group struct {
+	m     sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	wg   sync.WaitGroup
+	dups int
+
+	val interface{}
+	err error
+}
+
+func (g *group) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.m.Lock()
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.m.Unlock()
+
+		c.wg.Wait()
+		return c.val, c.err
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.m.Unlock()
+
+	defer func() {
+		// the callers waiting get an error if fn panics, instead of a nil
+		// value, while the panic goes on in this one.
+		r := recover()
+		if r != nil {
+			c.val, c.err = nil, fmt.Errorf("%w: %v", errPanicked, r)
+		}
+
+		g.m.Lock()
+		delete(g.calls, key)
+		g.m.Unlock()
+
+		c.wg.Done()
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	c.val, c.err = fn()
+	return c.val, c.err
+}
+
+func clean(path string) string {
+	return filepath.Clean(filepath.FromSlash(path))
+}
@@ -0,0 +1,163 @@
+package singleflightfs
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&SingleFlightSuite{})
+
+type SingleFlightSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *SingleFlightSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New()))
+}
+
+func (s *SingleFlightSuite) TestReadFileCoalesced(c *C) {
+	slow := &slowFS{Filesystem: memfs.New(), release: make(chan struct{})}
+	err := util.WriteFile(slow.Filesystem, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	fs := New(slow).(*SingleFlight)
+
+	const readers = 10
+	results := make(chan []byte, readers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// util.ReadFile goes through fs.ReadFile too.
+			read := fs.ReadFile
+			if i%2 == 0 {
+				read = func(filename string) ([]byte, error) {
+					return util.ReadFile(fs, filename)
+				}
+			}
+
+			content, err := read("foo")
+			c.Check(err, IsNil)
+			results <- content
+		}(i)
+	}
+
+	waitDups(c, fs.g, "read:foo", readers-1)
+	close(slow.release)
+	wg.Wait()
+	close(results)
+
+	c.Assert(atomic.LoadInt32(&slow.opens), Equals, int32(1))
+
+	var contents [][]byte
+	for content := range results {
+		c.Assert(string(content), Equals, "foo")
+		contents = append(contents, content)
+	}
+
+	contents[0][0] = 'x'
+	c.Assert(string(contents[1]), Equals, "foo")
+}
+
+func (s *SingleFlightSuite) TestReadFileNotFound(c *C) {
+	fs := New(memfs.New()).(*SingleFlight)
+
+	_, err := fs.ReadFile("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *SingleFlightSuite) TestStatNotCached(c *C) {
+	fs := New(memfs.New())
+
+	_, err := fs.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+
+	fi, err := fs.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(3))
+}
+
+func (s *SingleFlightSuite) TestPanic(c *C) {
+	g := &group{calls: make(map[string]*call)}
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			c.Check(recover(), Equals, "boom")
+		}()
+
+		_, _ = g.do("foo", func() (interface{}, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+
+	var err error
+	go func() {
+		defer wg.Done()
+		waitDups(c, g, "foo", 0)
+		_, err = g.do("foo", func() (interface{}, error) {
+			c.Error("call not shared")
+			return nil, nil
+		})
+	}()
+
+	waitDups(c, g, "foo", 1)
+	close(release)
+	wg.Wait()
+
+	c.Assert(errors.Is(err, errPanicked), Equals, true)
+}
+
+// waitDups waits until the call in flight for key has the given number of
+// callers waiting for it.
+func waitDups(c *C, g *group, key string, dups int) {
+	for i := 0; i < 1000; i++ {
+		g.m.Lock()
+		call, ok := g.calls[key]
+		done := ok && call.dups == dups
+		g.m.Unlock()
+
+		if done {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Fatalf("timeout waiting for %d callers of %q", dups, key)
+}
+
+// slowFS blocks every Open until release is closed.
+type slowFS struct {
+	billy.Filesystem
+	release chan struct{}
+	opens   int32
+}
+
+func (fs *slowFS) Open(filename string) (billy.File, error) {
+	atomic.AddInt32(&fs.opens, 1)
+	<-fs.release
+	return fs.Filesystem.Open(filename)
+}
@@ -0,0 +1,117 @@
+package statsfs
+
+import (
+	"os"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// DefaultLimit is the number of paths counted by the filesystems from New.
+const DefaultLimit = 1024
+
+// Stats is a helper that counts the times each file is opened, to build an
+// access profile of the underlying filesystem, eg.: to decide which files to
+// keep in a cache.
+//
+// Up to a limit of paths are counted, once reached the least accessed path is
+// evicted to count a new one, which inherits its count, as the Space-Saving
+// algorithm does. So the most accessed paths are kept, and their counts are
+// overestimated by at most the count of the path evicted.
+type Stats struct {
+	billy.Filesystem
+	counts *counts
+	prefix string
+}
+
+type counts struct {
+	m     sync.Mutex
+	limit int
+	paths map[string]int
+}
+
+// New creates a new filesystem wrapping up 'fs', counting the accesses of up
+// to DefaultLimit paths. The function returned reports the counts by path,
+// relative to the root of fs.
+func New(fs billy.Filesystem) (billy.Filesystem, func() map[string]int) {
+	return NewWithLimit(fs, DefaultLimit)
+}
+
+// NewWithLimit creates a new filesystem like New, counting the accesses of up
+// to limit paths.
+func NewWithLimit(fs billy.Filesystem, limit int) (billy.Filesystem, func() map[string]int) {
+	c := &counts{limit: limit, paths: make(map[string]int)}
+	return &Stats{Filesystem: fs, counts: c}, c.report
+}
+
+func (fs *Stats) Open(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Open(filename)
+	if err == nil {
+		fs.counts.add(fs.Join(fs.prefix, filename))
+	}
+
+	return f, err
+}
+
+func (fs *Stats) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err == nil {
+		fs.counts.add(fs.Join(fs.prefix, filename))
+	}
+
+	return f, err
+}
+
+func (fs *Stats) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stats{
+		Filesystem: chroot,
+		counts:     fs.counts,
+		prefix:     fs.Join(fs.prefix, path),
+	}, nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Stats) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+func (c *counts) add(path string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if _, ok := c.paths[path]; ok || len(c.paths) < c.limit {
+		c.paths[path]++
+		return
+	}
+
+	if c.limit <= 0 {
+		return
+	}
+
+	min, evicted := -1, ""
+	for p, n := range c.paths {
+		if min < 0 || n < min {
+			min, evicted = n, p
+		}
+	}
+
+	delete(c.paths, evicted)
+	c.paths[path] = min + 1
+}
+
+func (c *counts) report() map[string]int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	r := make(map[string]int, len(c.paths))
+	for p, n := range c.paths {
+		r[p] = n
+	}
+
+	return r
+}
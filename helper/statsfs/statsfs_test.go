@@ -0,0 +1,68 @@
+package statsfs
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&StatsSuite{})
+
+type StatsSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *StatsSuite) SetUpTest(c *C) {
+	fs, _ := New(memfs.New())
+	s.FilesystemSuite = test.NewFilesystemSuite(fs)
+}
+
+func (s *StatsSuite) TestCounts(c *C) {
+	fs, stats := New(memfs.New())
+
+	c.Assert(util.WriteFile(fs, "foo/bar", []byte("bar"), 0644), IsNil)
+	for i := 0; i < 3; i++ {
+		_, err := util.ReadFileString(fs, "foo/bar")
+		c.Assert(err, IsNil)
+	}
+
+	chroot, err := fs.Chroot("foo")
+	c.Assert(err, IsNil)
+	_, err = util.ReadFileString(chroot, "bar")
+	c.Assert(err, IsNil)
+
+	_, err = fs.Open("missing")
+	c.Assert(err, NotNil)
+
+	c.Assert(stats(), DeepEquals, map[string]int{fs.Join("foo", "bar"): 5})
+}
+
+func (s *StatsSuite) TestLimit(c *C) {
+	fs, stats := NewWithLimit(memfs.New(), 2)
+
+	for i, name := range []string{"foo", "bar", "qux"} {
+		c.Assert(util.WriteFile(fs, name, []byte(name), 0644), IsNil)
+		for j := i; j < 2; j++ {
+			_, err := util.ReadFileString(fs, name)
+			c.Assert(err, IsNil)
+		}
+	}
+
+	counts := stats()
+	c.Assert(counts, DeepEquals, map[string]int{"foo": 3, "qux": 3})
+
+	for i := 0; i < 5; i++ {
+		_, err := util.ReadFileString(fs, "qux")
+		c.Assert(err, IsNil)
+	}
+
+	counts = stats()
+	c.Assert(counts, HasLen, 2)
+	c.Assert(counts["qux"] >= 5, Equals, true)
+}
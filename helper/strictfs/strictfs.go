@@ -0,0 +1,157 @@
+package strictfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// ErrUncleanPath is returned when a path is not in its clean form, as returned
+// by filepath.Clean.
+var ErrUncleanPath = errors.New("path is not clean")
+
+// Strict is a helper that validates that every path given to the underlying
+// filesystem is already clean: without "." or ".." elements, double or
+// trailing separators. It is meant to be used in development and testing, to
+// catch code relying on the implicit cleaning done by the filesystems.
+type Strict struct {
+	billy.Filesystem
+}
+
+// New creates a new filesystem wrapping up the given 'fs', rejecting any
+// unclean path with an *os.PathError wrapping ErrUncleanPath.
+func New(fs billy.Filesystem) billy.Filesystem {
+	return &Strict{Filesystem: fs}
+}
+
+func (fs *Strict) Create(filename string) (billy.File, error) {
+	if err := validate("create", filename); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Create(filename)
+}
+
+func (fs *Strict) Open(filename string) (billy.File, error) {
+	if err := validate("open", filename); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Open(filename)
+}
+
+func (fs *Strict) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if err := validate("open", filename); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.OpenFile(filename, flag, perm)
+}
+
+func (fs *Strict) Stat(filename string) (os.FileInfo, error) {
+	if err := validate("stat", filename); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Stat(filename)
+}
+
+func (fs *Strict) Rename(from, to string) error {
+	if err := validate("rename", from); err != nil {
+		return err
+	}
+
+	if err := validate("rename", to); err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Rename(from, to)
+}
+
+func (fs *Strict) Remove(filename string) error {
+	if err := validate("remove", filename); err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Remove(filename)
+}
+
+func (fs *Strict) TempFile(dir, prefix string) (billy.File, error) {
+	if dir != "" {
+		if err := validate("tempfile", dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return fs.Filesystem.TempFile(dir, prefix)
+}
+
+func (fs *Strict) ReadDir(path string) ([]os.FileInfo, error) {
+	if err := validate("readdir", path); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.ReadDir(path)
+}
+
+func (fs *Strict) MkdirAll(filename string, perm os.FileMode) error {
+	if err := validate("mkdir", filename); err != nil {
+		return err
+	}
+
+	return fs.Filesystem.MkdirAll(filename, perm)
+}
+
+func (fs *Strict) Lstat(filename string) (os.FileInfo, error) {
+	if err := validate("lstat", filename); err != nil {
+		return nil, err
+	}
+
+	return fs.Filesystem.Lstat(filename)
+}
+
+// Symlink validates only the link, since the target may be legitimately a
+// relative path containing "..".
+func (fs *Strict) Symlink(target, link string) error {
+	if err := validate("symlink", link); err != nil {
+		return err
+	}
+
+	return fs.Filesystem.Symlink(target, link)
+}
+
+func (fs *Strict) Readlink(link string) (string, error) {
+	if err := validate("readlink", link); err != nil {
+		return "", err
+	}
+
+	return fs.Filesystem.Readlink(link)
+}
+
+func (fs *Strict) Chroot(path string) (billy.Filesystem, error) {
+	if err := validate("chroot", path); err != nil {
+		return nil, err
+	}
+
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(chroot), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Strict) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+func validate(op, path string) error {
+	if filepath.ToSlash(filepath.Clean(path)) == filepath.ToSlash(path) {
+		return nil
+	}
+
+	return &os.PathError{Op: op, Path: path, Err: ErrUncleanPath}
+}
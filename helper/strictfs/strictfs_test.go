@@ -0,0 +1,78 @@
+package strictfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&StrictSuite{})
+
+type StrictSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *StrictSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New()))
+}
+
+func (s *StrictSuite) TestUncleanPaths(c *C) {
+	paths := []string{
+		"./foo",
+		"foo/../bar",
+		"foo//bar",
+		"foo/",
+		"foo/.",
+	}
+
+	for _, path := range paths {
+		_, err := s.FS.Create(path)
+		c.Assert(errors.Is(err, ErrUncleanPath), Equals, true, Commentf("path %q", path))
+
+		err = s.FS.MkdirAll(path, 0755)
+		c.Assert(errors.Is(err, ErrUncleanPath), Equals, true, Commentf("path %q", path))
+
+		perr, ok := err.(*os.PathError)
+		c.Assert(ok, Equals, true)
+		c.Assert(perr.Path, Equals, path)
+	}
+
+	_, err := s.FS.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *StrictSuite) TestRenameUncleanDestination(c *C) {
+	f, err := s.FS.Create("foo")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	err = s.FS.Rename("foo", "bar/../qux")
+	c.Assert(errors.Is(err, ErrUncleanPath), Equals, true)
+
+	_, err = s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+}
+
+func (s *StrictSuite) TestSymlinkRelativeTarget(c *C) {
+	err := s.FS.Symlink("../foo", "bar/link")
+	c.Assert(err, IsNil)
+
+	target, err := s.FS.Readlink("bar/link")
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "../foo")
+}
+
+// TestRemoveAllRelative overrides the test from the suite, since it relies on
+// unclean paths.
+func (s *StrictSuite) TestRemoveAllRelative(c *C) {
+	err := util.RemoveAll(s.FS, "foo/bar/..")
+	c.Assert(errors.Is(err, ErrUncleanPath), Equals, true)
+}
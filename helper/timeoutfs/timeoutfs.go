@@ -0,0 +1,363 @@
+package timeoutfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// ErrTimeout is returned, wrapped in an *os.PathError, by the operations not
+// done in the time given to the filesystem.
+var ErrTimeout = errors.New("operation timed out")
+
+// ContextFilesystem is implemented by the filesystems able to cancel their
+// operations, eg.: network requests, through a context.
+type ContextFilesystem interface {
+	// WithContext returns a filesystem whose operations are canceled when
+	// the given context is done. The context only covers the calls to the
+	// filesystem, not the use of the files returned by them.
+	WithContext(ctx context.Context) billy.Filesystem
+}
+
+// Timeout is a helper that bounds the time taken by each operation, over the
+// filesystem and over the files opened from it, eg.: to avoid hanging on a
+// slow network backend.
+//
+// Each operation is run in its own goroutine, and abandoned if it isn't done
+// in time. An abandoned operation keeps running, holding its resources, until
+// the underlying filesystem returns; if it opens a file, the file is closed
+// once opened. A file must not be used after one of its operations timed
+// out, since the abandoned operation may still be using it. If the underlying
+// filesystem implements ContextFilesystem, the operations are canceled
+// through the context instead of just abandoned.
+type Timeout struct {
+	billy.Filesystem
+	ctx   context.Context
+	perOp time.Duration
+}
+
+// New creates a new filesystem wrapping up 'fs', where every operation fails
+// with ErrTimeout if it takes longer than perOp.
+func New(fs billy.Filesystem, perOp time.Duration) billy.Filesystem {
+	return NewWithContext(context.Background(), fs, perOp)
+}
+
+// NewWithContext behaves as New, the operations also fail, with the error of
+// the context, once the given context is done.
+func NewWithContext(ctx context.Context, fs billy.Filesystem, perOp time.Duration) billy.Filesystem {
+	return &Timeout{Filesystem: fs, ctx: ctx, perOp: perOp}
+}
+
+func (fs *Timeout) Create(filename string) (billy.File, error) {
+	return fs.open("open", filename, func(u billy.Filesystem) (billy.File, error) {
+		return u.Create(filename)
+	})
+}
+
+func (fs *Timeout) Open(filename string) (billy.File, error) {
+	return fs.open("open", filename, func(u billy.Filesystem) (billy.File, error) {
+		return u.Open(filename)
+	})
+}
+
+func (fs *Timeout) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	return fs.open("open", filename, func(u billy.Filesystem) (billy.File, error) {
+		return u.OpenFile(filename, flag, perm)
+	})
+}
+
+func (fs *Timeout) TempFile(dir, prefix string) (billy.File, error) {
+	return fs.open("createtemp", dir, func(u billy.Filesystem) (billy.File, error) {
+		return u.TempFile(dir, prefix)
+	})
+}
+
+func (fs *Timeout) Stat(filename string) (os.FileInfo, error) {
+	var fi os.FileInfo
+	var err error
+	terr := fs.do("stat", filename, func(u billy.Filesystem) {
+		fi, err = u.Stat(filename)
+	})
+
+	if terr != nil {
+		return nil, terr
+	}
+
+	return fi, err
+}
+
+func (fs *Timeout) Lstat(filename string) (os.FileInfo, error) {
+	var fi os.FileInfo
+	var err error
+	terr := fs.do("lstat", filename, func(u billy.Filesystem) {
+		fi, err = u.Lstat(filename)
+	})
+
+	if terr != nil {
+		return nil, terr
+	}
+
+	return fi, err
+}
+
+func (fs *Timeout) Rename(from, to string) error {
+	var err error
+	terr := fs.do("rename", from, func(u billy.Filesystem) {
+		err = u.Rename(from, to)
+	})
+
+	if terr != nil {
+		return terr
+	}
+
+	return err
+}
+
+func (fs *Timeout) Remove(filename string) error {
+	var err error
+	terr := fs.do("remove", filename, func(u billy.Filesystem) {
+		err = u.Remove(filename)
+	})
+
+	if terr != nil {
+		return terr
+	}
+
+	return err
+}
+
+func (fs *Timeout) ReadDir(path string) ([]os.FileInfo, error) {
+	var fis []os.FileInfo
+	var err error
+	terr := fs.do("readdir", path, func(u billy.Filesystem) {
+		fis, err = u.ReadDir(path)
+	})
+
+	if terr != nil {
+		return nil, terr
+	}
+
+	return fis, err
+}
+
+func (fs *Timeout) MkdirAll(filename string, perm os.FileMode) error {
+	var err error
+	terr := fs.do("mkdir", filename, func(u billy.Filesystem) {
+		err = u.MkdirAll(filename, perm)
+	})
+
+	if terr != nil {
+		return terr
+	}
+
+	return err
+}
+
+func (fs *Timeout) Symlink(target, link string) error {
+	var err error
+	terr := fs.do("symlink", link, func(u billy.Filesystem) {
+		err = u.Symlink(target, link)
+	})
+
+	if terr != nil {
+		return terr
+	}
+
+	return err
+}
+
+func (fs *Timeout) Readlink(link string) (string, error) {
+	var target string
+	var err error
+	terr := fs.do("readlink", link, func(u billy.Filesystem) {
+		target, err = u.Readlink(link)
+	})
+
+	if terr != nil {
+		return "", terr
+	}
+
+	return target, err
+}
+
+func (fs *Timeout) Chroot(path string) (billy.Filesystem, error) {
+	var chroot billy.Filesystem
+	var err error
+	terr := fs.do("chroot", path, func(u billy.Filesystem) {
+		chroot, err = u.Chroot(path)
+	})
+
+	if terr != nil {
+		return nil, terr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithContext(fs.ctx, chroot, fs.perOp), nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Timeout) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// do runs fn in its own goroutine, it returns an error if fn doesn't return
+// in time. The values written by fn must not be read if an error is returned.
+func (fs *Timeout) do(op, path string, fn func(billy.Filesystem)) error {
+	_, err := fs.run(op, path, fn)
+	return err
+}
+
+// open runs fn like do, closing the opened file if fn doesn't return in time.
+func (fs *Timeout) open(op, path string, fn func(billy.Filesystem) (billy.File, error)) (billy.File, error) {
+	var f billy.File
+	var err error
+	done, terr := fs.run(op, path, func(u billy.Filesystem) {
+		f, err = fn(u)
+	})
+
+	if terr != nil {
+		go func() {
+			<-done
+			if f != nil {
+				_ = f.Close()
+			}
+		}()
+
+		return nil, terr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &file{File: f, fs: fs}, nil
+}
+
+func (fs *Timeout) run(op, path string, fn func(billy.Filesystem)) (<-chan struct{}, error) {
+	ctx, cancel := context.WithTimeout(fs.ctx, fs.perOp)
+	defer cancel()
+
+	u := fs.Filesystem
+	if cf, ok := u.(ContextFilesystem); ok {
+		u = cf.WithContext(ctx)
+	}
+
+	return wait(ctx, op, path, func() { fn(u) })
+}
+
+// wait runs fn in its own goroutine, until it returns or ctx is done. The
+// returned channel is closed once fn returns.
+func wait(ctx context.Context, op, path string, fn func()) (<-chan struct{}, error) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+		return done, nil
+	case <-ctx.Done():
+	}
+
+	err := ctx.Err()
+	if err == context.DeadlineExceeded {
+		err = ErrTimeout
+	}
+
+	return done, &os.PathError{Op: op, Path: path, Err: err}
+}
+
+// file bounds the time taken by the operations over a file, but Lock, which
+// may wait for the lock as long as needed. The buffers given to Read, ReadAt
+// and Write are copied, so they aren't used once the operation timed out.
+type file struct {
+	billy.File
+	fs *Timeout
+}
+
+func (f *file) do(op string, fn func()) error {
+	ctx, cancel := context.WithTimeout(f.fs.ctx, f.fs.perOp)
+	defer cancel()
+
+	_, err := wait(ctx, op, f.Name(), fn)
+	return err
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	var n int
+	var err error
+	buf := make([]byte, len(p))
+	if terr := f.do("read", func() { n, err = f.File.Read(buf) }); terr != nil {
+		return 0, terr
+	}
+
+	copy(p, buf[:n])
+	return n, err
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	var n int
+	var err error
+	buf := make([]byte, len(p))
+	if terr := f.do("read", func() { n, err = f.File.ReadAt(buf, off) }); terr != nil {
+		return 0, terr
+	}
+
+	copy(p, buf[:n])
+	return n, err
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	var n int
+	var err error
+	buf := append([]byte(nil), p...)
+	if terr := f.do("write", func() { n, err = f.File.Write(buf) }); terr != nil {
+		return 0, terr
+	}
+
+	return n, err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	var ret int64
+	var err error
+	if terr := f.do("seek", func() { ret, err = f.File.Seek(offset, whence) }); terr != nil {
+		return 0, terr
+	}
+
+	return ret, err
+}
+
+func (f *file) Truncate(size int64) error {
+	var err error
+	if terr := f.do("truncate", func() { err = f.File.Truncate(size) }); terr != nil {
+		return terr
+	}
+
+	return err
+}
+
+func (f *file) Sync() error {
+	var err error
+	if terr := f.do("sync", func() { err = f.File.Sync() }); terr != nil {
+		return terr
+	}
+
+	return err
+}
+
+func (f *file) Close() error {
+	var err error
+	if terr := f.do("close", func() { err = f.File.Close() }); terr != nil {
+		return terr
+	}
+
+	return err
+}
@@ -0,0 +1,138 @@
+package timeoutfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&TimeoutSuite{})
+
+type TimeoutSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *TimeoutSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New(), time.Minute))
+}
+
+func (s *TimeoutSuite) TestTimeout(c *C) {
+	slow := &slowFS{Filesystem: memfs.New(), release: make(chan struct{})}
+	defer close(slow.release)
+
+	fs := New(slow, 10*time.Millisecond)
+
+	_, err := fs.Stat("foo")
+	c.Assert(errors.Is(err, ErrTimeout), Equals, true)
+
+	_, err = fs.Open("foo")
+	c.Assert(errors.Is(err, ErrTimeout), Equals, true)
+}
+
+func (s *TimeoutSuite) TestFileTimeout(c *C) {
+	underlying := memfs.New()
+	err := util.WriteFile(underlying, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	fs := New(&slowFileFS{Filesystem: underlying, release: release}, 10*time.Millisecond)
+
+	f, err := fs.Open("foo")
+	c.Assert(err, IsNil)
+
+	_, err = f.Read(make([]byte, 3))
+	c.Assert(errors.Is(err, ErrTimeout), Equals, true)
+}
+
+func (s *TimeoutSuite) TestContext(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ctxfs := &ctxFS{Filesystem: memfs.New()}
+	fs := NewWithContext(ctx, ctxfs, time.Minute)
+
+	_, err := fs.Stat("foo")
+	c.Assert(errors.Is(err, context.Canceled), Equals, true)
+
+	ctxfs.ctx = nil
+	fs = New(ctxfs, time.Minute)
+	_, err = fs.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	c.Assert(ctxfs.ctx, NotNil)
+	c.Assert(ctxfs.ctx.Err(), Equals, context.Canceled)
+}
+
+// slowFS blocks on every call to Stat and Open, until released.
+type slowFS struct {
+	billy.Filesystem
+	release chan struct{}
+}
+
+func (fs *slowFS) Stat(filename string) (os.FileInfo, error) {
+	<-fs.release
+	return fs.Filesystem.Stat(filename)
+}
+
+func (fs *slowFS) Open(filename string) (billy.File, error) {
+	<-fs.release
+	return fs.Filesystem.Open(filename)
+}
+
+// slowFileFS returns files blocking on every Read, until released.
+type slowFileFS struct {
+	billy.Filesystem
+	release chan struct{}
+}
+
+func (fs *slowFileFS) Open(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Open(filename)
+	return &slowFile{File: f, release: fs.release}, err
+}
+
+type slowFile struct {
+	billy.File
+	release chan struct{}
+}
+
+func (f *slowFile) Read(p []byte) (int, error) {
+	<-f.release
+	return f.File.Read(p)
+}
+
+// ctxFS records the context given to WithContext, its Stat fails once the
+// context is done.
+type ctxFS struct {
+	billy.Filesystem
+	ctx context.Context
+}
+
+func (fs *ctxFS) WithContext(ctx context.Context) billy.Filesystem {
+	fs.ctx = ctx
+	return &ctxStatFS{Filesystem: fs.Filesystem, ctx: ctx}
+}
+
+type ctxStatFS struct {
+	billy.Filesystem
+	ctx context.Context
+}
+
+func (fs *ctxStatFS) Stat(filename string) (os.FileInfo, error) {
+	if err := fs.ctx.Err(); err != nil {
+		return nil, &os.PathError{Op: "stat", Path: filename, Err: err}
+	}
+
+	return fs.Filesystem.Stat(filename)
+}
@@ -0,0 +1,183 @@
+package ttlfs
+
+import (
+	"os"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// TTL is a helper that expires the files of the underlying filesystem once
+// they are older than a time to live, eg.: for a cache directory. The expired
+// files are seen as not existing, and removed when accessed, or swept at once
+// with Purge. The directories don't expire.
+//
+// The age of a file is taken from its birth time, if known, see
+// util.StatBirthTime, otherwise from its modification time, so it's kept
+// across restarts without recording anything.
+type TTL struct {
+	billy.Filesystem
+	ttl time.Duration
+	now func() time.Time
+}
+
+// New creates a new filesystem wrapping up 'fs', where the files expire once
+// older than ttl.
+func New(fs billy.Filesystem, ttl time.Duration) billy.Filesystem {
+	return &TTL{Filesystem: fs, ttl: ttl, now: time.Now}
+}
+
+func (fs *TTL) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *TTL) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file, if expired it is created again with
+// os.O_CREATE, otherwise an error wrapping os.ErrNotExist is returned.
+func (fs *TTL) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	_, err := fs.stat("open", filename, true)
+	if os.IsNotExist(err) && flag&os.O_CREATE == 0 {
+		return nil, err
+	}
+
+	return fs.Filesystem.OpenFile(filename, flag, perm)
+}
+
+func (fs *TTL) Stat(filename string) (os.FileInfo, error) {
+	return fs.stat("stat", filename, true)
+}
+
+func (fs *TTL) Lstat(filename string) (os.FileInfo, error) {
+	return fs.stat("lstat", filename, false)
+}
+
+// ReadDir reads the directory, leaving out the expired files, which are
+// removed.
+func (fs *TTL) ReadDir(path string) ([]os.FileInfo, error) {
+	entries, err := fs.Filesystem.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	live := entries[:0]
+	for _, fi := range entries {
+		filename := fs.Join(path, fi.Name())
+		if fs.expired(filename, fi) {
+			_ = fs.Filesystem.Remove(filename)
+			continue
+		}
+
+		live = append(live, fi)
+	}
+
+	return live, nil
+}
+
+func (fs *TTL) Rename(from, to string) error {
+	if _, err := fs.stat("rename", from, false); os.IsNotExist(err) {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrNotExist}
+	}
+
+	return fs.Filesystem.Rename(from, to)
+}
+
+func (fs *TTL) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TTL{Filesystem: chroot, ttl: fs.ttl, now: fs.now}, nil
+}
+
+// Capabilities implements the Capable interface.
+func (fs *TTL) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.Filesystem)
+}
+
+// Purge removes all the expired files, returning the first error found.
+func (fs *TTL) Purge() error {
+	return fs.purge(string(os.PathSeparator))
+}
+
+func (fs *TTL) purge(dir string) error {
+	entries, err := fs.Filesystem.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, fi := range entries {
+		path := fs.Join(dir, fi.Name())
+
+		switch {
+		case fi.IsDir():
+			err = fs.purge(path)
+		case fs.expired(path, fi):
+			err = fs.Filesystem.Remove(path)
+		default:
+			continue
+		}
+
+		if err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// stat returns the FileInfo of the named file, following the symlinks if
+// follow is true. If the file is expired it's removed, unless reached through
+// a symlink, and an error wrapping os.ErrNotExist is returned.
+func (fs *TTL) stat(op, filename string, follow bool) (os.FileInfo, error) {
+	var fi os.FileInfo
+	var err error
+	if follow {
+		fi, err = fs.Filesystem.Stat(filename)
+	} else {
+		fi, err = fs.Filesystem.Lstat(filename)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !fs.expired(filename, fi) {
+		return fi, nil
+	}
+
+	if !follow || !fs.isSymlink(filename) {
+		_ = fs.Filesystem.Remove(filename)
+	}
+
+	return nil, &os.PathError{Op: op, Path: filename, Err: os.ErrNotExist}
+}
+
+func (fs *TTL) isSymlink(filename string) bool {
+	fi, err := fs.Filesystem.Lstat(filename)
+	return err == nil && fi.Mode()&os.ModeSymlink != 0
+}
+
+// expired returns true if fi, the FileInfo of filename, is the one of a file
+// older than the ttl.
+func (fs *TTL) expired(filename string, fi os.FileInfo) bool {
+	if fi.IsDir() {
+		return false
+	}
+
+	created, ok := util.BirthTime(fi)
+	if !ok && fi.Mode().IsRegular() {
+		created, ok = util.StatBirthTime(fs.Filesystem, filename)
+	}
+
+	if !ok {
+		created = fi.ModTime()
+	}
+
+	return fs.now().Sub(created) > fs.ttl
+}
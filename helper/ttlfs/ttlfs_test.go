@@ -0,0 +1,115 @@
+package ttlfs
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&TTLSuite{})
+
+type TTLSuite struct {
+	test.FilesystemSuite
+}
+
+func (s *TTLSuite) SetUpTest(c *C) {
+	s.FilesystemSuite = test.NewFilesystemSuite(New(memfs.New(), time.Hour))
+}
+
+// newTTL returns a TTL over a new memfs, with the clock moved forward by
+// the returned function.
+func newTTL(ttl time.Duration) (*TTL, billy.Filesystem, func(time.Duration)) {
+	underlying := memfs.New()
+	fs := New(underlying, ttl).(*TTL)
+
+	var offset time.Duration
+	fs.now = func() time.Time {
+		return time.Now().Add(offset)
+	}
+
+	return fs, underlying, func(d time.Duration) { offset += d }
+}
+
+func (s *TTLSuite) TestExpire(c *C) {
+	fs, underlying, advance := newTTL(time.Minute)
+	c.Assert(util.WriteFile(fs, "dir/foo", []byte("foo"), 0644), IsNil)
+	c.Assert(fs.Symlink("dir/foo", "link"), IsNil)
+
+	_, err := fs.Stat("dir/foo")
+	c.Assert(err, IsNil)
+
+	advance(2 * time.Minute)
+
+	_, err = fs.Open("link")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = fs.Stat("dir/foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = underlying.Stat("dir/foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	fi, err := fs.Stat("dir")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+
+	entries, err := fs.ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Assert(entries[0].Name(), Equals, "dir")
+
+	_, err = underlying.Lstat("link")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *TTLSuite) TestCreateExpired(c *C) {
+	fs, _, advance := newTTL(time.Minute)
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+
+	c.Assert(util.WriteFile(fs, "qux", []byte("qux"), 0644), IsNil)
+
+	advance(2 * time.Minute)
+	c.Assert(fs.Rename("qux", "bar"), NotNil)
+
+	f, err := fs.OpenFile("foo", os.O_RDWR|os.O_CREATE, 0644)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("b"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	// The file is created again, instead of keeping the expired content.
+	advance(-2 * time.Minute)
+	content, err := util.ReadFileString(fs, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "b")
+}
+
+func (s *TTLSuite) TestPurge(c *C) {
+	fs, underlying, advance := newTTL(time.Minute)
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+	c.Assert(util.WriteFile(fs, "dir/bar", []byte("bar"), 0644), IsNil)
+
+	c.Assert(fs.Purge(), IsNil)
+	_, err := underlying.Stat("dir/bar")
+	c.Assert(err, IsNil)
+
+	advance(2 * time.Minute)
+	c.Assert(fs.Purge(), IsNil)
+
+	for _, name := range []string{"foo", "dir/bar"} {
+		_, err := underlying.Stat(name)
+		c.Assert(os.IsNotExist(err), Equals, true)
+	}
+
+	_, err = underlying.Stat("dir")
+	c.Assert(err, IsNil)
+}
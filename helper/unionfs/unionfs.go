@@ -0,0 +1,591 @@
+package unionfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/helper/chroot"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+const (
+	// whiteoutPrefix starts the name of the whiteouts, the empty files in the
+	// upper filesystem hiding the file of the lower with the rest of the name.
+	whiteoutPrefix = ".wh."
+	// opaqueName is the name of the empty file in a directory of the upper
+	// filesystem hiding the whole content of the same directory of the lower.
+	opaqueName = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+var separator = string(filepath.Separator)
+
+var (
+	errNotDir   = errors.New("not a directory")
+	errNotEmpty = errors.New("directory not empty")
+	errReserved = errors.New("name reserved for the whiteouts")
+)
+
+// Union is a helper that overlays an upper filesystem over a lower one, eg.:
+// for a build sandbox over a read-only base. Reads look at the upper first
+// and fall back to the lower, while every change lands in the upper, the
+// files of the lower being copied up before changed, so the lower is never
+// modified.
+//
+// The removals of files of the lower are recorded in the upper as whiteouts,
+// empty files named ".wh." followed by the name removed, and the directories
+// hiding all the content of the lower, as the ones recreated after removed,
+// hold an empty ".wh..wh..opq" file, as aufs does. So they are kept along
+// with the upper. The names starting with ".wh." are reserved.
+type Union struct {
+	upper billy.Filesystem
+	lower billy.Filesystem
+
+	m sync.RWMutex
+}
+
+// New creates a new filesystem overlaying upper over lower.
+func New(upper, lower billy.Filesystem) billy.Filesystem {
+	return chroot.New(&Union{upper: upper, lower: lower}, separator)
+}
+
+func (fs *Union) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *Union) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *Union) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	filename = cleanPath(filename)
+	if !isWrite(flag) {
+		fs.m.RLock()
+		defer fs.m.RUnlock()
+
+		l, err := fs.layer(filename)
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: filename, Err: err}
+		}
+
+		return l.OpenFile(filename, flag, perm)
+	}
+
+	if isReserved(filename) {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: errReserved}
+	}
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	_, err := fs.lstat(filename)
+	switch {
+	case os.IsNotExist(err):
+		if flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+
+		err = fs.copyUp(filepath.Dir(filename))
+	case err != nil:
+		return nil, err
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrExist}
+	case fs.inUpper(filename):
+	case flag&os.O_TRUNC != 0:
+		err = fs.copyUp(filepath.Dir(filename))
+		flag |= os.O_CREATE
+	default:
+		err = fs.copyUp(filename)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fs.upper.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fs.unhide(filename); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (fs *Union) Stat(filename string) (os.FileInfo, error) {
+	filename = cleanPath(filename)
+
+	fs.m.RLock()
+	defer fs.m.RUnlock()
+
+	return fs.stat(filename)
+}
+
+func (fs *Union) Lstat(filename string) (os.FileInfo, error) {
+	filename = cleanPath(filename)
+
+	fs.m.RLock()
+	defer fs.m.RUnlock()
+
+	return fs.lstat(filename)
+}
+
+func (fs *Union) Readlink(link string) (string, error) {
+	link = cleanPath(link)
+
+	fs.m.RLock()
+	defer fs.m.RUnlock()
+
+	l, err := fs.layer(link)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: link, Err: err}
+	}
+
+	return l.Readlink(link)
+}
+
+// ReadDir returns the entries of both filesystems, the ones of the upper
+// shadowing the ones of the lower with the same name.
+func (fs *Union) ReadDir(path string) ([]os.FileInfo, error) {
+	path = cleanPath(path)
+
+	fs.m.RLock()
+	defer fs.m.RUnlock()
+
+	fi, err := fs.stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: errNotDir}
+	}
+
+	return fs.readDir(path)
+}
+
+func (fs *Union) MkdirAll(filename string, perm os.FileMode) error {
+	filename = cleanPath(filename)
+	if isReserved(filename) {
+		return &os.PathError{Op: "mkdir", Path: filename, Err: errReserved}
+	}
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	if fi, err := fs.stat(filename); err == nil && !fi.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: filename, Err: os.ErrExist}
+	}
+
+	if err := fs.copyUp(filepath.Dir(filename)); err != nil {
+		return err
+	}
+
+	if err := fs.upper.MkdirAll(filename, perm); err != nil {
+		return err
+	}
+
+	return fs.unhide(filename)
+}
+
+func (fs *Union) Symlink(target, link string) error {
+	link = cleanPath(link)
+	if isReserved(link) {
+		return &os.LinkError{Op: "symlink", Old: target, New: link, Err: errReserved}
+	}
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	if _, err := fs.lstat(link); err == nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: link, Err: os.ErrExist}
+	}
+
+	if err := fs.copyUp(filepath.Dir(link)); err != nil {
+		return err
+	}
+
+	if err := fs.upper.Symlink(target, link); err != nil {
+		return err
+	}
+
+	return fs.unhide(link)
+}
+
+func (fs *Union) TempFile(dir, prefix string) (billy.File, error) {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	if err := fs.copyUp(cleanPath(dir)); err != nil {
+		return nil, err
+	}
+
+	return fs.upper.TempFile(dir, prefix)
+}
+
+// Rename copies up from, along with all its content, before renaming it in
+// the upper filesystem, and hides it in the lower.
+func (fs *Union) Rename(from, to string) error {
+	from, to = cleanPath(from), cleanPath(to)
+	if isReserved(to) {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: errReserved}
+	}
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	if _, err := fs.lstat(from); err != nil {
+		return err
+	}
+
+	if err := fs.copyUp(filepath.Dir(to)); err != nil {
+		return err
+	}
+
+	if err := fs.copyUpTree(from); err != nil {
+		return err
+	}
+
+	if err := fs.upper.Rename(from, to); err != nil {
+		return err
+	}
+
+	if err := fs.hide(from); err != nil {
+		return err
+	}
+
+	return fs.unhide(to)
+}
+
+// Remove removes the file from the upper filesystem, and hides it in the
+// lower with a whiteout.
+func (fs *Union) Remove(filename string) error {
+	filename = cleanPath(filename)
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	fi, err := fs.lstat(filename)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		l, err := fs.readDir(filename)
+		if err != nil {
+			return err
+		}
+
+		if len(l) != 0 {
+			return &os.PathError{Op: "remove", Path: filename, Err: errNotEmpty}
+		}
+	}
+
+	if fs.inUpper(filename) {
+		if err := fs.removeUpper(filename, fi.IsDir()); err != nil {
+			return err
+		}
+	}
+
+	return fs.hide(filename)
+}
+
+func (fs *Union) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Union) Capabilities() billy.Capability {
+	return billy.Capabilities(fs.upper)
+}
+
+// layer returns the filesystem holding the given path, the upper one if it
+// holds it, or else the lower one if it isn't hidden.
+func (fs *Union) layer(filename string) (billy.Filesystem, error) {
+	if isReserved(filename) {
+		return nil, os.ErrNotExist
+	}
+
+	if fs.inUpper(filename) {
+		return fs.upper, nil
+	}
+
+	if fs.lowerVisible(filename) {
+		return fs.lower, nil
+	}
+
+	return nil, os.ErrNotExist
+}
+
+// stat, lstat and readDir are the lock-free versions of the public methods,
+// to be used when fs.m is already held.
+func (fs *Union) stat(filename string) (os.FileInfo, error) {
+	l, err := fs.layer(filename)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: filename, Err: err}
+	}
+
+	return l.Stat(filename)
+}
+
+func (fs *Union) lstat(filename string) (os.FileInfo, error) {
+	l, err := fs.layer(filename)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: filename, Err: err}
+	}
+
+	return l.Lstat(filename)
+}
+
+func (fs *Union) readDir(path string) ([]os.FileInfo, error) {
+	entries := make(map[string]os.FileInfo)
+	hidden := make(map[string]bool)
+	if fs.inUpper(path) {
+		l, err := fs.upper.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fi := range l {
+			if isReserved(fi.Name()) {
+				hidden[strings.TrimPrefix(fi.Name(), whiteoutPrefix)] = true
+				continue
+			}
+
+			entries[fi.Name()] = fi
+		}
+	}
+
+	if fs.lowerVisible(path) && !fs.isOpaque(path) {
+		if l, err := fs.lower.ReadDir(path); err == nil {
+			for _, fi := range l {
+				if _, ok := entries[fi.Name()]; !ok && !hidden[fi.Name()] {
+					entries[fi.Name()] = fi
+				}
+			}
+		}
+	}
+
+	result := make([]os.FileInfo, 0, len(entries))
+	for _, fi := range entries {
+		result = append(result, fi)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name() < result[j].Name()
+	})
+
+	return result, nil
+}
+
+func (fs *Union) inUpper(filename string) bool {
+	_, err := fs.upper.Lstat(filename)
+	return err == nil
+}
+
+func (fs *Union) isOpaque(dir string) bool {
+	_, err := fs.upper.Lstat(fs.Join(dir, opaqueName))
+	return err == nil
+}
+
+func (fs *Union) isWhiteout(filename string) bool {
+	_, err := fs.upper.Lstat(whiteout(filename))
+	return err == nil
+}
+
+// lowerVisible returns true if the given path of the lower filesystem isn't
+// hidden by a whiteout, of itself or of any of its parents, nor by an opaque
+// parent.
+func (fs *Union) lowerVisible(filename string) bool {
+	for p := filename; ; p = filepath.Dir(p) {
+		if p == "." || p == separator {
+			return p == filename || !fs.isOpaque(p)
+		}
+
+		if fs.isWhiteout(p) || (p != filename && fs.isOpaque(p)) {
+			return false
+		}
+	}
+}
+
+// hide creates a whiteout for the given path, if it's still visible in the
+// lower filesystem.
+func (fs *Union) hide(filename string) error {
+	if !fs.lowerVisible(filename) {
+		return nil
+	}
+
+	if _, err := fs.lower.Lstat(filename); err != nil {
+		return nil
+	}
+
+	if err := fs.copyUp(filepath.Dir(filename)); err != nil {
+		return err
+	}
+
+	return util.WriteFile(fs.upper, whiteout(filename), nil, 0600)
+}
+
+// unhide removes the whiteouts of the given path and of its parents, created
+// again in the upper filesystem, the directories among them become opaque,
+// so the content removed from the lower isn't seen again.
+func (fs *Union) unhide(filename string) error {
+	for p := filename; p != "." && p != separator; p = filepath.Dir(p) {
+		if !fs.isWhiteout(p) {
+			continue
+		}
+
+		if err := fs.upper.Remove(whiteout(p)); err != nil {
+			return err
+		}
+
+		if fi, err := fs.upper.Lstat(p); err == nil && fi.IsDir() {
+			if err := util.WriteFile(fs.upper, fs.Join(p, opaqueName), nil, 0600); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeUpper removes the given path from the upper filesystem, along with
+// the whiteouts inside of it if it's a directory.
+func (fs *Union) removeUpper(filename string, isDir bool) error {
+	if isDir {
+		l, err := fs.upper.ReadDir(filename)
+		if err != nil {
+			return err
+		}
+
+		for _, fi := range l {
+			if !isReserved(fi.Name()) {
+				continue
+			}
+
+			if err := fs.upper.Remove(fs.Join(filename, fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return fs.upper.Remove(filename)
+}
+
+// copyUp copies the given path from the lower filesystem to the upper one,
+// along with its parent directories. Directories are created empty.
+func (fs *Union) copyUp(filename string) error {
+	if fs.inUpper(filename) || !fs.lowerVisible(filename) {
+		return nil
+	}
+
+	fi, err := fs.lower.Lstat(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(filename); dir != filename {
+		if err := fs.copyUp(dir); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case fi.IsDir():
+		return fs.upper.MkdirAll(filename, fi.Mode().Perm())
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := fs.lower.Readlink(filename)
+		if err != nil {
+			return err
+		}
+
+		return fs.upper.Symlink(target, filename)
+	default:
+		return fs.copyFile(filename, fi.Mode())
+	}
+}
+
+// copyUpTree copies the given path and all its content from the lower
+// filesystem to the upper one, the directories become opaque.
+func (fs *Union) copyUpTree(filename string) error {
+	if err := fs.copyUp(filename); err != nil {
+		return err
+	}
+
+	fi, err := fs.upper.Lstat(filename)
+	if err != nil || !fi.IsDir() {
+		return err
+	}
+
+	l, err := fs.readDir(filename)
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range l {
+		if err := fs.copyUpTree(fs.Join(filename, fi.Name())); err != nil {
+			return err
+		}
+	}
+
+	return util.WriteFile(fs.upper, fs.Join(filename, opaqueName), nil, 0600)
+}
+
+func (fs *Union) copyFile(filename string, mode os.FileMode) error {
+	src, err := fs.lower.Open(filename)
+	if err != nil {
+		return err
+	}
+
+	dst, err := fs.upper.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		_ = src.Close()
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+
+	if err1 := src.Close(); err == nil {
+		err = err1
+	}
+
+	return err
+}
+
+// whiteout returns the path of the whiteout hiding the given path.
+func whiteout(filename string) string {
+	return filepath.Join(filepath.Dir(filename), whiteoutPrefix+filepath.Base(filename))
+}
+
+func isReserved(filename string) bool {
+	return strings.HasPrefix(filepath.Base(filename), whiteoutPrefix)
+}
+
+func isWrite(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+}
+
+func cleanPath(path string) string {
+	path = filepath.FromSlash(path)
+	rel, err := filepath.Rel(separator, path)
+	if err == nil {
+		path = rel
+	}
+
+	return filepath.Clean(path)
+}
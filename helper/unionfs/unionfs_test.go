@@ -0,0 +1,167 @@
+package unionfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&UnionSuite{})
+
+type UnionSuite struct {
+	test.FilesystemSuite
+	upper billy.Filesystem
+	lower billy.Filesystem
+}
+
+func (s *UnionSuite) SetUpTest(c *C) {
+	s.upper = memfs.New()
+	s.lower = memfs.New()
+	s.FilesystemSuite = test.NewFilesystemSuite(New(s.upper, s.lower))
+}
+
+func (s *UnionSuite) TestReadFallThrough(c *C) {
+	err := util.WriteFile(s.lower, "foo/bar", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+
+	s.assertContent(c, s.FS, "foo/bar", "lower")
+
+	fi, err := s.FS.Stat("foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(5))
+
+	_, err = s.upper.Stat("foo/bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *UnionSuite) TestShadowing(c *C) {
+	err := util.WriteFile(s.lower, "foo", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.upper, "foo", []byte("upper"), 0644)
+	c.Assert(err, IsNil)
+
+	s.assertContent(c, s.FS, "foo", "upper")
+}
+
+func (s *UnionSuite) TestWriteCopiesUp(c *C) {
+	err := util.WriteFile(s.lower, "foo", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.OpenFile("foo", os.O_WRONLY|os.O_APPEND, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("-upper"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	s.assertContent(c, s.FS, "foo", "lower-upper")
+	s.assertContent(c, s.upper, "foo", "lower-upper")
+	s.assertContent(c, s.lower, "foo", "lower")
+}
+
+func (s *UnionSuite) TestRemoveWhiteout(c *C) {
+	err := util.WriteFile(s.lower, "foo/bar", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.FS.Remove("foo/bar"), IsNil)
+
+	_, err = s.FS.Stat("foo/bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	fis, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 0)
+
+	_, err = s.upper.Stat("foo/.wh.bar")
+	c.Assert(err, IsNil)
+	s.assertContent(c, s.lower, "foo/bar", "lower")
+
+	_, err = New(s.upper, s.lower).Stat("foo/bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *UnionSuite) TestRemoveAndRecreate(c *C) {
+	err := util.WriteFile(s.lower, "foo", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.FS.Remove("foo"), IsNil)
+	err = util.WriteFile(s.FS, "foo", []byte("upper"), 0644)
+	c.Assert(err, IsNil)
+
+	s.assertContent(c, s.FS, "foo", "upper")
+
+	_, err = s.upper.Stat(".wh.foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *UnionSuite) TestRemoveAndRecreateDir(c *C) {
+	err := util.WriteFile(s.lower, "foo/bar", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(util.RemoveAll(s.FS, "foo"), IsNil)
+	c.Assert(s.FS.MkdirAll("foo", 0755), IsNil)
+
+	fis, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 0)
+
+	_, err = s.FS.Stat("foo/bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *UnionSuite) TestRenameFromLower(c *C) {
+	err := util.WriteFile(s.lower, "foo/bar", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.FS.Rename("foo", "qux"), IsNil)
+
+	_, err = s.FS.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	s.assertContent(c, s.FS, "qux/bar", "lower")
+	s.assertContent(c, s.lower, "foo/bar", "lower")
+}
+
+func (s *UnionSuite) TestReadDirMerge(c *C) {
+	err := util.WriteFile(s.lower, "foo/bar", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.lower, "foo/qux", []byte("lower"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.FS, "foo/baz", []byte("upper"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.FS, "foo/qux", []byte("upper!"), 0644)
+	c.Assert(err, IsNil)
+	c.Assert(s.FS.Remove("foo/bar"), IsNil)
+
+	fis, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 2)
+	c.Assert(fis[0].Name(), Equals, "baz")
+	c.Assert(fis[1].Name(), Equals, "qux")
+	c.Assert(fis[1].Size(), Equals, int64(6))
+}
+
+func (s *UnionSuite) TestReservedName(c *C) {
+	_, err := s.FS.Create(".wh.foo")
+	c.Assert(err, NotNil)
+
+	c.Assert(s.FS.MkdirAll("foo/.wh.bar", 0755), NotNil)
+}
+
+func (s *UnionSuite) assertContent(c *C, fs billy.Basic, filename, expected string) {
+	f, err := fs.Open(filename)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, expected)
+	c.Assert(f.Close(), IsNil)
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/go-git/go-billy/v5"
@@ -21,7 +23,17 @@ const separator = filepath.Separator
 type Memory struct {
 	s *storage
 
+	// explicitDirs is set when the parent directories must exist to create
+	// a file, see NewExplicitDirs.
+	explicitDirs bool
+
+	// tempCount and temps are guarded by tempMu.
 	tempCount int
+
+	// temps holds the names of the files created through TempFile, to
+	// remove the leftovers with CleanupTempFiles.
+	temps  map[string]struct{}
+	tempMu sync.Mutex
 }
 
 //New returns a new Memory filesystem.
@@ -30,6 +42,95 @@ func New() billy.Filesystem {
 	return chroot.New(fs, string(separator))
 }
 
+// NewWithSpace returns a new Memory filesystem able to hold up to totalBytes
+// of content, the sum of the size of all the files. The writes and truncates
+// growing the content past it fail with an *os.PathError wrapping ENOSPC, like
+// a full disk. The anonymous temporary files are not accounted.
+func NewWithSpace(totalBytes int64) billy.Filesystem {
+	s := newStorage()
+	s.space = &space{total: totalBytes}
+	return chroot.New(&Memory{s: s}, string(separator))
+}
+
+// NewExplicitDirs returns a new Memory filesystem where, as on a real OS,
+// the directories are only created by MkdirAll. Creating a file, a symlink or
+// renaming a file into a missing directory fails with an error wrapping
+// os.ErrNotExist, instead of creating its parent directories as New does,
+// eg.: to catch in tests the code missing a MkdirAll, when run over an OS
+// filesystem without the implicit creation of osfs.
+func NewExplicitDirs() billy.Filesystem {
+	fs := &Memory{s: newStorage(), explicitDirs: true}
+	return chroot.New(fs, string(separator))
+}
+
+// CreateSized creates the named file, like Create, reserving up front the
+// capacity to hold size bytes if it implements billy.Grower, as the files
+// from this package, so writing the content sequentially doesn't reallocate
+// it. Any other file is returned as is.
+func CreateSized(fs billy.Basic, filename string, size int64) (billy.File, error) {
+	f, err := fs.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if g, ok := f.(billy.Grower); ok {
+		g.Grow(int(size))
+	}
+
+	return f, nil
+}
+
+// OpenCOW opens the named file for reading and writing, copy-on-write, if it
+// is a file from this package: the handle shares the content of the file
+// until the first write, when it forks a private copy of it, and on Close the
+// content of the file is replaced at once with the copy, so the changes are
+// seen by the other handles either all or none. Any other file is returned as
+// is.
+func OpenCOW(fs billy.Basic, filename string) (billy.File, error) {
+	f, err := fs.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if mf := memFile(f); mf != nil {
+		mf.cow = true
+	}
+
+	return f, nil
+}
+
+// Bytes returns the content of f without copying it, eg.: to parse it in
+// place, if it is a file from this package, failing with
+// billy.ErrNotSupported otherwise. It's available only on the files opened
+// read-only, and the slice returned must not be modified. It's the one of the
+// file: a later write or truncate, through any handle, may change it in
+// place, and doing so while it's read is a data race, so the file must not be
+// written while the slice is in use.
+func Bytes(f billy.File) ([]byte, error) {
+	mf := memFile(f)
+	if mf == nil {
+		return nil, billy.ErrNotSupported
+	}
+
+	return mf.contentBytes()
+}
+
+// memFile returns the file from this package wrapped by f, if any.
+func memFile(f billy.File) *file {
+	for {
+		if mf, ok := f.(*file); ok {
+			return mf
+		}
+
+		uf, ok := f.(interface{ Underlying() billy.File })
+		if !ok {
+			return nil
+		}
+
+		f = uf.Underlying()
+	}
+}
+
 func (fs *Memory) Create(filename string) (billy.File, error) {
 	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 }
@@ -38,39 +139,77 @@ func (fs *Memory) Open(filename string) (billy.File, error) {
 	return fs.OpenFile(filename, os.O_RDONLY, 0)
 }
 
+// OpenFile opens the named file, creating it with os.O_CREATE, along with its
+// missing parent directories, as osfs does, unless created by NewExplicitDirs.
+// The directories can't be opened, whatever the flags, failing with an
+// *os.PathError wrapping syscall.EISDIR. With os.O_CREATE and os.O_EXCL, it
+// fails with an *os.PathError wrapping os.ErrExist if the file exists, even
+// as a symlink.
 func (fs *Memory) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
-	f, has := fs.s.Get(filename)
-	if !has {
-		if !isCreate(flag) {
-			return nil, os.ErrNotExist
+	var f *file
+	var created bool
+	if isCreate(flag) {
+		if !fs.hasParent(filename) {
+			return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
 		}
 
 		var err error
-		f, err = fs.s.New(filename, perm, flag)
+		f, created, err = fs.s.GetOrNew(filename, perm, flag)
 		if err != nil {
 			return nil, err
 		}
+
+		if !created && isExclusive(flag) {
+			return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrExist}
+		}
 	} else {
+		var has bool
+		f, has = fs.s.Get(filename)
+		if !has {
+			return nil, os.ErrNotExist
+		}
+	}
+
+	if !created {
 		if target, isLink := fs.resolveLink(filename, f); isLink {
 			return fs.OpenFile(target, flag, perm)
 		}
 	}
 
 	if f.mode.IsDir() {
-		return nil, fmt.Errorf("cannot open directory: %s", filename)
+		return nil, &os.PathError{Op: "open", Path: filename, Err: syscall.EISDIR}
 	}
 
 	return f.Duplicate(filename, perm, flag), nil
 }
 
-var errNotLink = errors.New("not a link")
+var (
+	errNotLink = errors.New("not a link")
+	errNotDir  = syscall.ENOTDIR
+)
+
+// hasParent returns false if the parent directory of path is missing and the
+// directories must be created explicitly.
+func (fs *Memory) hasParent(path string) bool {
+	if !fs.explicitDirs {
+		return true
+	}
+
+	dir := filepath.Dir(clean(path))
+	if dir == "." || dir == string(separator) {
+		return true
+	}
+
+	fi, err := fs.Stat(dir)
+	return err == nil && fi.IsDir()
+}
 
 func (fs *Memory) resolveLink(fullpath string, f *file) (target string, isLink bool) {
 	if !isSymlink(f.mode) {
 		return fullpath, false
 	}
 
-	target = string(f.content.bytes)
+	target = f.content.String()
 	if !isAbs(target) {
 		target = fs.Join(filepath.Dir(fullpath), target)
 	}
@@ -78,6 +217,13 @@ func (fs *Memory) resolveLink(fullpath string, f *file) (target string, isLink b
 	return target, true
 }
 
+// isRoot returns true if path is the root of the filesystem, which exists
+// even without an entry in the storage.
+func isRoot(path string) bool {
+	path = clean(path)
+	return path == "." || path == string(separator)
+}
+
 // On Windows OS, IsAbs validates if a path is valid based on if stars with a
 // unit (eg.: `C:\`)  to assert that is absolute, but in this mem implementation
 // any path starting by `separator` is also considered absolute.
@@ -117,11 +263,21 @@ func (fs *Memory) Lstat(filename string) (os.FileInfo, error) {
 	return f.Stat()
 }
 
+// ReadDir returns the entries of the directory at path, following the
+// symlinks. It fails with an *os.PathError wrapping os.ErrNotExist if path
+// doesn't exist, or syscall.ENOTDIR if it isn't a directory, an empty slice
+// being returned only for the empty directories.
 func (fs *Memory) ReadDir(path string) ([]os.FileInfo, error) {
-	if f, has := fs.s.Get(path); has {
-		if target, isLink := fs.resolveLink(path, f); isLink {
-			return fs.ReadDir(target)
-		}
+	f, has := fs.s.Get(path)
+	switch {
+	case !has && !isRoot(path):
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrNotExist}
+	case !has:
+	case isSymlink(f.mode):
+		target, _ := fs.resolveLink(path, f)
+		return fs.ReadDir(target)
+	case !f.mode.IsDir():
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: errNotDir}
 	}
 
 	var entries []os.FileInfo
@@ -139,38 +295,162 @@ func (fs *Memory) MkdirAll(path string, perm os.FileMode) error {
 }
 
 func (fs *Memory) TempFile(dir, prefix string) (billy.File, error) {
-	return util.TempFile(fs, dir, prefix)
+	return fs.trackTempFile(util.TempFile(fs, dir, prefix))
+}
+
+// TempFileSuffix implements the billy.TempFileSuffix interface.
+func (fs *Memory) TempFileSuffix(dir, prefix, suffix string) (billy.File, error) {
+	return fs.trackTempFile(util.TempFileSuffix(fs, dir, prefix, suffix))
+}
+
+// AnonTempFile implements the billy.AnonTempFile interface. The content of the
+// file is not stored, so it is not reachable from the filesystem.
+func (fs *Memory) AnonTempFile() (billy.File, error) {
+	return &file{
+		content: newContent("", nil),
+		mode:    0600,
+		flag:    os.O_RDWR,
+		btime:   time.Now(),
+	}, nil
+}
+
+// Compact reallocates the content of every file to fit its length, releasing
+// the capacity left unused by truncates and growths, eg.: to reclaim memory
+// after a burst of large files. It is safe to call with files open, they keep
+// seeing the same content. The content of the removed files, and of the
+// anonymous temporary files, isn't held by the filesystem, it is freed once
+// all the files opened on it are closed.
+func (fs *Memory) Compact() {
+	fs.s.mu.RLock()
+	defer fs.s.mu.RUnlock()
+
+	for _, f := range fs.s.files {
+		f.content.Compact()
+	}
 }
 
 func (fs *Memory) getTempFilename(dir, prefix string) string {
+	fs.tempMu.Lock()
+	defer fs.tempMu.Unlock()
+
 	fs.tempCount++
 	filename := fmt.Sprintf("%s_%d_%d", prefix, fs.tempCount, time.Now().UnixNano())
 	return fs.Join(dir, filename)
 }
 
+// Rename renames from to to, replacing the file at to, if any, as POSIX
+// rename. A directory can replace only an empty directory, and a file only
+// a file.
 func (fs *Memory) Rename(from, to string) error {
+	if !fs.hasParent(to) {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrNotExist}
+	}
+
 	return fs.s.Rename(from, to)
 }
 
+// Exchange implements the billy.Exchange interface.
+func (fs *Memory) Exchange(a, b string) error {
+	return fs.s.Exchange(a, b)
+}
+
 func (fs *Memory) Remove(filename string) error {
 	return fs.s.Remove(filename)
 }
 
+// RemoveAll removes path and any children it contains, at once. If the path
+// does not exist, RemoveAll returns nil.
+func (fs *Memory) RemoveAll(path string) error {
+	return fs.s.RemoveAll(path)
+}
+
+// chmodBits are the bits of the mode changed by Chmod, as os.Chmod.
+const chmodBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// Chmod implements the billy.Chmod interface.
+func (fs *Memory) Chmod(name string, mode os.FileMode) error {
+	f, has := fs.s.Get(name)
+	if !has {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrNotExist}
+	}
+
+	if target, isLink := fs.resolveLink(name, f); isLink {
+		return fs.Chmod(target, mode)
+	}
+
+	if err := fs.s.Chmod(name, mode, chmodBits); err != nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: err}
+	}
+
+	return nil
+}
+
+// Chtimes implements the billy.Chtimes interface, the access time is not
+// kept so atime is ignored.
+func (fs *Memory) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	f, has := fs.s.Get(name)
+	if !has {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrNotExist}
+	}
+
+	if target, isLink := fs.resolveLink(name, f); isLink {
+		return fs.Chtimes(target, atime, mtime)
+	}
+
+	f.content.SetModTime(mtime)
+	return nil
+}
+
 func (fs *Memory) Join(elem ...string) string {
 	return filepath.Join(elem...)
 }
 
 func (fs *Memory) Symlink(target, link string) error {
-	_, err := fs.Stat(link)
-	if err == nil {
-		return os.ErrExist
+	if !fs.hasParent(link) {
+		return &os.LinkError{Op: "symlink", Old: target, New: link, Err: os.ErrNotExist}
 	}
 
-	if !os.IsNotExist(err) {
-		return err
+	_, err := fs.s.NewSymlink(link, target)
+	return err
+}
+
+// badNodeTypes are the types of file that CreateNode can't model, the symlinks
+// need a target, see Symlink.
+const badNodeTypes = os.ModeSymlink | os.ModeIrregular
+
+// CreateNode creates an empty entry with the given mode, including its type
+// bits, eg.: to keep a device node or a named pipe extracted from a tar. The
+// entry has no content, the mode is only stored to be reported back by Stat.
+// It fails with billy.ErrNotSupported for symlinks and irregular files, and
+// with an error wrapping os.ErrExist if the name is taken.
+func (fs *Memory) CreateNode(name string, mode os.FileMode) error {
+	if mode&badNodeTypes != 0 {
+		return &os.PathError{Op: "mknod", Path: name, Err: billy.ErrNotSupported}
+	}
+
+	if !fs.hasParent(name) {
+		return &os.PathError{Op: "mknod", Path: name, Err: os.ErrNotExist}
+	}
+
+	if _, err := fs.s.NewNode(name, mode); err != nil {
+		return &os.PathError{Op: "mknod", Path: name, Err: err}
 	}
 
-	return util.WriteFile(fs, link, []byte(target), 0777|os.ModeSymlink)
+	return nil
+}
+
+// Link implements the billy.Link interface, newname shares the content of
+// oldname, the mode is kept by each name.
+func (fs *Memory) Link(oldname, newname string) error {
+	if !fs.hasParent(newname) {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+
+	if err := fs.s.Link(oldname, newname); err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+
+	return nil
 }
 
 func (fs *Memory) Readlink(link string) (string, error) {
@@ -187,7 +467,13 @@ func (fs *Memory) Readlink(link string) (string, error) {
 		}
 	}
 
-	return string(f.content.bytes), nil
+	return f.content.String(), nil
+}
+
+// InMemory returns true, the content of the filesystem is held in memory, it
+// is used by util.IsInMemory.
+func (fs *Memory) InMemory() bool {
+	return true
 }
 
 // Capabilities implements the Capable interface.
@@ -196,7 +482,10 @@ func (fs *Memory) Capabilities() billy.Capability {
 		billy.ReadCapability |
 		billy.ReadAndWriteCapability |
 		billy.SeekCapability |
-		billy.TruncateCapability
+		billy.TruncateCapability |
+		billy.SymlinkCapability |
+		billy.ChrootCapability |
+		billy.TempFileCapability
 }
 
 type file struct {
@@ -205,6 +494,12 @@ type file struct {
 	position int64
 	flag     int
 	mode     os.FileMode
+	btime    time.Time
+
+	// cow is set on the handles opened by OpenCOW, shared is then the content
+	// of the file once forked on the first write, to be replaced on Close.
+	cow    bool
+	shared *content
 
 	isClosed bool
 }
@@ -213,6 +508,11 @@ func (f *file) Name() string {
 	return f.name
 }
 
+// Flags returns the flags used to open the file.
+func (f *file) Flags() int {
+	return f.flag
+}
+
 func (f *file) Read(b []byte) (int, error) {
 	n, err := f.ReadAt(b, f.position)
 	f.position += int64(n)
@@ -224,13 +524,17 @@ func (f *file) Read(b []byte) (int, error) {
 	return n, err
 }
 
+// ReadAt reads len(b) bytes from the file starting at off, without using nor
+// changing the position of the file. Unlike Read, Write and Seek, which share
+// the position, it is safe to call ReadAt concurrently on the same file, even
+// while the content is written through other handles.
 func (f *file) ReadAt(b []byte, off int64) (int, error) {
 	if f.isClosed {
 		return 0, os.ErrClosed
 	}
 
 	if !isReadAndWrite(f.flag) && !isReadOnly(f.flag) {
-		return 0, errors.New("read not supported")
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: billy.ErrWriteOnly}
 	}
 
 	n, err := f.content.ReadAt(b, off)
@@ -250,6 +554,8 @@ func (f *file) Seek(offset int64, whence int) (int64, error) {
 		f.position = offset
 	case io.SeekEnd:
 		f.position = int64(f.content.Len()) + offset
+	default:
+		return f.position, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
 	}
 
 	return f.position, nil
@@ -261,12 +567,44 @@ func (f *file) Write(p []byte) (int, error) {
 	}
 
 	if !isReadAndWrite(f.flag) && !isWriteOnly(f.flag) {
-		return 0, errors.New("write not supported")
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
 	}
 
+	f.fork()
 	n, err := f.content.WriteAt(p, f.position)
 	f.position += int64(n)
+	return n, err
+}
+
+// WriteAt writes len(b) bytes at the given offset, without changing the
+// position of the file.
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
 
+	if !isReadAndWrite(f.flag) && !isWriteOnly(f.flag) {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+	}
+
+	f.fork()
+	return f.content.WriteAt(p, off)
+}
+
+// WriteBuffers implements the billy.BuffersWriter interface, the buffers are
+// written at once, as a single write.
+func (f *file) WriteBuffers(bufs [][]byte) (int64, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	if !isReadAndWrite(f.flag) && !isWriteOnly(f.flag) {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+	}
+
+	f.fork()
+	n, err := f.content.WriteBuffersAt(bufs, f.position)
+	f.position += n
 	return n, err
 }
 
@@ -276,14 +614,71 @@ func (f *file) Close() error {
 	}
 
 	f.isClosed = true
+	return f.commit()
+}
+
+// fork makes a private copy of the content on the first write of a handle
+// opened by OpenCOW.
+func (f *file) fork() {
+	if !f.cow || f.shared != nil {
+		return
+	}
+
+	c := newContent(f.content.name, nil)
+	c.bytes = f.content.CopyBytes()
+
+	f.shared, f.content = f.content, c
+}
+
+// commit replaces the content shared with the other handles with the private
+// copy, if forked.
+func (f *file) commit() error {
+	if f.shared == nil {
+		return nil
+	}
+
+	shared, c := f.shared, f.content
+	f.shared, f.content = nil, shared
+
+	if err := shared.Replace(c.bytes); err != nil {
+		return &os.PathError{Op: "close", Path: f.name, Err: err}
+	}
+
 	return nil
 }
 
+// contentBytes returns the content of the file without copying it, as Bytes.
+func (f *file) contentBytes() ([]byte, error) {
+	if f.isClosed {
+		return nil, os.ErrClosed
+	}
+
+	if !isReadOnly(f.flag) {
+		return nil, &os.PathError{Op: "bytes", Path: f.name, Err: os.ErrPermission}
+	}
+
+	return f.content.Bytes(), nil
+}
+
+// Grow implements the billy.Grower interface, it reserves the capacity to
+// write another n bytes past the end of the file without reallocating its
+// content. The size of the file is not changed.
+func (f *file) Grow(n int) {
+	f.content.Grow(n)
+}
+
+// Truncate changes the size of the file. The content is shared by all the
+// handles of the same file, like an inode, so the change is seen by all of
+// them, unless opened by OpenCOW. The position of the other handles is not
+// changed, writing past the new size fills the gap with zeros.
 func (f *file) Truncate(size int64) error {
-	if size < int64(len(f.content.bytes)) {
-		f.content.bytes = f.content.bytes[:size]
-	} else if more := int(size) - len(f.content.bytes); more > 0 {
-		f.content.bytes = append(f.content.bytes, make([]byte, more)...)
+	if size < 0 {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: os.ErrInvalid}
+	}
+
+	f.fork()
+	if err := f.content.SetSize(int(size)); err != nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: err}
 	}
 
 	return nil
@@ -295,6 +690,7 @@ func (f *file) Duplicate(filename string, mode os.FileMode, flag int) billy.File
 		content: f.content,
 		mode:    mode,
 		flag:    flag,
+		btime:   f.btime,
 	}
 
 	if isAppend(flag) {
@@ -308,12 +704,51 @@ func (f *file) Duplicate(filename string, mode os.FileMode, flag int) billy.File
 	return new
 }
 
+// Stat returns the FileInfo of the file, named with its base name, as the
+// one of an *os.File. The size of directories is always reported as 0,
+// regardless of its content, on Stat, Lstat and ReadDir.
 func (f *file) Stat() (os.FileInfo, error) {
+	f.content.mu.RLock()
+	defer f.content.mu.RUnlock()
+
+	return f.stat(), nil
+}
+
+// ReadStat returns a copy of the whole content of the file along with its
+// FileInfo, both read at once, so the FileInfo is the one of the content
+// returned, whatever the writes through the other handles.
+// util.ReadFileStat relies on it.
+func (f *file) ReadStat() ([]byte, os.FileInfo, error) {
+	if f.isClosed {
+		return nil, nil, os.ErrClosed
+	}
+
+	if !isReadAndWrite(f.flag) && !isReadOnly(f.flag) {
+		return nil, nil, &os.PathError{Op: "read", Path: f.name, Err: billy.ErrWriteOnly}
+	}
+
+	f.content.mu.RLock()
+	defer f.content.mu.RUnlock()
+
+	return append([]byte(nil), f.content.bytes...), f.stat(), nil
+}
+
+// stat returns the FileInfo of the file, it must be called with the lock of
+// the content held.
+func (f *file) stat() *fileInfo {
+	size := len(f.content.bytes)
+	if f.mode.IsDir() {
+		size = 0
+	}
+
 	return &fileInfo{
-		name: f.Name(),
-		mode: f.mode,
-		size: f.content.Len(),
-	}, nil
+		name:    filepath.Base(f.Name()),
+		mode:    f.mode,
+		size:    size,
+		modTime: f.content.modTime,
+		btime:   f.btime,
+		nlink:   f.content.Links(),
+	}
 }
 
 // Lock is a no-op in memfs.
@@ -326,10 +761,18 @@ func (f *file) Unlock() error {
 	return nil
 }
 
+// Sync is a no-op in memfs, there is no stable storage.
+func (f *file) Sync() error {
+	return nil
+}
+
 type fileInfo struct {
-	name string
-	size int
-	mode os.FileMode
+	name    string
+	size    int
+	mode    os.FileMode
+	modTime time.Time
+	btime   time.Time
+	nlink   int
 }
 
 func (fi *fileInfo) Name() string {
@@ -344,23 +787,163 @@ func (fi *fileInfo) Mode() os.FileMode {
 	return fi.mode
 }
 
-func (*fileInfo) ModTime() time.Time {
-	return time.Now()
+// ModTime returns the time of the last write or truncation of the file, or
+// the time it was created if it was never changed.
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
 }
 
 func (fi *fileInfo) IsDir() bool {
 	return fi.mode.IsDir()
 }
 
-func (*fileInfo) Sys() interface{} {
-	return nil
+// Sys returns a *Sys, holding the attributes of the file not covered by
+// os.FileInfo.
+func (fi *fileInfo) Sys() interface{} {
+	return &Sys{Btime: fi.btime, Nlink: uint64(fi.nlink)}
+}
+
+// Sys holds the attributes of a file not covered by os.FileInfo, it is the
+// value returned by the Sys method of the FileInfo from this filesystem.
+type Sys struct {
+	// Btime is the birth time of the file, the time when it was created.
+	Btime time.Time
+	// Nlink is the number of hard links to the file, 1 for the directories,
+	// and 0 once removed if still open.
+	Nlink uint64
+}
+
+// BirthTime returns the birth time of the file.
+func (s *Sys) BirthTime() time.Time {
+	return s.Btime
+}
+
+// LinkCount returns the number of hard links to the file.
+func (s *Sys) LinkCount() uint64 {
+	return s.Nlink
 }
 
 func (c *content) Truncate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.resize(0)
 	c.bytes = make([]byte, 0)
+	c.changed()
+}
+
+// SetSize changes the length of the content to size, growing it with zeros.
+func (c *content) SetSize(size int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.resize(size); err != nil {
+		return err
+	}
+
+	if size < len(c.bytes) {
+		c.bytes = c.bytes[:size]
+	} else if more := size - len(c.bytes); more > 0 {
+		c.bytes = append(c.bytes, make([]byte, more)...)
+	}
+
+	c.changed()
+	return nil
+}
+
+// Replace replaces the bytes of the content with the given ones.
+func (c *content) Replace(bytes []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.resize(len(bytes)); err != nil {
+		return err
+	}
+
+	c.bytes = bytes
+	c.changed()
+	return nil
+}
+
+// Bytes returns the bytes of the content, not a copy of them.
+func (c *content) Bytes() []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.bytes
+}
+
+// CopyBytes returns a copy of the bytes of the content.
+func (c *content) CopyBytes() []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]byte(nil), c.bytes...)
+}
+
+// String returns the content as a string, eg.: the target of a symlink.
+func (c *content) String() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return string(c.bytes)
+}
+
+// ModTime returns the time of the last change of the content.
+func (c *content) ModTime() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.modTime
+}
+
+// SetModTime sets the time of the last change of the content, as Chtimes.
+func (c *content) SetModTime(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.modTime = t
+}
+
+// Version returns the version of the content, increased on every change.
+func (c *content) Version() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.version
+}
+
+func (c *content) Grow(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n <= 0 || cap(c.bytes)-len(c.bytes) >= n {
+		return
+	}
+
+	bytes := make([]byte, len(c.bytes), len(c.bytes)+n)
+	copy(bytes, c.bytes)
+	c.bytes = bytes
+}
+
+// Compact reallocates the bytes to its length, if the capacity exceeds it.
+func (c *content) Compact() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cap(c.bytes) == len(c.bytes) {
+		return
+	}
+
+	bytes := make([]byte, len(c.bytes))
+	copy(bytes, c.bytes)
+	c.bytes = bytes
 }
 
 func (c *content) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return len(c.bytes)
 }
 
@@ -368,6 +951,10 @@ func isCreate(flag int) bool {
 	return flag&os.O_CREATE != 0
 }
 
+func isExclusive(flag int) bool {
+	return flag&os.O_EXCL != 0
+}
+
 func isAppend(flag int) bool {
 	return flag&os.O_APPEND != 0
 }
@@ -380,8 +967,10 @@ func isReadAndWrite(flag int) bool {
 	return flag&os.O_RDWR != 0
 }
 
+// isReadOnly tells if the access mode of flag is os.O_RDONLY, whatever the
+// other flags, as os.O_CREATE.
 func isReadOnly(flag int) bool {
-	return flag == os.O_RDONLY
+	return flag&(os.O_WRONLY|os.O_RDWR) == 0
 }
 
 func isWriteOnly(flag int) bool {
@@ -1,11 +1,22 @@
 package memfs
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-billy/v5/test"
+	"github.com/go-git/go-billy/v5/util"
 
 	. "gopkg.in/check.v1"
 )
@@ -28,7 +39,7 @@ func (s *MemorySuite) TestCapabilities(c *C) {
 	c.Assert(ok, Equals, true)
 
 	caps := billy.Capabilities(s.FS)
-	c.Assert(caps, Equals, billy.DefaultCapabilities&^billy.LockCapability)
+	c.Assert(caps, Equals, billy.AllCapabilities&^billy.LockCapability)
 }
 
 func (s *MemorySuite) TestNegativeOffsets(c *C) {
@@ -44,3 +55,1104 @@ func (s *MemorySuite) TestNegativeOffsets(c *C) {
 	_, err = f.Write(buf)
 	c.Assert(err, ErrorMatches, "writeat negative: negative offset")
 }
+
+func (s *MemorySuite) TestDirectorySize(c *C) {
+	err := s.FS.MkdirAll("foo/bar", 0755)
+	c.Assert(err, IsNil)
+
+	err = util.WriteFile(s.FS, "foo/qux", []byte("qux"), 0644)
+	c.Assert(err, IsNil)
+
+	fi, err := s.FS.Stat("foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(0))
+
+	fi, err = s.FS.Lstat("foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(0))
+
+	fis, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 2)
+	for _, fi := range fis {
+		if fi.IsDir() {
+			c.Assert(fi.Size(), Equals, int64(0))
+		} else {
+			c.Assert(fi.Size(), Equals, int64(3))
+		}
+	}
+
+	fi, err = s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(0))
+}
+
+func (s *MemorySuite) TestFileStatName(c *C) {
+	err := util.WriteFile(s.FS, "foo/bar", []byte("bar"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.Open("foo/bar")
+	c.Assert(err, IsNil)
+
+	fi, err := f.(interface{ Underlying() billy.File }).Underlying().(*file).Stat()
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "bar")
+	c.Assert(fi.Size(), Equals, int64(3))
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestSymlinkConcurrent(c *C) {
+	const n = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- s.FS.Symlink(fmt.Sprintf("target-%d", i), "link")
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var succeeded int
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+
+		c.Assert(os.IsExist(err), Equals, true)
+	}
+
+	c.Assert(succeeded, Equals, 1)
+}
+
+func (s *MemorySuite) TestConcurrentCreateRemove(c *C) {
+	const n, rounds = 16, 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- createRemove(s.FS, fmt.Sprintf("dir/%d", i), rounds)
+		}(i)
+
+		go func(i int) {
+			defer wg.Done()
+			errs <- statRenamed(s.FS, fmt.Sprintf("dir/%d", i), rounds)
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		c.Assert(err, IsNil)
+	}
+
+	entries, err := s.FS.ReadDir("dir")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+}
+
+func (s *MemorySuite) TestSeekInvalidWhence(c *C) {
+	f, err := s.FS.Create("foo")
+	c.Assert(err, IsNil)
+
+	_, err = f.Seek(1, 99)
+	c.Assert(errors.Is(err, syscall.EINVAL), Equals, true)
+
+	var perr *os.PathError
+	c.Assert(errors.As(err, &perr), Equals, true)
+	c.Assert(perr.Op, Equals, "seek")
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestReadOnlyCreate(c *C) {
+	c.Assert(util.WriteFile(s.FS, "foo", []byte("foo"), 0644), IsNil)
+
+	f, err := s.FS.OpenFile("foo", os.O_RDONLY|os.O_CREATE, 0644)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "foo")
+
+	_, err = f.Write([]byte("bar"))
+	c.Assert(os.IsPermission(err), Equals, true)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestConcurrentCreate(c *C) {
+	const n, rounds = 8, 500
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n*rounds)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				f, err := s.FS.Create(fmt.Sprintf("dir/%d/foo", j))
+				if err == nil {
+					err = f.Close()
+				}
+
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		c.Assert(err, IsNil)
+	}
+
+	entries, err := s.FS.ReadDir("dir")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, rounds)
+}
+
+func (s *MemorySuite) TestConcurrentChmodStat(c *C) {
+	const rounds = 200
+
+	c.Assert(util.WriteFile(s.FS, "foo", nil, 0644), IsNil)
+	ch := s.FS.(billy.Chmod)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if err := ch.Chmod("foo", os.FileMode(0600+i%2*0044)); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if _, err := s.FS.Stat("foo"); err != nil {
+				errs <- err
+				return
+			}
+
+			if _, err := s.FS.ReadDir("/"); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		c.Assert(err, IsNil)
+	}
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Mode(), Equals, os.FileMode(0644))
+}
+
+func (s *MemorySuite) TestConcurrentRenameStat(c *C) {
+	const rounds = 1000
+
+	c.Assert(util.WriteFile(s.FS, "foo", nil, 0644), IsNil)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- statRenamed(s.FS, "foo", rounds)
+	}()
+
+	for i := 0; i < rounds; i++ {
+		c.Assert(s.FS.Rename("foo", "foo.renamed"), IsNil)
+		c.Assert(s.FS.Rename("foo.renamed", "foo"), IsNil)
+	}
+
+	c.Assert(<-errs, IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "foo")
+}
+
+// statRenamed stats the given file rounds times, under its name and once
+// renamed by createRemove. The files not found are ignored.
+func statRenamed(fs billy.Filesystem, filename string, rounds int) error {
+	for i := 0; i < rounds; i++ {
+		for _, name := range []string{filename, filename + ".renamed"} {
+			if fi, err := fs.Stat(name); err == nil {
+				_ = fi.Name()
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+
+			if _, err := fs.Lstat(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// createRemove creates, lists, renames and removes the given file rounds
+// times.
+func createRemove(fs billy.Filesystem, filename string, rounds int) error {
+	for i := 0; i < rounds; i++ {
+		if err := util.WriteFile(fs, filename, []byte("foo"), 0644); err != nil {
+			return err
+		}
+
+		if _, err := fs.ReadDir(filepath.Dir(filename)); err != nil {
+			return err
+		}
+
+		if err := fs.Rename(filename, filename+".renamed"); err != nil {
+			return err
+		}
+
+		if _, err := fs.Stat(filename + ".renamed"); err != nil {
+			return err
+		}
+
+		if err := fs.Remove(filename + ".renamed"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MemorySuite) TestCreateSized(c *C) {
+	f, err := CreateSized(s.FS, "foo", 1024)
+	c.Assert(err, IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(0))
+
+	content := f.(interface{ Underlying() billy.File }).Underlying().(*file).content
+	c.Assert(cap(content.bytes), Equals, 1024)
+
+	chunk := make([]byte, 256)
+	for i := 0; i < 4; i++ {
+		_, err = f.Write(chunk)
+		c.Assert(err, IsNil)
+		c.Assert(cap(content.bytes), Equals, 1024)
+	}
+
+	fi, err = s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(1024))
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestGrowKeepsContent(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.OpenFile("foo", os.O_RDWR|os.O_APPEND, 0)
+	c.Assert(err, IsNil)
+
+	g, ok := f.(billy.Grower)
+	c.Assert(ok, Equals, true)
+	g.Grow(100)
+
+	mf := f.(interface{ Underlying() billy.File }).Underlying().(*file)
+	c.Assert(cap(mf.content.bytes) >= 103, Equals, true)
+
+	_, err = f.Write([]byte("bar"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(6))
+}
+
+func (s *MemorySuite) TestBytes(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.Open("foo")
+	c.Assert(err, IsNil)
+
+	b, err := Bytes(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, "foo")
+
+	mf := f.(interface{ Underlying() billy.File }).Underlying().(*file)
+	c.Assert(mf.content.bytes, HasLen, len(b))
+	c.Assert(&b[0], Equals, &mf.content.bytes[0])
+
+	c.Assert(f.Close(), IsNil)
+	_, err = Bytes(f)
+	c.Assert(err, Equals, os.ErrClosed)
+
+	for _, flag := range []int{os.O_WRONLY, os.O_RDWR} {
+		f, err := s.FS.OpenFile("foo", flag, 0)
+		c.Assert(err, IsNil)
+
+		_, err = Bytes(f)
+		c.Assert(errors.Is(err, os.ErrPermission), Equals, true)
+		c.Assert(f.Close(), IsNil)
+	}
+}
+
+func (s *MemorySuite) TestBytesEmpty(c *C) {
+	err := util.WriteFile(s.FS, "foo", nil, 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.Open("foo")
+	c.Assert(err, IsNil)
+
+	b, err := Bytes(f)
+	c.Assert(err, IsNil)
+	c.Assert(b, HasLen, 0)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestBytesNotMemory(c *C) {
+	dir, err := ioutil.TempDir("", "memfs-bytes")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	f, err := osfs.New(dir).Create("foo")
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	_, err = Bytes(f)
+	c.Assert(err, Equals, billy.ErrNotSupported)
+}
+
+func (s *MemorySuite) TestNewWithSpace(c *C) {
+	fs := NewWithSpace(10)
+
+	err := util.WriteFile(fs, "foo", []byte("foobar"), 0644)
+	c.Assert(err, IsNil)
+
+	err = util.WriteFile(fs, "bar", []byte("foobar"), 0644)
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+
+	f, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(11), NotNil)
+	c.Assert(f.Truncate(10), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(fs.Remove("bar"), IsNil)
+	c.Assert(fs.Rename("foo", "bar"), IsNil)
+
+	err = util.WriteFile(fs, "bar", []byte("qux"), 0644)
+	c.Assert(err, IsNil)
+
+	err = util.WriteFile(fs, "foo", []byte("1234567"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(fs.Symlink("target", "link"), NotNil)
+	_, err = fs.Lstat("link")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	err = util.WriteFile(fs, "foo", []byte("12345678"), 0644)
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+}
+
+func (s *MemorySuite) TestWriteBuffersAtomic(c *C) {
+	const rounds = 200
+
+	c.Assert(util.WriteFile(s.FS, "foo", []byte("cccccccc"), 0644), IsNil)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errs <- writeRounds(s.FS, "foo", rounds, func(f billy.File) error {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+
+			_, err := util.WriteBuffers(f, [][]byte{[]byte("aaaa"), []byte("bbbb")})
+			return err
+		})
+	}()
+
+	go func() {
+		defer wg.Done()
+		errs <- writeRounds(s.FS, "foo", rounds, func(f billy.File) error {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+
+			_, err := f.Write([]byte("cccccccc"))
+			return err
+		})
+	}()
+
+	for i := 0; i < rounds; i++ {
+		content, err := util.ReadFileString(s.FS, "foo")
+		c.Assert(err, IsNil)
+		c.Assert(content, Matches, "aaaabbbb|cccccccc")
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		c.Assert(err, IsNil)
+	}
+}
+
+// writeRounds opens the given file for writing and calls write on it rounds
+// times.
+func writeRounds(fs billy.Filesystem, filename string, rounds int, write func(billy.File) error) error {
+	f, err := fs.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	for i := 0; i < rounds; i++ {
+		if err := write(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MemorySuite) TestWriteBuffersNoSpace(c *C) {
+	fs := NewWithSpace(6)
+
+	f, err := fs.Create("foo")
+	c.Assert(err, IsNil)
+
+	n, err := util.WriteBuffers(f, [][]byte{[]byte("foo"), []byte("bar"), []byte("qux")})
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+	c.Assert(n, Equals, int64(0))
+	c.Assert(f.Close(), IsNil)
+
+	fi, err := fs.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(0))
+}
+
+func (s *MemorySuite) TestCompact(c *C) {
+	fs := &Memory{s: newStorage()}
+
+	err := util.WriteFile(fs, "foo", make([]byte, 1024), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(3), IsNil)
+	_, err = f.Write([]byte("bar"))
+	c.Assert(err, IsNil)
+
+	content := f.(*file).content
+	c.Assert(cap(content.bytes) > 3, Equals, true)
+
+	fs.Compact()
+	c.Assert(cap(content.bytes), Equals, 3)
+
+	_, err = f.Write([]byte("qux"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	data, err := util.ReadFileString(fs, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(data, Equals, "barqux")
+}
+
+func (s *MemorySuite) TestRenameMissing(c *C) {
+	err := s.FS.Rename("foo", "bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, ok := err.(*os.LinkError)
+	c.Assert(ok, Equals, true)
+
+	_, err = s.FS.Stat("bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *MemorySuite) TestRenameOverwriteFile(c *C) {
+	c.Assert(util.WriteFile(s.FS, "foo", []byte("foo"), 0644), IsNil)
+	c.Assert(util.WriteFile(s.FS, "bar", []byte("bar"), 0644), IsNil)
+
+	c.Assert(s.FS.Rename("foo", "bar"), IsNil)
+
+	content, err := util.ReadFileString(s.FS, "bar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foo")
+
+	_, err = s.FS.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *MemorySuite) TestRenameOntoDir(c *C) {
+	c.Assert(util.WriteFile(s.FS, "foo/bar", []byte("bar"), 0644), IsNil)
+	c.Assert(util.WriteFile(s.FS, "qux/baz", []byte("baz"), 0644), IsNil)
+	c.Assert(util.WriteFile(s.FS, "file", []byte("file"), 0644), IsNil)
+
+	err := s.FS.Rename("foo", "qux")
+	c.Assert(errors.Is(err, errNotEmpty), Equals, true)
+
+	err = s.FS.Rename("file", "qux")
+	c.Assert(errors.Is(err, syscall.EISDIR), Equals, true)
+
+	err = s.FS.Rename("foo", "file")
+	c.Assert(errors.Is(err, syscall.ENOTDIR), Equals, true)
+
+	for name, expected := range map[string]string{
+		"foo/bar": "bar", "qux/baz": "baz", "file": "file",
+	} {
+		content, err := util.ReadFileString(s.FS, name)
+		c.Assert(err, IsNil)
+		c.Assert(content, Equals, expected)
+	}
+
+	c.Assert(s.FS.MkdirAll("empty", 0755), IsNil)
+	c.Assert(s.FS.Rename("foo", "empty"), IsNil)
+
+	content, err := util.ReadFileString(s.FS, "empty/bar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "bar")
+}
+
+func (s *MemorySuite) TestRenameSubtree(c *C) {
+	fs := &Memory{s: newStorage()}
+
+	names := []string{"a/foo", "a/b/bar", "a/b/c/baz", "ab/qux"}
+	for _, name := range names {
+		c.Assert(util.WriteFile(fs, name, []byte(name), 0644), IsNil)
+	}
+
+	c.Assert(fs.Rename("a", "z"), IsNil)
+
+	for _, name := range names[:3] {
+		_, err := fs.Stat(name)
+		c.Assert(os.IsNotExist(err), Equals, true, Commentf("%s", name))
+
+		moved := filepath.Join("z", strings.TrimPrefix(name, "a/"))
+		content, err := util.ReadFileString(fs, moved)
+		c.Assert(err, IsNil, Commentf("%s", moved))
+		c.Assert(content, Equals, name)
+	}
+
+	entries, err := fs.ReadDir(filepath.Join("z", "b"))
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+
+	_, err = fs.ReadDir("a")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	entries, err = fs.ReadDir(".")
+	c.Assert(err, IsNil)
+	for _, e := range entries {
+		c.Assert(e.Name(), Not(Equals), "a")
+	}
+
+	content, err := util.ReadFileString(fs, "ab/qux")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "ab/qux")
+
+	for p := range fs.s.files {
+		c.Assert(p == "a" || isInside(p, "a"), Equals, false, Commentf("%s", p))
+	}
+
+	for p := range fs.s.children {
+		c.Assert(p == "a" || isInside(p, "a"), Equals, false, Commentf("%s", p))
+	}
+}
+
+func (s *MemorySuite) TestRenameIntoItself(c *C) {
+	err := util.WriteFile(s.FS, "foo/bar", []byte("bar"), 0644)
+	c.Assert(err, IsNil)
+
+	for _, to := range []string{"foo/qux", "foo/bar/qux"} {
+		err = s.FS.Rename("foo", to)
+		c.Assert(errors.Is(err, syscall.EINVAL), Equals, true, Commentf("to %q", to))
+	}
+
+	c.Assert(s.FS.Rename("foo", "foo"), IsNil)
+	c.Assert(s.FS.Rename("foo/bar", "foo/bar"), IsNil)
+	c.Assert(s.FS.Rename("foo/bar", "foo/./bar"), IsNil)
+
+	err = s.FS.Rename("foo/qux", "foo/qux")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	content, err := util.ReadFileString(s.FS, "foo/bar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "bar")
+}
+
+func (s *MemorySuite) TestChrootTwice(c *C) {
+	a, err := s.FS.Chroot("a/")
+	c.Assert(err, IsNil)
+
+	b, err := a.Chroot("./b/../c//")
+	c.Assert(err, IsNil)
+	c.Assert(b.Root(), Equals, filepath.Join(string(separator), "a", "c"))
+
+	type underlying interface{ Underlying() billy.Basic }
+	c.Assert(b.(underlying).Underlying(), Equals, s.FS.(underlying).Underlying())
+
+	c.Assert(util.WriteFile(b, "foo", []byte("foo"), 0644), IsNil)
+
+	content, err := util.ReadFileString(s.FS, "a/c/foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foo")
+
+	_, err = b.Chroot("..")
+	c.Assert(err, Equals, billy.ErrCrossedBoundary)
+}
+
+func (s *MemorySuite) TestConcurrentReadAt(c *C) {
+	content := make([]byte, 1024)
+	for i := range content {
+		content[i] = byte(i)
+	}
+
+	c.Assert(util.WriteFile(s.FS, "foo", content, 0644), IsNil)
+
+	f, err := s.FS.Open("foo")
+	c.Assert(err, IsNil)
+	_, err = f.Seek(10, io.SeekStart)
+	c.Assert(err, IsNil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+
+			buf := make([]byte, 64)
+			for j := 0; j < 100; j++ {
+				n, err := f.ReadAt(buf, off)
+				c.Check(err, IsNil)
+				c.Check(buf[:n], DeepEquals, content[off:off+64])
+			}
+		}(int64(i * 64))
+	}
+
+	wg.Wait()
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	c.Assert(err, IsNil)
+	c.Assert(pos, Equals, int64(10))
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestModTime(c *C) {
+	c.Assert(s.FS.MkdirAll("foo", 0755), IsNil)
+	dir, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(dir.ModTime().IsZero(), Equals, false)
+
+	f, err := s.FS.Create("foo/bar")
+	c.Assert(err, IsNil)
+	created := modTime(c, s.FS, "foo/bar")
+	c.Assert(created.IsZero(), Equals, false)
+
+	time.Sleep(10 * time.Millisecond)
+	c.Assert(modTime(c, s.FS, "foo/bar"), Equals, created)
+
+	_, err = f.Write([]byte("bar"))
+	c.Assert(err, IsNil)
+	written := modTime(c, s.FS, "foo/bar")
+	c.Assert(written.After(created), Equals, true)
+
+	time.Sleep(10 * time.Millisecond)
+	c.Assert(f.Truncate(1), IsNil)
+	truncated := modTime(c, s.FS, "foo/bar")
+	c.Assert(truncated.After(written), Equals, true)
+	c.Assert(f.Close(), IsNil)
+
+	time.Sleep(10 * time.Millisecond)
+	f, err = s.FS.OpenFile("foo/bar", os.O_WRONLY|os.O_APPEND, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("r"))
+	c.Assert(err, IsNil)
+	c.Assert(modTime(c, s.FS, "foo/bar").After(truncated), Equals, true)
+	c.Assert(f.Close(), IsNil)
+}
+
+func modTime(c *C, fs billy.Filesystem, filename string) time.Time {
+	fi, err := fs.Stat(filename)
+	c.Assert(err, IsNil)
+	return fi.ModTime()
+}
+
+func (s *MemorySuite) TestRemoveAll(c *C) {
+	fs := &Memory{s: newStorage()}
+
+	for _, name := range []string{"foo/bar/baz", "foo/bar/qux/quux", "foo/corge", "foobar"} {
+		c.Assert(util.WriteFile(fs, name, []byte(name), 0644), IsNil)
+	}
+
+	c.Assert(fs.Symlink("foobar", "foo/link"), IsNil)
+
+	c.Assert(fs.RemoveAll("foo/bar/"), IsNil)
+	c.Assert(fs.RemoveAll("foo/bar"), IsNil)
+
+	entries, err := fs.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	for _, e := range entries {
+		c.Assert(e.Name(), Not(Equals), "bar")
+	}
+
+	for p := range fs.s.files {
+		c.Assert(strings.HasPrefix(p, filepath.Join("foo", "bar")), Equals, false, Commentf("%s", p))
+	}
+
+	for p := range fs.s.children {
+		c.Assert(strings.HasPrefix(p, filepath.Join("foo", "bar")), Equals, false, Commentf("%s", p))
+	}
+
+	c.Assert(fs.RemoveAll("foo"), IsNil)
+
+	_, err = fs.Stat("foo")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	content, err := util.ReadFileString(fs, "foobar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foobar")
+}
+
+func (s *MemorySuite) TestOpenCOW(c *C) {
+	c.Assert(util.WriteFile(s.FS, "foo", []byte("hello world"), 0644), IsNil)
+
+	r, err := s.FS.Open("foo")
+	c.Assert(err, IsNil)
+
+	f, err := OpenCOW(s.FS, "foo")
+	c.Assert(err, IsNil)
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(f, buf)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf), Equals, "hello")
+
+	_, err = f.Seek(0, io.SeekStart)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("HELLO"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(8), IsNil)
+
+	content, err := util.ReadFileString(s.FS, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "hello world")
+
+	_, err = f.Seek(0, io.SeekStart)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "HELLO wo")
+
+	c.Assert(f.Close(), IsNil)
+
+	content, err = util.ReadFileString(s.FS, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "HELLO wo")
+
+	data, err = ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "HELLO wo")
+	c.Assert(r.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestOpenCOWNoSpace(c *C) {
+	fs := NewWithSpace(10)
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+
+	f, err := OpenCOW(fs, "foo")
+	c.Assert(err, IsNil)
+	_, err = f.Seek(0, io.SeekEnd)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("bar"))
+	c.Assert(err, IsNil)
+	c.Assert(util.WriteFile(fs, "qux", []byte("quxquxqux"), 0644), NotNil)
+	c.Assert(util.WriteFile(fs, "qux", []byte("quxqux"), 0644), IsNil)
+
+	err = f.Close()
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+
+	content, err := util.ReadFileString(fs, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foo")
+}
+
+func (s *MemorySuite) TestExplicitDirs(c *C) {
+	fs := NewExplicitDirs()
+
+	_, err := fs.Create("a/b/c.txt")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = fs.Stat("a")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+
+	_, err = fs.Create("foo/bar")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	c.Assert(fs.Rename("foo", "a/foo"), NotNil)
+	c.Assert(fs.Symlink("foo", "a/link"), NotNil)
+	_, err = util.TempFile(fs, "a", "tmp")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	c.Assert(fs.MkdirAll("a/b", 0755), IsNil)
+	c.Assert(util.WriteFile(fs, "a/b/c.txt", []byte("c"), 0644), IsNil)
+	c.Assert(fs.Rename("foo", "a/foo"), IsNil)
+	c.Assert(fs.Symlink("b", "a/link"), IsNil)
+
+	c.Assert(util.WriteFile(New(), "a/b/c.txt", []byte("c"), 0644), IsNil)
+}
+
+// BenchmarkRenameSubtree renames a small directory back and forth in a
+// filesystem holding many other files, to tell if the cost of the rename
+// depends on the size of the subtree only.
+func BenchmarkRenameSubtree(b *testing.B) {
+	fs := &Memory{s: newStorage()}
+	for i := 0; i < 100000; i++ {
+		if _, err := fs.s.New(fmt.Sprintf("/big/%d/%d", i/100, i%100), 0644, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := fs.s.New(fmt.Sprintf("/small/dir/%d", i), 0644, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := fs.Rename("/small", "/renamed"); err != nil {
+			b.Fatal(err)
+		}
+
+		if err := fs.Rename("/renamed", "/small"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func (s *MemorySuite) TestWriteReadOnly(c *C) {
+	fs := &Memory{s: newStorage()}
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+
+	f, err := fs.Open("foo")
+	c.Assert(err, IsNil)
+
+	_, err = f.Write([]byte("bar"))
+	c.Assert(os.IsPermission(err), Equals, true)
+	c.Assert(err, ErrorMatches, "write foo: permission denied")
+
+	_, err = f.(io.WriterAt).WriteAt([]byte("bar"), 0)
+	c.Assert(os.IsPermission(err), Equals, true)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestReadWriteOnly(c *C) {
+	fs := &Memory{s: newStorage()}
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+
+	f, err := fs.OpenFile("foo", os.O_WRONLY, 0)
+	c.Assert(err, IsNil)
+
+	_, err = f.Read(make([]byte, 3))
+	c.Assert(os.IsPermission(err), Equals, true)
+	c.Assert(err, ErrorMatches, "read foo: permission denied")
+
+	_, err = f.ReadAt(make([]byte, 3), 0)
+	c.Assert(os.IsPermission(err), Equals, true)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *MemorySuite) TestLinkKeepsSpace(c *C) {
+	fs := NewWithSpace(10)
+	c.Assert(util.WriteFile(fs, "foo", []byte("foobar"), 0644), IsNil)
+	c.Assert(fs.(billy.Link).Link("foo", "bar"), IsNil)
+
+	err := fs.(billy.Link).Link("/", "qux")
+	c.Assert(os.IsPermission(err), Equals, true)
+
+	c.Assert(fs.Remove("foo"), IsNil)
+	err = util.WriteFile(fs, "qux", []byte("12345"), 0644)
+	c.Assert(errors.Is(err, errNoSpace), Equals, true)
+
+	content, err := util.ReadFileString(fs, "bar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foobar")
+
+	c.Assert(fs.Remove("bar"), IsNil)
+	c.Assert(util.WriteFile(fs, "qux", []byte("12345"), 0644), IsNil)
+}
+
+func (s *MemorySuite) TestConcurrentReadWrite(c *C) {
+	const readers, writes = 4, 200
+
+	w, err := s.FS.Create("foo")
+	c.Assert(err, IsNil)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < readers; i++ {
+		r, err := s.FS.Open("foo")
+		c.Assert(err, IsNil)
+
+		wg.Add(1)
+		go func(r billy.File) {
+			defer wg.Done()
+			defer r.Close()
+
+			buf := make([]byte, 16)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				n, _ := r.ReadAt(buf, 0)
+				for _, b := range buf[:n] {
+					if b != 'a' {
+						panic(fmt.Sprintf("unexpected byte %q", b))
+					}
+				}
+			}
+		}(r)
+	}
+
+	for i := 0; i < writes; i++ {
+		_, err := w.Write([]byte("a"))
+		c.Assert(err, IsNil)
+	}
+
+	close(done)
+	wg.Wait()
+	c.Assert(w.Close(), IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(writes))
+}
+
+func (s *MemorySuite) TestCreateNode(c *C) {
+	fs := &Memory{s: newStorage()}
+
+	for name, mode := range map[string]os.FileMode{
+		"dev/null": os.ModeDevice | os.ModeCharDevice | 0666,
+		"fifo":     os.ModeNamedPipe | 0644,
+		"empty":    0644,
+		"dir":      os.ModeDir | 0700,
+	} {
+		c.Assert(fs.CreateNode(name, mode), IsNil)
+
+		fi, err := fs.Stat(name)
+		c.Assert(err, IsNil)
+		c.Assert(fi.Mode(), Equals, mode)
+		c.Assert(fi.Size(), Equals, int64(0))
+	}
+
+	entries, err := fs.ReadDir("dev")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Assert(entries[0].Mode()&os.ModeCharDevice, Not(Equals), os.FileMode(0))
+
+	err = fs.CreateNode("empty", 0644)
+	c.Assert(os.IsExist(err), Equals, true)
+
+	err = fs.CreateNode("link", os.ModeSymlink|0777)
+	c.Assert(errors.Is(err, billy.ErrNotSupported), Equals, true)
+	_, err = fs.Lstat("link")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *MemorySuite) TestReadDirErrors(c *C) {
+	c.Assert(util.WriteFile(s.FS, "foo/bar", nil, 0644), IsNil)
+
+	_, err := s.FS.ReadDir("foo/bar")
+	c.Assert(errors.Is(err, syscall.ENOTDIR), Equals, true)
+	_, ok := err.(*os.PathError)
+	c.Assert(ok, Equals, true)
+
+	_, err = s.FS.ReadDir("qux")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	entries, err := s.FS.ReadDir("foo")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+
+	entries, err = New().ReadDir("/")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+
+	c.Assert(s.FS.MkdirAll("empty", 0755), IsNil)
+	entries, err = s.FS.ReadDir("empty")
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+}
+
+func (s *MemorySuite) TestOpenDir(c *C) {
+	c.Assert(s.FS.MkdirAll("foo", 0755), IsNil)
+
+	for _, flag := range []int{os.O_RDONLY, os.O_WRONLY, os.O_RDWR, os.O_RDWR | os.O_CREATE} {
+		_, err := s.FS.OpenFile("foo", flag, 0644)
+		c.Assert(errors.Is(err, syscall.EISDIR), Equals, true, Commentf("flag %d", flag))
+		_, ok := err.(*os.PathError)
+		c.Assert(ok, Equals, true)
+	}
+}
+
+func (s *MemorySuite) TestCreateCollisions(c *C) {
+	c.Assert(s.FS.MkdirAll("foo", 0755), IsNil)
+	c.Assert(util.WriteFile(s.FS, "bar", []byte("bar"), 0644), IsNil)
+
+	_, err := s.FS.Create("foo")
+	c.Assert(errors.Is(err, syscall.EISDIR), Equals, true)
+	_, ok := err.(*os.PathError)
+	c.Assert(ok, Equals, true)
+
+	err = s.FS.MkdirAll("bar", 0755)
+	c.Assert(errors.Is(err, os.ErrExist), Equals, true)
+	_, ok = err.(*os.PathError)
+	c.Assert(ok, Equals, true)
+
+	err = s.FS.MkdirAll("bar/qux", 0755)
+	c.Assert(errors.Is(err, errNotDir), Equals, true)
+
+	_, err = s.FS.Create("bar/qux")
+	c.Assert(errors.Is(err, errNotDir), Equals, true)
+
+	content, err := util.ReadFileString(s.FS, "bar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "bar")
+
+	_, err = s.FS.Stat("bar/qux")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *MemorySuite) TestOpenFileExclusiveSymlink(c *C) {
+	c.Assert(s.FS.Symlink("missing", "link"), IsNil)
+
+	_, err := s.FS.OpenFile("link", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	c.Assert(errors.Is(err, os.ErrExist), Equals, true)
+	_, ok := err.(*os.PathError)
+	c.Assert(ok, Equals, true)
+
+	_, err = s.FS.Lstat("missing")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
@@ -0,0 +1,9 @@
+// +build !plan9
+
+package memfs
+
+import "syscall"
+
+// errNoSpace is the error returned once the space of the filesystem is
+// exhausted.
+var errNoSpace error = syscall.ENOSPC
@@ -0,0 +1,7 @@
+package memfs
+
+import "errors"
+
+// errNoSpace is the error returned once the space of the filesystem is
+// exhausted, Plan 9 has no ENOSPC.
+var errNoSpace = errors.New("no space left on device")
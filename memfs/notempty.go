@@ -0,0 +1,9 @@
+// +build !plan9
+
+package memfs
+
+import "syscall"
+
+// errNotEmpty is the error returned when renaming a directory over a non
+// empty one.
+var errNotEmpty error = syscall.ENOTEMPTY
@@ -0,0 +1,7 @@
+package memfs
+
+import "errors"
+
+// errNotEmpty is the error returned when renaming a directory over a non
+// empty one, Plan 9 has no ENOTEMPTY.
+var errNotEmpty = errors.New("directory not empty")
@@ -0,0 +1,98 @@
+package memfs
+
+import (
+	"os"
+	"sort"
+)
+
+// ChangeType is the kind of change of a path reported by ChangesSince.
+type ChangeType int
+
+const (
+	// Added is a path not present in the snapshot.
+	Added ChangeType = iota
+	// Modified is a path whose content, or mode, changed since the snapshot,
+	// or replaced by another file, eg.: with Rename.
+	Modified
+	// Removed is a path present in the snapshot not present anymore.
+	Removed
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Modified:
+		return "modified"
+	case Removed:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is a change of a path since a snapshot.
+type Change struct {
+	// Path is the path, from the root of the filesystem.
+	Path string
+	Type ChangeType
+}
+
+// Snapshot is the state of the files of a Memory filesystem at a given point,
+// to tell the changes since then with ChangesSince. It doesn't hold the
+// content of the files.
+type Snapshot struct {
+	entries map[string]snapshotEntry
+}
+
+type snapshotEntry struct {
+	id, version uint64
+	mode        os.FileMode
+}
+
+// Snapshot returns the current state of the filesystem.
+func (fs *Memory) Snapshot() *Snapshot {
+	fs.s.mu.RLock()
+	defer fs.s.mu.RUnlock()
+
+	snap := &Snapshot{entries: make(map[string]snapshotEntry, len(fs.s.files))}
+	for path, f := range fs.s.files {
+		snap.entries[path] = snapshotEntry{
+			id:      f.content.id,
+			version: f.content.Version(),
+			mode:    f.mode,
+		}
+	}
+
+	return snap
+}
+
+// ChangesSince returns the paths added, modified or removed since the given
+// snapshot, sorted by path. The directories are only reported as modified if
+// its mode changed, not when its children do.
+func (fs *Memory) ChangesSince(snap *Snapshot) []Change {
+	current := fs.Snapshot()
+
+	var changes []Change
+	for path, e := range current.entries {
+		prev, ok := snap.entries[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Type: Added})
+		case prev != e:
+			changes = append(changes, Change{Path: path, Type: Modified})
+		}
+	}
+
+	for path := range snap.entries {
+		if _, ok := current.entries[path]; !ok {
+			changes = append(changes, Change{Path: path, Type: Removed})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
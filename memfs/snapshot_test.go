@@ -0,0 +1,46 @@
+package memfs
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5/util"
+
+	. "gopkg.in/check.v1"
+)
+
+type SnapshotSuite struct{}
+
+var _ = Suite(&SnapshotSuite{})
+
+func (s *SnapshotSuite) TestChangesSince(c *C) {
+	fs := &Memory{s: newStorage()}
+	c.Assert(util.WriteFile(fs, "foo", []byte("foo"), 0644), IsNil)
+	c.Assert(util.WriteFile(fs, "bar", []byte("bar"), 0644), IsNil)
+	c.Assert(util.WriteFile(fs, "qux", []byte("qux"), 0644), IsNil)
+	c.Assert(util.WriteFile(fs, "dir/baz", []byte("baz"), 0644), IsNil)
+
+	snap := fs.Snapshot()
+	c.Assert(fs.ChangesSince(snap), HasLen, 0)
+
+	f, err := fs.OpenFile("foo", os.O_WRONLY, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("FOO"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(fs.Remove("bar"), IsNil)
+	c.Assert(fs.Rename("dir/baz", "qux"), IsNil)
+	c.Assert(util.WriteFile(fs, "new/file", nil, 0644), IsNil)
+
+	c.Assert(fs.ChangesSince(snap), DeepEquals, []Change{
+		{Path: "bar", Type: Removed},
+		{Path: filepath.Join("dir", "baz"), Type: Removed},
+		{Path: "foo", Type: Modified},
+		{Path: "new", Type: Added},
+		{Path: filepath.Join("new", "file"), Type: Added},
+		{Path: "qux", Type: Modified},
+	})
+
+	c.Assert(fs.ChangesSince(fs.Snapshot()), HasLen, 0)
+}
@@ -6,11 +6,22 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+// storage holds the files of a Memory filesystem, it's safe for concurrent
+// use: the exported methods take the lock, the unexported ones must be called
+// with it held.
 type storage struct {
 	files    map[string]*file
 	children map[string]map[string]*file
+	space    *space
+
+	mu sync.RWMutex
 }
 
 func newStorage() *storage {
@@ -21,17 +32,61 @@ func newStorage() *storage {
 }
 
 func (s *storage) Has(path string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.has(path)
+}
+
+func (s *storage) has(path string) bool {
 	path = clean(path)
 
 	_, ok := s.files[path]
 	return ok
 }
 
+// New creates an entry at path, as new, returning a copy of it, as Get.
 func (s *storage) New(path string, mode os.FileMode, flag int) (*file, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.new(path, mode, flag)
+	if f == nil {
+		return nil, err
+	}
+
+	c := *f
+	return &c, err
+}
+
+// GetOrNew returns a copy of the entry at path, as Get, creating it first, as
+// New, if it doesn't exist. The lookup and the creation are done atomically,
+// created tells if the entry was created by this call.
+func (s *storage) GetOrNew(path string, mode os.FileMode, flag int) (f *file, created bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.get(path)
+	if !ok {
+		f, err = s.new(path, mode, flag)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	c := *f
+	return &c, !ok, nil
+}
+
+// new creates an entry at path, along with its missing parent directories.
+// If path is an existing directory nothing is done, and if it's an existing
+// file an *os.PathError wrapping os.ErrExist is returned. A parent being a
+// file fails with an *os.PathError wrapping errNotDir.
+func (s *storage) new(path string, mode os.FileMode, flag int) (*file, error) {
 	path = clean(path)
-	if s.Has(path) {
-		if !s.MustGet(path).mode.IsDir() {
-			return nil, fmt.Errorf("file already exists %q", path)
+	if s.has(path) {
+		if !s.mustGet(path).mode.IsDir() {
+			return nil, &os.PathError{Op: "mkdir", Path: path, Err: os.ErrExist}
 		}
 
 		return nil, nil
@@ -41,13 +96,86 @@ func (s *storage) New(path string, mode os.FileMode, flag int) (*file, error) {
 
 	f := &file{
 		name:    name,
-		content: &content{name: name},
+		content: newContent(name, s.space),
 		mode:    mode,
 		flag:    flag,
+		btime:   time.Now(),
 	}
 
 	s.files[path] = f
-	s.createParent(path, mode, f)
+	if err := s.createParent(path, mode, f); err != nil {
+		delete(s.files, path)
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Link creates a new entry at to for the content of the file at from, the
+// check of the existence of to and the creation of the entry are done
+// atomically. Directories can't be linked.
+func (s *storage) Link(from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, to = clean(from), clean(to)
+	f, ok := s.get(from)
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	if f.mode.IsDir() {
+		return os.ErrPermission
+	}
+
+	if s.has(to) {
+		return os.ErrExist
+	}
+
+	l, err := s.new(to, f.mode, f.flag)
+	if err != nil {
+		return err
+	}
+
+	l.content = f.content
+	atomic.AddInt32(&l.content.links, 1)
+	return nil
+}
+
+// NewNode creates an empty entry at path with the given mode, the check of
+// the existence of path and the creation of the entry are done atomically.
+func (s *storage) NewNode(path string, mode os.FileMode) (*file, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.has(path) {
+		return nil, os.ErrExist
+	}
+
+	return s.new(path, mode, 0)
+}
+
+// NewSymlink creates a symlink at path pointing to target, the check of the
+// existence of path and the creation of the link are done atomically.
+func (s *storage) NewSymlink(path, target string) (*file, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path = clean(path)
+	if s.has(path) {
+		return nil, os.ErrExist
+	}
+
+	f, err := s.new(path, 0777|os.ModeSymlink, os.O_WRONLY)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := f.content.WriteAt([]byte(target), 0); err != nil {
+		s.remove(path)
+		return nil, err
+	}
+
 	return f, nil
 }
 
@@ -58,7 +186,11 @@ func (s *storage) createParent(path string, mode os.FileMode, f *file) error {
 		return nil
 	}
 
-	if _, err := s.New(base, mode.Perm()|os.ModeDir, 0); err != nil {
+	if p, ok := s.get(base); ok && !p.mode.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: base, Err: errNotDir}
+	}
+
+	if _, err := s.new(base, mode.Perm()|os.ModeDir, 0); err != nil {
 		return err
 	}
 
@@ -70,19 +202,24 @@ func (s *storage) createParent(path string, mode os.FileMode, f *file) error {
 	return nil
 }
 
+// Children returns copies of the entries of the directory at path, as Get.
 func (s *storage) Children(path string) []*file {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	path = clean(path)
 
 	l := make([]*file, 0)
 	for _, f := range s.children[path] {
-		l = append(l, f)
+		c := *f
+		l = append(l, &c)
 	}
 
 	return l
 }
 
-func (s *storage) MustGet(path string) *file {
-	f, ok := s.Get(path)
+func (s *storage) mustGet(path string) *file {
+	f, ok := s.get(path)
 	if !ok {
 		panic(fmt.Errorf("couldn't find %q", path))
 	}
@@ -90,9 +227,25 @@ func (s *storage) MustGet(path string) *file {
 	return f
 }
 
+// Get returns a copy of the entry at path, taken with the lock held, so its
+// name and mode can be read while they are changed, eg.: by Chmod or Rename.
+// The content is shared with the entry.
 func (s *storage) Get(path string) (*file, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.get(path)
+	if !ok {
+		return nil, false
+	}
+
+	c := *f
+	return &c, true
+}
+
+func (s *storage) get(path string) (*file, bool) {
 	path = clean(path)
-	if !s.Has(path) {
+	if !s.has(path) {
 		return nil, false
 	}
 
@@ -100,25 +253,61 @@ func (s *storage) Get(path string) (*file, bool) {
 	return file, ok
 }
 
+// Chmod changes the bits of the mode of the entry at path given by mask to the
+// ones of mode.
+func (s *storage) Chmod(path string, mode, mask os.FileMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.get(path)
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	f.mode = f.mode&^mask | mode&mask
+	return nil
+}
+
 func (s *storage) Rename(from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rename(from, to)
+}
+
+func (s *storage) rename(from, to string) error {
 	from = clean(from)
 	to = clean(to)
 
-	if !s.Has(from) {
-		return os.ErrNotExist
+	if !s.has(from) {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrNotExist}
 	}
 
-	move := [][2]string{{from, to}}
+	// as os.Rename, renaming a file or a directory onto itself does nothing.
+	if to == from {
+		return nil
+	}
 
-	for pathFrom := range s.files {
-		if pathFrom == from || !filepath.HasPrefix(pathFrom, from) {
-			continue
-		}
+	// moving a directory into itself would drop it from the storage, along
+	// with its content, as os.Rename it fails with EINVAL.
+	if isInside(to, from) {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: syscall.EINVAL}
+	}
 
-		rel, _ := filepath.Rel(from, pathFrom)
-		pathTo := filepath.Join(to, rel)
+	// as os.Rename, renaming a hard link onto another link of the same file
+	// does nothing, both names are kept.
+	if dst, ok := s.files[to]; ok && dst.content == s.files[from].content {
+		return nil
+	}
 
-		move = append(move, [2]string{pathFrom, pathTo})
+	if err := s.replaceable(from, to); err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+
+	move := [][2]string{{from, to}}
+	for _, pathFrom := range s.descendants(from) {
+		rel, _ := filepath.Rel(from, pathFrom)
+		move = append(move, [2]string{pathFrom, filepath.Join(to, rel)})
 	}
 
 	for _, ops := range move {
@@ -133,10 +322,92 @@ func (s *storage) Rename(from, to string) error {
 	return nil
 }
 
+// replaceable returns nil if the entry at to, if any, can be replaced by the
+// one at from, as in POSIX rename: a file replaces a file, and a directory
+// replaces an empty directory.
+func (s *storage) replaceable(from, to string) error {
+	dst, ok := s.files[to]
+	if !ok {
+		return nil
+	}
+
+	src := s.files[from]
+	switch {
+	case src.mode.IsDir() && !dst.mode.IsDir():
+		return syscall.ENOTDIR
+	case !src.mode.IsDir() && dst.mode.IsDir():
+		return syscall.EISDIR
+	case dst.mode.IsDir() && len(s.children[to]) != 0:
+		return errNotEmpty
+	}
+
+	return nil
+}
+
+// Exchange swaps the files, or directories, at the paths a and b atomically.
+func (s *storage) Exchange(a, b string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, b = clean(a), clean(b)
+	if !s.has(a) || !s.has(b) {
+		return &os.LinkError{Op: "exchange", Old: a, New: b, Err: os.ErrNotExist}
+	}
+
+	if a == b {
+		return nil
+	}
+
+	if isInside(a, b) || isInside(b, a) {
+		return &os.LinkError{Op: "exchange", Old: a, New: b, Err: errExchangeSubtree}
+	}
+
+	tmp := a + ".exchange"
+	for i := 0; s.has(tmp); i++ {
+		tmp = fmt.Sprintf("%s.exchange%d", a, i)
+	}
+
+	for _, r := range [][2]string{{a, tmp}, {b, a}, {tmp, b}} {
+		if err := s.rename(r[0], r[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var errExchangeSubtree = errors.New("can't exchange a directory with its own content")
+
+// descendants returns the paths of the files and directories inside of the
+// directory at path, every directory before its content. They are found by
+// walking the children, so it scales with the size of the subtree, not of the
+// whole storage.
+func (s *storage) descendants(path string) []string {
+	var paths []string
+	for name := range s.children[path] {
+		p := filepath.Join(path, name)
+		paths = append(paths, p)
+		paths = append(paths, s.descendants(p)...)
+	}
+
+	return paths
+}
+
+// isInside returns true if path is a descendant of dir.
+func isInside(path, dir string) bool {
+	return strings.HasPrefix(path, dir+string(separator))
+}
+
 func (s *storage) move(from, to string) error {
+	if old, ok := s.files[to]; ok && old != s.files[from] {
+		old.content.unlink()
+	}
+
 	s.files[to] = s.files[from]
 	s.files[to].name = filepath.Base(to)
-	s.children[to] = s.children[from]
+	if children, ok := s.children[from]; ok {
+		s.children[to] = children
+	}
 
 	defer func() {
 		delete(s.children, from)
@@ -148,9 +419,16 @@ func (s *storage) move(from, to string) error {
 }
 
 func (s *storage) Remove(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.remove(path)
+}
+
+func (s *storage) remove(path string) error {
 	path = clean(path)
 
-	f, has := s.Get(path)
+	f, has := s.get(path)
 	if !has {
 		return os.ErrNotExist
 	}
@@ -159,6 +437,8 @@ func (s *storage) Remove(path string) error {
 		return fmt.Errorf("dir: %s contains files", path)
 	}
 
+	f.content.unlink()
+
 	base, file := filepath.Split(path)
 	base = filepath.Clean(base)
 
@@ -167,16 +447,135 @@ func (s *storage) Remove(path string) error {
 	return nil
 }
 
+// RemoveAll removes path and, if it's a directory, everything it contains.
+// It returns nil if path doesn't exist.
+func (s *storage) RemoveAll(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path = clean(path)
+	if !s.has(path) {
+		return nil
+	}
+
+	for _, p := range append(s.descendants(path), path) {
+		if f, ok := s.files[p]; ok {
+			f.content.unlink()
+		}
+
+		delete(s.files, p)
+		delete(s.children, p)
+	}
+
+	base, file := filepath.Split(path)
+	delete(s.children[filepath.Clean(base)], file)
+	return nil
+}
+
 func clean(path string) string {
 	return filepath.Clean(filepath.FromSlash(path))
 }
 
+// content is the content of a file, shared by all its handles. It's safe for
+// concurrent use: the reads are done in parallel, and the changes one at a
+// time, guarded by mu.
 type content struct {
 	name  string
+	space *space
+
+	mu    sync.RWMutex
 	bytes []byte
+
+	// id identifies the content, and version is increased on every change,
+	// to tell the changes since a Snapshot.
+	id      uint64
+	version uint64
+	// modTime is the time of the last change, it's kept with the content
+	// since it's shared by all the handles of the same file.
+	modTime time.Time
+	// links is the number of entries of the storage holding the content,
+	// it's changed with the lock of the storage held, and accessed
+	// atomically, to be read by Stat.
+	links int32
+}
+
+var lastContentID uint64
+
+func newContent(name string, s *space) *content {
+	return &content{
+		name:    name,
+		space:   s,
+		id:      atomic.AddUint64(&lastContentID, 1),
+		modTime: time.Now(),
+		links:   1,
+	}
+}
+
+// space accounts the bytes used by the content of the files, up to a total.
+type space struct {
+	m     sync.Mutex
+	total int64
+	used  int64
 }
 
+func (s *space) add(n int64) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if n > 0 && s.used+n > s.total {
+		return errNoSpace
+	}
+
+	s.used += n
+	return nil
+}
+
+// resize accounts the change of the length of the content to size, failing
+// if there is not enough space left. It must be called before changing the
+// length of the content, with mu held.
+func (c *content) resize(size int) error {
+	if c.space == nil {
+		return nil
+	}
+
+	return c.space.add(int64(size - len(c.bytes)))
+}
+
+// release frees the space used by the content, once removed from the
+// filesystem. The content isn't accounted anymore, even if it's still
+// written through a file opened before it was removed.
+func (c *content) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.resize(0)
+	c.space = nil
+}
+
+// Links returns the number of entries of the storage holding the content.
+func (c *content) Links() int {
+	return int(atomic.LoadInt32(&c.links))
+}
+
+// unlink accounts the removal of an entry holding the content, releasing it
+// once there are none left.
+func (c *content) unlink() {
+	if atomic.AddInt32(&c.links, -1) <= 0 {
+		c.release()
+	}
+}
+
+// WriteAt writes the whole of p at off, or nothing if there isn't enough
+// space left, so there are no partial writes.
 func (c *content) WriteAt(p []byte, off int64) (int, error) {
+	n, err := c.WriteBuffersAt([][]byte{p}, off)
+	return int(n), err
+}
+
+// WriteBuffersAt writes the buffers one after the other, starting at off, as
+// a single write: the space is accounted once for all of them, and no other
+// write can happen in between.
+func (c *content) WriteBuffersAt(bufs [][]byte, off int64) (int64, error) {
 	if off < 0 {
 		return 0, &os.PathError{
 			Op:   "writeat",
@@ -185,19 +584,37 @@ func (c *content) WriteAt(p []byte, off int64) (int, error) {
 		}
 	}
 
+	var n int64
+	for _, b := range bufs {
+		n += int64(len(b))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	prev := len(c.bytes)
+	if end := int(off + n); end > prev {
+		if err := c.resize(end); err != nil {
+			return 0, &os.PathError{Op: "write", Path: c.name, Err: err}
+		}
 
-	diff := int(off) - prev
-	if diff > 0 {
-		c.bytes = append(c.bytes, make([]byte, diff)...)
+		c.bytes = append(c.bytes, make([]byte, end-prev)...)
 	}
 
-	c.bytes = append(c.bytes[:off], p...)
-	if len(c.bytes) < prev {
-		c.bytes = c.bytes[:prev]
+	pos := int(off)
+	for _, b := range bufs {
+		pos += copy(c.bytes[pos:], b)
 	}
 
-	return len(p), nil
+	c.changed()
+
+	return n, nil
+}
+
+// changed records a change of the content, it must be called with mu held.
+func (c *content) changed() {
+	c.version++
+	c.modTime = time.Now()
 }
 
 func (c *content) ReadAt(b []byte, off int64) (n int, err error) {
@@ -209,6 +626,9 @@ func (c *content) ReadAt(b []byte, off int64) (n int, err error) {
 		}
 	}
 
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	size := int64(len(c.bytes))
 	if off >= size {
 		return 0, io.EOF
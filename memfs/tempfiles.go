@@ -0,0 +1,68 @@
+package memfs
+
+import (
+	"os"
+	"sort"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// TempFiles implements the billy.TempFileTracker interface, it returns the
+// names of the files created through TempFile and TempFileSuffix that still
+// exist, sorted. A file is not tracked anymore once removed or renamed.
+func (fs *Memory) TempFiles() []string {
+	fs.tempMu.Lock()
+	defer fs.tempMu.Unlock()
+
+	var names []string
+	for name := range fs.temps {
+		if !fs.s.Has(name) {
+			delete(fs.temps, name)
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// CleanupTempFiles implements the billy.TempFileTracker interface, it removes
+// the files created through TempFile and TempFileSuffix that still exist,
+// returning the first error found.
+func (fs *Memory) CleanupTempFiles() error {
+	var firstErr error
+	for _, name := range fs.TempFiles() {
+		err := fs.Remove(name)
+		if err != nil && !os.IsNotExist(err) {
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		fs.tempMu.Lock()
+		delete(fs.temps, name)
+		fs.tempMu.Unlock()
+	}
+
+	return firstErr
+}
+
+func (fs *Memory) trackTempFile(f billy.File, err error) (billy.File, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	fs.tempMu.Lock()
+	defer fs.tempMu.Unlock()
+
+	if fs.temps == nil {
+		fs.temps = make(map[string]struct{})
+	}
+
+	fs.temps[clean(f.Name())] = struct{}{}
+	return f, nil
+}
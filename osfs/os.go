@@ -2,10 +2,12 @@
 package osfs // import "github.com/go-git/go-billy/v5/osfs"
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/helper/chroot"
@@ -14,10 +16,18 @@ import (
 const (
 	defaultDirectoryMode = 0755
 	defaultCreateMode    = 0666
+
+	// tempFileFlag are the flags used by ioutil.TempFile.
+	tempFileFlag = os.O_RDWR | os.O_CREATE | os.O_EXCL
 )
 
 // OS is a filesystem based on the os filesystem.
-type OS struct{}
+type OS struct {
+	// temps holds the names of the files created through TempFile, to
+	// remove the leftovers with CleanupTempFiles.
+	temps  map[string]struct{}
+	tempMu sync.Mutex
+}
 
 // New returns a new OS filesystem.
 func New(baseDir string) billy.Filesystem {
@@ -39,7 +49,19 @@ func (fs *OS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File,
 	if err != nil {
 		return nil, err
 	}
-	return &file{File: f}, err
+	return &file{File: f, flag: flag}, err
+}
+
+// OpenFileHint implements the billy.OpenFileHint interface, the hint is given
+// to the kernel with posix_fadvise on Linux, and ignored elsewhere.
+func (fs *OS) OpenFileHint(filename string, flag int, perm os.FileMode, hint billy.AccessHint) (billy.File, error) {
+	f, err := fs.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	fadvise(f.(*file).File, hint)
+	return f, nil
 }
 
 func (fs *OS) createDir(fullpath string) error {
@@ -75,6 +97,53 @@ func (fs *OS) Rename(from, to string) error {
 	return rename(from, to)
 }
 
+// Exchange implements the billy.Exchange interface. On Linux the paths are
+// swapped atomically with renameat2, elsewhere, or if the filesystem doesn't
+// support it, they are swapped renaming them through a temporary name, which
+// is not atomic.
+func (fs *OS) Exchange(a, b string) error {
+	err := exchange(a, b)
+	if err != billy.ErrNotSupported {
+		return err
+	}
+
+	return exchangeRename(a, b)
+}
+
+func exchangeRename(a, b string) error {
+	for _, path := range []string{a, b} {
+		if _, err := os.Lstat(path); err != nil {
+			return err
+		}
+	}
+
+	tmp := a + ".exchange"
+	for i := 0; ; i++ {
+		if _, err := os.Lstat(tmp); os.IsNotExist(err) {
+			break
+		}
+
+		tmp = fmt.Sprintf("%s.exchange%d", a, i)
+	}
+
+	if err := rename(a, tmp); err != nil {
+		return err
+	}
+
+	if err := rename(b, a); err != nil {
+		_ = rename(tmp, a)
+		return err
+	}
+
+	if err := rename(tmp, b); err != nil {
+		_ = rename(a, b)
+		_ = rename(tmp, a)
+		return err
+	}
+
+	return nil
+}
+
 func (fs *OS) MkdirAll(path string, perm os.FileMode) error {
 	return os.MkdirAll(path, defaultDirectoryMode)
 }
@@ -87,6 +156,14 @@ func (fs *OS) Stat(filename string) (os.FileInfo, error) {
 	return os.Stat(filename)
 }
 
+// BirthTime implements the billy.BirthTime interface, the birth time is read
+// only when asked, since it takes an additional call on some systems. It
+// returns billy.ErrNotSupported if the system or the underlying filesystem
+// doesn't keep it.
+func (fs *OS) BirthTime(filename string) (time.Time, error) {
+	return birthTime(filename)
+}
+
 func (fs *OS) Remove(filename string) error {
 	return os.Remove(filename)
 }
@@ -100,7 +177,46 @@ func (fs *OS) TempFile(dir, prefix string) (billy.File, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &file{File: f}, nil
+
+	fs.trackTempFile(f.Name())
+	return &file{File: f, flag: tempFileFlag}, nil
+}
+
+// TempFileSuffix implements the billy.TempFileSuffix interface.
+func (fs *OS) TempFileSuffix(dir, prefix, suffix string) (billy.File, error) {
+	if err := fs.createDir(dir + string(os.PathSeparator)); err != nil {
+		return nil, err
+	}
+
+	f, err := ioutil.TempFile(dir, prefix+"*"+suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.trackTempFile(f.Name())
+	return &file{File: f, flag: tempFileFlag}, nil
+}
+
+// AnonTempFile implements the billy.AnonTempFile interface. The file is created
+// at the default directory for temporary files, with O_TMPFILE where available,
+// otherwise the file is removed right after being created.
+func (fs *OS) AnonTempFile() (billy.File, error) {
+	dir := os.TempDir()
+	if f, err := openTmpFile(dir); err == nil {
+		return &file{File: f, flag: tempFileFlag}, nil
+	}
+
+	f, err := ioutil.TempFile(dir, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(f.Name()); err != nil {
+		// some systems, like Windows, can't remove an open file.
+		return &unlinkOnCloseFile{file: &file{File: f, flag: tempFileFlag}}, nil
+	}
+
+	return &file{File: f, flag: tempFileFlag}, nil
 }
 
 func (fs *OS) Join(elem ...string) string {
@@ -111,6 +227,25 @@ func (fs *OS) RemoveAll(path string) error {
 	return os.RemoveAll(filepath.Clean(path))
 }
 
+// Chmod implements the billy.Chmod interface.
+func (fs *OS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(filepath.Clean(name), mode)
+}
+
+// Chtimes implements the billy.Chtimes interface.
+func (fs *OS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(filepath.Clean(name), atime, mtime)
+}
+
+// Link implements the billy.Link interface.
+func (fs *OS) Link(oldname, newname string) error {
+	if err := fs.createDir(newname); err != nil {
+		return err
+	}
+
+	return os.Link(filepath.Clean(oldname), filepath.Clean(newname))
+}
+
 func (fs *OS) Lstat(filename string) (os.FileInfo, error) {
 	return os.Lstat(filepath.Clean(filename))
 }
@@ -129,11 +264,56 @@ func (fs *OS) Readlink(link string) (string, error) {
 
 // Capabilities implements the Capable interface.
 func (fs *OS) Capabilities() billy.Capability {
-	return billy.DefaultCapabilities
+	return billy.DefaultCapabilities |
+		billy.SymlinkCapability |
+		billy.ChrootCapability |
+		billy.TempFileCapability
 }
 
 // file is a wrapper for an os.File which adds support for file locking.
 type file struct {
 	*os.File
-	m sync.Mutex
+	m    sync.Mutex
+	flag int
+}
+
+// Flags returns the flags used to open the file.
+func (f *file) Flags() int {
+	return f.flag
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if isWriteOnly(f.flag) {
+		return 0, &os.PathError{Op: "read", Path: f.Name(), Err: billy.ErrWriteOnly}
+	}
+
+	return f.File.Read(p)
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if isWriteOnly(f.flag) {
+		return 0, &os.PathError{Op: "read", Path: f.Name(), Err: billy.ErrWriteOnly}
+	}
+
+	return f.File.ReadAt(p, off)
+}
+
+// isWriteOnly returns true if the file was opened with os.O_WRONLY, the
+// reads would fail with a system specific error, EBADF on most systems.
+func isWriteOnly(flag int) bool {
+	return flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) == os.O_WRONLY
+}
+
+// unlinkOnCloseFile is a file that is removed once is closed.
+type unlinkOnCloseFile struct {
+	*file
+}
+
+func (f *unlinkOnCloseFile) Close() error {
+	err := f.file.Close()
+	if err1 := os.Remove(f.Name()); err == nil {
+		err = err1
+	}
+
+	return err
 }
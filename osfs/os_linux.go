@@ -0,0 +1,178 @@
+// +build linux
+
+package osfs
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-git/go-billy/v5"
+	"golang.org/x/sys/unix"
+)
+
+// openTmpFile opens an unnamed temporary file at dir using O_TMPFILE.
+func openTmpFile(dir string) (*os.File, error) {
+	return os.OpenFile(dir, os.O_RDWR|unix.O_TMPFILE, 0600)
+}
+
+// CopyRangeFrom copies n bytes from src, starting at srcOff, to the file at
+// dstOff using copy_file_range, without changing the position of any of the
+// files. If src is not a file from this package or the syscall can't be used,
+// billy.ErrNotSupported is returned and nothing is copied.
+func (f *file) CopyRangeFrom(dstOff int64, src billy.File, srcOff, n int64) (int64, error) {
+	sf, ok := src.(*file)
+	if !ok {
+		return 0, billy.ErrNotSupported
+	}
+
+	var written int64
+	for written < n {
+		c, err := unix.CopyFileRange(
+			int(sf.Fd()), &srcOff,
+			int(f.Fd()), &dstOff,
+			int(n-written), 0,
+		)
+
+		if err != nil {
+			if written == 0 {
+				return 0, billy.ErrNotSupported
+			}
+
+			return written, err
+		}
+
+		if c == 0 {
+			return written, io.EOF
+		}
+
+		written += int64(c)
+	}
+
+	return written, nil
+}
+
+// fadvise gives the hint to the kernel with posix_fadvise, for the whole
+// file. The errors are ignored, the hint is only advisory.
+func fadvise(f *os.File, hint billy.AccessHint) {
+	var advice int
+	switch hint {
+	case billy.SequentialHint:
+		advice = unix.FADV_SEQUENTIAL
+	case billy.RandomHint:
+		advice = unix.FADV_RANDOM
+	case billy.WillNeedHint:
+		advice = unix.FADV_WILLNEED
+	default:
+		return
+	}
+
+	_ = unix.Fadvise(int(f.Fd()), 0, 0, advice)
+}
+
+// maxIovecs is the maximum number of buffers accepted by writev, IOV_MAX.
+const maxIovecs = 1024
+
+// WriteBuffers implements the billy.BuffersWriter interface using writev.
+func (f *file) WriteBuffers(bufs [][]byte) (int64, error) {
+	// bufs is copied, since consume modifies it.
+	bufs = append([][]byte(nil), bufs...)
+
+	var written int64
+	iovs := make([]syscall.Iovec, 0, maxIovecs)
+	for {
+		for len(bufs) > 0 && len(bufs[0]) == 0 {
+			bufs = bufs[1:]
+		}
+
+		if len(bufs) == 0 {
+			return written, nil
+		}
+
+		iovs = iovs[:0]
+		for _, b := range bufs {
+			if len(iovs) == maxIovecs {
+				break
+			}
+
+			if len(b) == 0 {
+				continue
+			}
+
+			iov := syscall.Iovec{Base: &b[0]}
+			iov.SetLen(len(b))
+			iovs = append(iovs, iov)
+		}
+
+		n, _, errno := syscall.Syscall(
+			syscall.SYS_WRITEV, f.Fd(),
+			uintptr(unsafe.Pointer(&iovs[0])), uintptr(len(iovs)),
+		)
+
+		if errno == syscall.EINTR {
+			continue
+		}
+
+		if errno != 0 {
+			return written, &os.PathError{Op: "writev", Path: f.Name(), Err: errno}
+		}
+
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+
+		written += int64(n)
+		bufs = consume(bufs, int64(n))
+	}
+}
+
+// consume removes the first n bytes from bufs.
+func consume(bufs [][]byte, n int64) [][]byte {
+	for len(bufs) > 0 && n > 0 {
+		if int64(len(bufs[0])) > n {
+			bufs[0] = bufs[0][n:]
+			return bufs
+		}
+
+		n -= int64(len(bufs[0]))
+		bufs = bufs[1:]
+	}
+
+	return bufs
+}
+
+// birthTime returns the birth time of the file at path with statx, since it
+// isn't part of the result of stat.
+func birthTime(path string) (time.Time, error) {
+	var st unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &st)
+	if err != nil {
+		if err == unix.ENOSYS {
+			return time.Time{}, billy.ErrNotSupported
+		}
+
+		return time.Time{}, &os.PathError{Op: "statx", Path: path, Err: err}
+	}
+
+	if st.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, billy.ErrNotSupported
+	}
+
+	return time.Unix(st.Btime.Sec, int64(st.Btime.Nsec)), nil
+}
+
+// exchange swaps a and b with renameat2, it returns billy.ErrNotSupported if
+// the kernel or the filesystem doesn't support RENAME_EXCHANGE.
+func exchange(a, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	switch err {
+	case nil:
+		return nil
+	case unix.ENOSYS, unix.EINVAL:
+		return billy.ErrNotSupported
+	default:
+		return &os.LinkError{Op: "exchange", Old: a, New: b, Err: err}
+	}
+}
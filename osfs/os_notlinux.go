@@ -0,0 +1,40 @@
+// +build !linux
+
+package osfs
+
+import (
+	"os"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// openTmpFile is only supported on Linux, with O_TMPFILE.
+func openTmpFile(dir string) (*os.File, error) {
+	return nil, billy.ErrNotSupported
+}
+
+// birthTime returns the birth time of the file at path, from the value
+// returned by the Sys method of its FileInfo, on the systems supporting it.
+func birthTime(path string) (time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	btime, ok := util.BirthTime(fi)
+	if !ok {
+		return time.Time{}, billy.ErrNotSupported
+	}
+
+	return btime, nil
+}
+
+// fadvise is a no-op, the hints are only given to the kernel on Linux.
+func fadvise(f *os.File, hint billy.AccessHint) {}
+
+// exchange is only supported on Linux, with renameat2.
+func exchange(a, b string) error {
+	return billy.ErrNotSupported
+}
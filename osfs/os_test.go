@@ -1,10 +1,12 @@
 package osfs
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"syscall"
 	"testing"
 
 	"github.com/go-git/go-billy/v5"
@@ -48,6 +50,23 @@ func (s *OSSuite) TestOpenDoesNotCreateDir(c *C) {
 	c.Assert(os.IsNotExist(err), Equals, true)
 }
 
+func (s *OSSuite) TestOpenDirForWrite(c *C) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		c.Skip("EISDIR not returned on " + runtime.GOOS)
+	}
+
+	c.Assert(s.FS.MkdirAll("foo", 0755), IsNil)
+
+	for _, flag := range []int{os.O_WRONLY, os.O_RDWR} {
+		_, err := s.FS.OpenFile("foo", flag, 0)
+		c.Assert(errors.Is(err, syscall.EISDIR), Equals, true, Commentf("flag %d", flag))
+	}
+
+	f, err := s.FS.OpenFile("foo", os.O_RDONLY, 0)
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+}
+
 func (s *OSSuite) TestCapabilities(c *C) {
 	_, ok := s.FS.(billy.Capable)
 	c.Assert(ok, Equals, true)
@@ -55,3 +74,27 @@ func (s *OSSuite) TestCapabilities(c *C) {
 	caps := billy.Capabilities(s.FS)
 	c.Assert(caps, Equals, billy.AllCapabilities)
 }
+
+func (s *OSSuite) TestExchangeRename(c *C) {
+	foo := filepath.Join(s.path, "foo")
+	bar := filepath.Join(s.path, "bar")
+	c.Assert(ioutil.WriteFile(foo, []byte("foo"), 0644), IsNil)
+	c.Assert(os.Mkdir(bar, 0755), IsNil)
+
+	c.Assert(exchangeRename(foo, bar), IsNil)
+
+	fi, err := os.Stat(foo)
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+
+	content, err := ioutil.ReadFile(bar)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "foo")
+
+	err = exchangeRename(foo, filepath.Join(s.path, "missing"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	fis, err := ioutil.ReadDir(s.path)
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 2)
+}
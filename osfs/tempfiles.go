@@ -0,0 +1,62 @@
+package osfs
+
+import (
+	"os"
+	"sort"
+)
+
+// TempFiles implements the billy.TempFileTracker interface, it returns the
+// names of the files created through TempFile and TempFileSuffix that still
+// exist, sorted. Only the files created through this filesystem are tracked,
+// not any other file at the temporary directories.
+func (fs *OS) TempFiles() []string {
+	fs.tempMu.Lock()
+	defer fs.tempMu.Unlock()
+
+	var names []string
+	for name := range fs.temps {
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			delete(fs.temps, name)
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// CleanupTempFiles implements the billy.TempFileTracker interface, it removes
+// the files created through TempFile and TempFileSuffix that still exist,
+// returning the first error found.
+func (fs *OS) CleanupTempFiles() error {
+	var firstErr error
+	for _, name := range fs.TempFiles() {
+		err := os.Remove(name)
+		if err != nil && !os.IsNotExist(err) {
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		fs.tempMu.Lock()
+		delete(fs.temps, name)
+		fs.tempMu.Unlock()
+	}
+
+	return firstErr
+}
+
+func (fs *OS) trackTempFile(name string) {
+	fs.tempMu.Lock()
+	defer fs.tempMu.Unlock()
+
+	if fs.temps == nil {
+		fs.temps = make(map[string]struct{})
+	}
+
+	fs.temps[name] = struct{}{}
+}
@@ -2,11 +2,13 @@ package test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "gopkg.in/check.v1"
 	. "github.com/go-git/go-billy/v5"
@@ -280,6 +282,24 @@ func (s *BasicSuite) TestFileNonRead(c *C) {
 	c.Assert(f.Close(), IsNil)
 }
 
+func (s *BasicSuite) TestFileReadWriteOnly(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.OpenFile("foo", os.O_WRONLY, 0)
+	c.Assert(err, IsNil)
+
+	_, err = f.Read(make([]byte, 3))
+	c.Assert(errors.Is(err, ErrWriteOnly), Equals, true)
+	c.Assert(os.IsPermission(err), Equals, true)
+
+	_, err = f.ReadAt(make([]byte, 3), 0)
+	c.Assert(errors.Is(err, ErrWriteOnly), Equals, true)
+	c.Assert(os.IsPermission(err), Equals, true)
+
+	c.Assert(f.Close(), IsNil)
+}
+
 func (s *BasicSuite) TestFileSeekstart(c *C) {
 	s.testFileSeek(c, 10, io.SeekStart)
 }
@@ -315,6 +335,26 @@ func (s *BasicSuite) testFileSeek(c *C, offset int64, whence int) {
 	c.Assert(f.Close(), IsNil)
 }
 
+func (s *BasicSuite) TestFileSeekInvalidWhence(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("0123456789"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.Open("foo")
+	c.Assert(err, IsNil)
+
+	p, err := f.Seek(5, io.SeekStart)
+	c.Assert(err, IsNil)
+	c.Assert(p, Equals, int64(5))
+
+	_, err = f.Seek(1, 99)
+	c.Assert(err, NotNil)
+
+	p, err = f.Seek(0, io.SeekCurrent)
+	c.Assert(err, IsNil)
+	c.Assert(p, Equals, int64(5))
+	c.Assert(f.Close(), IsNil)
+}
+
 func (s *BasicSuite) TestSeekToEndAndWrite(c *C) {
 	defaultMode := os.FileMode(0666)
 
@@ -563,6 +603,31 @@ func (s *BasicSuite) TestWriteFile(c *C) {
 	c.Assert(f.Close(), IsNil)
 }
 
+func (s *BasicSuite) TestOpenFileExclusive(c *C) {
+	f, err := s.FS.OpenFile("foo", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("foo"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	_, err = s.FS.OpenFile("foo", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	c.Assert(os.IsExist(err), Equals, true)
+
+	s.assertContent(c, "foo", "foo")
+}
+
+func (s *BasicSuite) TestSync(c *C) {
+	f, err := s.FS.Create("foo")
+	c.Assert(err, IsNil)
+
+	_, err = f.Write([]byte("foo"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Sync(), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	s.assertContent(c, "foo", "foo")
+}
+
 func (s *BasicSuite) TestTruncate(c *C) {
 	f, err := s.FS.Create("foo")
 	c.Assert(err, IsNil)
@@ -581,3 +646,263 @@ func (s *BasicSuite) TestTruncate(c *C) {
 
 	c.Assert(f.Close(), IsNil)
 }
+
+func (s *BasicSuite) TestTruncateGrow(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(6), IsNil)
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	c.Assert(err, IsNil)
+	c.Assert(pos, Equals, int64(0))
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "foo\x00\x00\x00")
+	c.Assert(f.Close(), IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(6))
+}
+
+func (s *BasicSuite) TestTruncateShrink(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("0123456789"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+
+	_, err = f.Seek(2, io.SeekStart)
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(4), IsNil)
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	c.Assert(err, IsNil)
+	c.Assert(pos, Equals, int64(2))
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "23")
+
+	c.Assert(f.Truncate(-1), NotNil)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *BasicSuite) TestTruncateBelowPosition(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("0123456789"), 0644)
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+
+	_, err = f.Seek(8, io.SeekStart)
+	c.Assert(err, IsNil)
+	c.Assert(f.Truncate(4), IsNil)
+
+	// The position is kept past the end of the file, as in os.File.
+	pos, err := f.Seek(0, io.SeekCurrent)
+	c.Assert(err, IsNil)
+	c.Assert(pos, Equals, int64(8))
+
+	n, err := f.Read(make([]byte, 2))
+	c.Assert(n, Equals, 0)
+	c.Assert(err, Equals, io.EOF)
+
+	_, err = f.Write([]byte("89"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	content, err := util.ReadFileString(s.FS, "foo")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "0123\x00\x00\x00\x0089")
+}
+
+func (s *BasicSuite) TestTruncateSharedAcrossHandles(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("0123456789"), 0644)
+	c.Assert(err, IsNil)
+
+	a, err := s.FS.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+
+	b, err := s.FS.OpenFile("foo", os.O_RDWR, 0)
+	c.Assert(err, IsNil)
+
+	_, err = b.Seek(8, io.SeekStart)
+	c.Assert(err, IsNil)
+
+	c.Assert(a.Truncate(4), IsNil)
+
+	buf := make([]byte, 10)
+	n, err := b.ReadAt(buf, 0)
+	c.Assert(err, Equals, io.EOF)
+	c.Assert(string(buf[:n]), Equals, "0123")
+
+	// b still writes at its old position, the gap is filled with zeros.
+	_, err = b.Write([]byte("89"))
+	c.Assert(err, IsNil)
+
+	n, err = a.ReadAt(buf, 0)
+	c.Assert(err, IsNil)
+	c.Assert(string(buf[:n]), Equals, "0123\x00\x00\x00\x0089")
+
+	c.Assert(a.Close(), IsNil)
+	c.Assert(b.Close(), IsNil)
+}
+
+func (s *BasicSuite) TestExchange(c *C) {
+	e, ok := s.FS.(Exchange)
+	if !ok {
+		c.Skip("Exchange not supported")
+	}
+
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.FS, "dir/bar", []byte("bar"), 0644)
+	c.Assert(err, IsNil)
+	err = util.WriteFile(s.FS, "dir/qux", []byte("qux"), 0644)
+	c.Assert(err, IsNil)
+
+	err = e.Exchange("foo", "dir/bar")
+	if err == ErrNotSupported {
+		c.Skip("Exchange not supported")
+	}
+
+	c.Assert(err, IsNil)
+	s.assertContent(c, "foo", "bar")
+	s.assertContent(c, "dir/bar", "foo")
+
+	c.Assert(e.Exchange("foo", "dir"), IsNil)
+	s.assertContent(c, "dir", "bar")
+	s.assertContent(c, "foo/bar", "foo")
+	s.assertContent(c, "foo/qux", "qux")
+
+	c.Assert(e.Exchange("foo", "missing"), NotNil)
+	s.assertContent(c, "foo/bar", "foo")
+}
+
+func (s *BasicSuite) assertContent(c *C, filename, content string) {
+	f, err := s.FS.Open(filename)
+	c.Assert(err, IsNil)
+	s.testReadClose(c, f, content)
+}
+
+func (s *BasicSuite) TestChmod(c *C) {
+	ch, ok := s.FS.(Chmod)
+	if !ok {
+		c.Skip("Chmod not supported")
+	}
+
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	err = ch.Chmod("foo", 0755)
+	if err == ErrNotSupported {
+		c.Skip("Chmod not supported")
+	}
+
+	c.Assert(err, IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Mode().Perm(), Equals, os.FileMode(0755))
+	c.Assert(fi.Mode().IsRegular(), Equals, true)
+
+	err = ch.Chmod("missing", 0755)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *BasicSuite) TestChtimes(c *C) {
+	ch, ok := s.FS.(Chtimes)
+	if !ok {
+		c.Skip("Chtimes not supported")
+	}
+
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	mtime := time.Date(2000, time.January, 2, 3, 4, 5, 0, time.UTC)
+	err = ch.Chtimes("foo", mtime, mtime)
+	if err == ErrNotSupported {
+		c.Skip("Chtimes not supported")
+	}
+
+	c.Assert(err, IsNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.ModTime().Equal(mtime), Equals, true, Commentf("%s", fi.ModTime()))
+
+	err = ch.Chtimes("missing", mtime, mtime)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *BasicSuite) TestLink(c *C) {
+	l, ok := s.FS.(Link)
+	if !ok {
+		c.Skip("Link not supported")
+	}
+
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	err = l.Link("foo", "bar")
+	if err == ErrNotSupported {
+		c.Skip("Link not supported")
+	}
+
+	c.Assert(err, IsNil)
+
+	f, err := s.FS.OpenFile("bar", os.O_WRONLY|os.O_APPEND, 0)
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("bar"))
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	foo, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	bar, err := s.FS.Stat("bar")
+	c.Assert(err, IsNil)
+	c.Assert(foo.Size(), Equals, int64(6))
+	c.Assert(bar.Size(), Equals, foo.Size())
+
+	c.Assert(s.FS.Remove("foo"), IsNil)
+	content, err := util.ReadFileString(s.FS, "bar")
+	c.Assert(err, IsNil)
+	c.Assert(content, Equals, "foobar")
+
+	err = l.Link("missing", "qux")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	err = util.WriteFile(s.FS, "qux", nil, 0644)
+	c.Assert(err, IsNil)
+	err = l.Link("bar", "qux")
+	c.Assert(os.IsExist(err), Equals, true)
+}
+
+func (s *BasicSuite) TestRenameOntoLink(c *C) {
+	l, ok := s.FS.(Link)
+	if !ok {
+		c.Skip("Link not supported")
+	}
+
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	err = l.Link("foo", "bar")
+	if err == ErrNotSupported {
+		c.Skip("Link not supported")
+	}
+
+	c.Assert(err, IsNil)
+	c.Assert(s.FS.Rename("foo", "bar"), IsNil)
+
+	for _, name := range []string{"foo", "bar"} {
+		content, err := util.ReadFileString(s.FS, name)
+		c.Assert(err, IsNil, Commentf("file %q", name))
+		c.Assert(content, Equals, "foo")
+	}
+}
@@ -27,6 +27,33 @@ func (s *DirSuite) TestMkdirAll(c *C) {
 	c.Assert(fi.IsDir(), Equals, true)
 }
 
+func (s *DirSuite) TestOpenFileDirForWrite(c *C) {
+	err := s.FS.MkdirAll("foo", os.FileMode(0755))
+	c.Assert(err, IsNil)
+
+	for _, flag := range []int{os.O_WRONLY, os.O_RDWR, os.O_RDWR | os.O_CREATE} {
+		_, err = s.FS.OpenFile("foo", flag, 0644)
+		c.Assert(err, NotNil, Commentf("flag %d", flag))
+	}
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+}
+
+func (s *DirSuite) TestMkdirAllOverFile(c *C) {
+	err := util.WriteFile(s.FS, "foo", []byte("foo"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.FS.MkdirAll("foo", 0755), NotNil)
+	c.Assert(s.FS.MkdirAll("foo/bar", 0755), NotNil)
+
+	fi, err := s.FS.Stat("foo")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Mode().IsRegular(), Equals, true)
+	c.Assert(fi.Size(), Equals, int64(3))
+}
+
 func (s *DirSuite) TestMkdirAllNested(c *C) {
 	err := s.FS.MkdirAll("foo/bar/baz", os.FileMode(0755))
 	c.Assert(err, IsNil)
@@ -136,6 +163,24 @@ func (s *DirSuite) TestReadDir(c *C) {
 	c.Assert(info, HasLen, 2)
 }
 
+func (s *DirSuite) TestReadDirErrors(c *C) {
+	_, err := s.FS.ReadDir("qux")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	err = util.WriteFile(s.FS, "foo", nil, 0644)
+	c.Assert(err, IsNil)
+
+	_, err = s.FS.ReadDir("foo")
+	c.Assert(err, NotNil)
+
+	err = s.FS.MkdirAll("empty", 0755)
+	c.Assert(err, IsNil)
+
+	info, err := s.FS.ReadDir("empty")
+	c.Assert(err, IsNil)
+	c.Assert(info, HasLen, 0)
+}
+
 func (s *DirSuite) TestReadDirNested(c *C) {
 	max := 100
 	path := "/"
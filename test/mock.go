@@ -61,7 +61,7 @@ type TempFileMock struct {
 
 func (fs *TempFileMock) TempFile(dir, prefix string) (billy.File, error) {
 	fs.TempFileArgs = append(fs.TempFileArgs, [2]string{dir, prefix})
-	return &FileMock{name: "/tmp/hardcoded/mock/temp"}, nil
+	return &FileMock{name: path.Join(dir, prefix+"temp")}, nil
 }
 
 type DirMock struct {
@@ -135,6 +135,10 @@ func (*FileMock) Truncate(size int64) error {
 	return nil
 }
 
+func (*FileMock) Sync() error {
+	return nil
+}
+
 type OnlyReadCapFs struct {
 	BasicMock
 }
@@ -1,6 +1,10 @@
 package test
 
 import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
 	. "gopkg.in/check.v1"
@@ -92,3 +96,107 @@ func (s *TempFileSuite) TestTempFileManyWithUtil(c *C) {
 		}
 	}
 }
+
+func (s *TempFileSuite) TestTempFileSuffix(c *C) {
+	t, ok := s.FS.(billy.TempFileSuffix)
+	if !ok {
+		c.Skip("TempFileSuffix not supported")
+	}
+
+	f, err := t.TempFileSuffix("foo", "bar", ".yaml")
+	if err == billy.ErrNotSupported {
+		c.Skip("TempFileSuffix not supported")
+	}
+
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(strings.HasPrefix(f.Name(), s.FS.Join("foo", "bar")), Equals, true)
+	c.Assert(strings.HasSuffix(f.Name(), ".yaml"), Equals, true)
+
+	g, err := t.TempFileSuffix("foo", "bar", ".yaml")
+	c.Assert(err, IsNil)
+	c.Assert(g.Close(), IsNil)
+	c.Assert(g.Name(), Not(Equals), f.Name())
+}
+
+func (s *TempFileSuite) TestTempFileName(c *C) {
+	f, err := s.FS.TempFile("foo/bar", "qux")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	dir, name := filepath.Split(f.Name())
+	c.Assert(dir, Equals, s.FS.Join("foo", "bar")+string(filepath.Separator))
+	c.Assert(strings.HasPrefix(name, "qux"), Equals, true)
+
+	_, err = s.FS.Stat(f.Name())
+	c.Assert(err, IsNil)
+}
+
+func (s *TempFileSuite) TestAnonTempFile(c *C) {
+	t, ok := s.FS.(billy.AnonTempFile)
+	if !ok {
+		c.Skip("AnonTempFile not supported")
+	}
+
+	f, err := t.AnonTempFile()
+	if err == billy.ErrNotSupported {
+		c.Skip("AnonTempFile not supported")
+	}
+
+	c.Assert(err, IsNil)
+
+	_, err = f.Write([]byte("foo"))
+	c.Assert(err, IsNil)
+
+	_, err = f.Seek(0, io.SeekStart)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "foo")
+	c.Assert(f.Close(), IsNil)
+
+	if d, ok := s.FS.(billy.Dir); ok {
+		fis, err := d.ReadDir("/")
+		c.Assert(err, IsNil)
+		c.Assert(fis, HasLen, 0)
+	}
+}
+
+func (s *TempFileSuite) TestCleanupTempFiles(c *C) {
+	t, ok := s.FS.(billy.TempFileTracker)
+	if !ok {
+		c.Skip("TempFileTracker not supported")
+	}
+
+	// nothing is tracked yet, so it only tells if it is supported.
+	if err := t.CleanupTempFiles(); err == billy.ErrNotSupported {
+		c.Skip("TempFileTracker not supported")
+	}
+
+	f, err := s.FS.TempFile("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	g, err := s.FS.TempFile("foo", "bar")
+	c.Assert(err, IsNil)
+	c.Assert(g.Close(), IsNil)
+	c.Assert(s.FS.Rename(g.Name(), "qux"), IsNil)
+
+	err = util.WriteFile(s.FS, "foo/baz", []byte("baz"), 0644)
+	c.Assert(err, IsNil)
+
+	c.Assert(t.TempFiles(), DeepEquals, []string{f.Name()})
+
+	c.Assert(t.CleanupTempFiles(), IsNil)
+	c.Assert(t.TempFiles(), HasLen, 0)
+
+	_, err = s.FS.Stat(f.Name())
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = s.FS.Stat("qux")
+	c.Assert(err, IsNil)
+	_, err = s.FS.Stat("foo/baz")
+	c.Assert(err, IsNil)
+}
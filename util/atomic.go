@@ -0,0 +1,84 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// CreateAtomic creates a file whose content replaces the named file at once
+// on Close, the writes go to a temporary file at the same directory, renamed
+// over filename once closed. So the named file holds either its previous
+// content or the new one, never a partial write.
+//
+// The returned file implements billy.Aborter, Abort removes the temporary
+// file leaving the named file untouched. The mode is set to perm before the
+// rename if the filesystem implements billy.Chmod.
+func CreateAtomic(fs billy.Filesystem, filename string, perm os.FileMode) (billy.File, error) {
+	dir, name := filepath.Split(filename)
+	f, err := fs.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomicFile{File: f, fs: fs, name: filename, perm: perm}, nil
+}
+
+type atomicFile struct {
+	billy.File
+	fs   billy.Filesystem
+	name string
+	perm os.FileMode
+
+	done bool
+}
+
+func (f *atomicFile) Name() string {
+	return f.name
+}
+
+// Close closes the file and renames it over the named file. If anything
+// fails the temporary file is removed.
+func (f *atomicFile) Close() error {
+	if f.done {
+		return os.ErrClosed
+	}
+
+	f.done = true
+	tmp := f.File.Name()
+
+	err := f.File.Close()
+	if c, ok := f.fs.(billy.Chmod); ok && err == nil {
+		if cerr := c.Chmod(tmp, f.perm); cerr != billy.ErrNotSupported {
+			err = cerr
+		}
+	}
+
+	if err == nil {
+		err = f.fs.Rename(tmp, f.name)
+	}
+
+	if err != nil {
+		_ = f.fs.Remove(tmp)
+	}
+
+	return err
+}
+
+// Abort implements the billy.Aborter interface, it closes and removes the
+// temporary file, the named file is left untouched.
+func (f *atomicFile) Abort() error {
+	if f.done {
+		return os.ErrClosed
+	}
+
+	f.done = true
+
+	err := f.File.Close()
+	if rerr := f.fs.Remove(f.File.Name()); err == nil {
+		err = rerr
+	}
+
+	return err
+}
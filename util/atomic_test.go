@@ -0,0 +1,74 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestCreateAtomic(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "foo/bar", []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := util.CreateAtomic(fs, "foo/bar", 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, fs, "foo/bar", "old")
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, fs, "foo/bar", "new")
+	assertDirEntries(t, fs, "foo", 1)
+}
+
+func TestCreateAtomicAbort(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "foo/bar", []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := util.CreateAtomic(fs, "foo/bar", 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.(billy.Aborter).Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err == nil {
+		t.Errorf("Close() after Abort() succeeded")
+	}
+
+	assertFile(t, fs, "foo/bar", "old")
+	assertDirEntries(t, fs, "foo", 1)
+}
+
+func assertDirEntries(t *testing.T, fs billy.Filesystem, dir string, expected int) {
+	t.Helper()
+
+	fis, err := fs.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fis) != expected {
+		t.Errorf("%s has %d entries, want %d", dir, len(fis), expected)
+	}
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// ChmodAll changes the mode of all the files of the tree rooted at root,
+// including root, to dirMode for the directories and to fileMode for the
+// regular files, eg.: to normalize the permissions of an extracted archive.
+// The symlinks and the other types of files are left as they are.
+//
+// It fails with billy.ErrNotSupported if fs doesn't implement billy.Chmod.
+// The directories are changed before their content, so dirMode must allow
+// to read them.
+func ChmodAll(fs billy.Filesystem, root string, dirMode, fileMode os.FileMode) error {
+	c, ok := fs.(billy.Chmod)
+	if !ok {
+		return billy.ErrNotSupported
+	}
+
+	return Walk(fs, root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case fi.IsDir():
+			return c.Chmod(path, dirMode)
+		case fi.Mode().IsRegular():
+			return c.Chmod(path, fileMode)
+		default:
+			return nil
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package util_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestChmodAll(t *testing.T) {
+	fs := memfs.New()
+	for _, name := range []string{"foo/bar", "foo/baz/qux"} {
+		if err := util.WriteFile(fs, name, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fs.Symlink("bar", "foo/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.ChmodAll(fs, "foo", 0750, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]os.FileMode{
+		"foo":         os.ModeDir | 0750,
+		"foo/baz":     os.ModeDir | 0750,
+		"foo/bar":     0640,
+		"foo/baz/qux": 0640,
+	} {
+		fi, err := fs.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fi.Mode() != want {
+			t.Errorf("mode of %s = %v, want %v", name, fi.Mode(), want)
+		}
+	}
+
+	fi, err := fs.Lstat("foo/link")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("mode of foo/link = %v, want a symlink", fi.Mode())
+	}
+}
+
+func TestChmodAllNotSupported(t *testing.T) {
+	fs := struct{ billy.Filesystem }{memfs.New()}
+	if err := util.WriteFile(fs, "foo", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.ChmodAll(fs, "foo", 0750, 0640); err != billy.ErrNotSupported {
+		t.Errorf("ChmodAll = %v, want billy.ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"io"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// rangeCopier is implemented by the files able to copy a range of bytes from
+// another file without reading it, eg.: with copy_file_range.
+type rangeCopier interface {
+	CopyRangeFrom(dstOff int64, src billy.File, srcOff, n int64) (int64, error)
+}
+
+type underlyingFile interface {
+	Underlying() billy.File
+}
+
+// defaultBufferSize is the size of the buffers used to copy, when no buffer is
+// given.
+const defaultBufferSize = 32 * 1024
+
+// CopyBuffer copies from src to dst until either EOF is reached on src or an
+// error occurs, like io.CopyBuffer, staging the data through buf. It returns
+// the number of bytes copied and the first error encountered, if any.
+//
+// A buffer of a default size is allocated if buf is nil, so the buffers can be
+// reused, eg.: from a sync.Pool. As io.CopyBuffer, it panics if buf is not nil
+// but empty.
+func CopyBuffer(dst billy.File, src billy.File, buf []byte) (int64, error) {
+	return io.CopyBuffer(dst, src, buf)
+}
+
+// CopyRange copies n bytes from src, starting at the offset srcOff, to dst at
+// the offset dstOff. The positions of the files are not changed. The copy is
+// done in chunks, using ReadAt and WriteAt, unless the files support a
+// faster way, like the files from osfs on Linux. If src has less than n bytes
+// from srcOff, the number of bytes copied and io.EOF are returned.
+func CopyRange(dst billy.File, dstOff int64, src billy.File, srcOff, n int64) (int64, error) {
+	return CopyRangeBuffer(dst, dstOff, src, srcOff, n, nil)
+}
+
+// CopyRangeBuffer behaves as CopyRange, staging the data through buf when the
+// files don't support a faster way. A buffer of a default size is allocated
+// if buf is nil, it panics if buf is not nil but empty.
+func CopyRangeBuffer(dst billy.File, dstOff int64, src billy.File, srcOff, n int64, buf []byte) (int64, error) {
+	if buf != nil && len(buf) == 0 {
+		panic("empty buffer in CopyRangeBuffer")
+	}
+
+	if rc, ok := unwrapFile(dst).(rangeCopier); ok {
+		written, err := rc.CopyRangeFrom(dstOff, unwrapFile(src), srcOff, n)
+		if err != billy.ErrNotSupported {
+			return written, err
+		}
+	}
+
+	if buf == nil {
+		buf = make([]byte, defaultBufferSize)
+	}
+
+	var written int64
+	for written < n {
+		if int64(len(buf)) > n-written {
+			buf = buf[:n-written]
+		}
+
+		nr, rerr := src.ReadAt(buf, srcOff+written)
+		if nr > 0 {
+			nw, err := writeAt(dst, buf[:nr], dstOff+written)
+			written += int64(nw)
+			if err != nil {
+				return written, err
+			}
+		}
+
+		if rerr == io.EOF && written == n {
+			break
+		}
+
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	return written, nil
+}
+
+// writeAt writes p at the given offset of f, using WriteAt if available,
+// otherwise seeking to the offset and back to the original position.
+func writeAt(f billy.File, p []byte, off int64) (n int, err error) {
+	if w, ok := f.(io.WriterAt); ok {
+		return w.WriteAt(p, off)
+	}
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err = f.Write(p)
+	if _, serr := f.Seek(pos, io.SeekStart); err == nil {
+		err = serr
+	}
+
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+
+	return n, err
+}
+
+// unwrapFile returns the innermost file wrapped by f.
+func unwrapFile(f billy.File) billy.File {
+	for {
+		u, ok := f.(underlyingFile)
+		if !ok {
+			return f
+		}
+
+		f = u.Underlying()
+	}
+}
@@ -0,0 +1,138 @@
+package util_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestCopyRange(t *testing.T) {
+	testCopyRange(t, memfs.New())
+}
+
+func TestCopyRangeOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "billy-copy-range")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testCopyRange(t, osfs.New(dir))
+}
+
+func testCopyRange(t *testing.T, fs billy.Filesystem) {
+	if err := util.WriteFile(fs, "src", []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.WriteFile(fs, "dst", []byte("abcdefghij"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := fs.Open("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	dst, err := fs.OpenFile("dst", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	if _, err := src.Seek(1, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := util.CopyRange(dst, 2, src, 5, 3)
+	if err != nil || n != 3 {
+		t.Fatalf("CopyRange() = %d, %v, want 3", n, err)
+	}
+
+	if pos, _ := src.Seek(0, io.SeekCurrent); pos != 1 {
+		t.Errorf("src position = %d, want 1", pos)
+	}
+
+	if pos, _ := dst.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Errorf("dst position = %d, want 0", pos)
+	}
+
+	n, err = util.CopyRange(dst, 9, src, 8, 5)
+	if err != io.EOF || n != 2 {
+		t.Errorf("CopyRange() = %d, %v, want 2, io.EOF", n, err)
+	}
+
+	content, err := ioutil.ReadAll(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "ab567fghi89" {
+		t.Errorf("content = %q, want %q", content, "ab567fghi89")
+	}
+}
+
+func TestCopyBuffer(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "src", []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := fs.Open("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	dst, err := fs.Create("dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := util.CopyBuffer(&plainFile{dst}, &plainFile{src}, make([]byte, 3))
+	if err != nil || n != 10 {
+		t.Fatalf("CopyBuffer() = %d, %v, want 10", n, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, fs, "dst", "0123456789")
+}
+
+func TestCopyRangeBuffer(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "src", []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := fs.Open("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	dst, err := fs.Create("dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := util.CopyRangeBuffer(&plainFile{dst}, 0, &plainFile{src}, 2, 7, make([]byte, 2))
+	if err != nil || n != 7 {
+		t.Fatalf("CopyRangeBuffer() = %d, %v, want 7", n, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, fs, "dst", "2345678")
+}
@@ -0,0 +1,187 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// Digester is implemented by the filesystems able to return a digest of the
+// content of a file without reading it, eg.: from a cache. Digests from two
+// filesystems are comparable only if both are computed with the same
+// algorithm.
+type Digester interface {
+	// Digest returns the digest of the content of the named file.
+	Digest(filename string) ([]byte, error)
+}
+
+// EqualOptions holds the options to compare two trees with EqualWithOptions.
+type EqualOptions struct {
+	// SizeAndModTime considers equal two files with the same size and
+	// modification time, without comparing its content.
+	SizeAndModTime bool
+	// Digest compares the digests of the files, instead of its content, when
+	// both filesystems implement Digester.
+	Digest bool
+}
+
+// Equal returns true if the trees at the given path, in the filesystems a and
+// b, are equal: same names, types, symlink targets and file contents. The
+// permissions and the modification times are not taken into account.
+func Equal(a, b billy.Filesystem, path string) (bool, error) {
+	return EqualWithOptions(a, b, path, nil)
+}
+
+// EqualWithOptions behaves as Equal, using the given options to avoid reading
+// the content of the files when possible. The content is compared when the
+// sizes of the files are equal and the options can't tell if they differ.
+func EqualWithOptions(a, b billy.Filesystem, path string, o *EqualOptions) (bool, error) {
+	if o == nil {
+		o = &EqualOptions{}
+	}
+
+	return equal(a, b, path, o)
+}
+
+func equal(a, b billy.Filesystem, path string, o *EqualOptions) (bool, error) {
+	fa, err := a.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+
+	fb, err := b.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+
+	if fa.Mode()&os.ModeType != fb.Mode()&os.ModeType {
+		return false, nil
+	}
+
+	switch {
+	case fa.Mode()&os.ModeSymlink != 0:
+		return equalSymlink(a, b, path)
+	case fa.IsDir():
+		return equalDir(a, b, path, o)
+	default:
+		return equalFile(a, b, path, fa, fb, o)
+	}
+}
+
+func equalSymlink(a, b billy.Filesystem, path string) (bool, error) {
+	ta, err := a.Readlink(path)
+	if err != nil {
+		return false, err
+	}
+
+	tb, err := b.Readlink(path)
+	if err != nil {
+		return false, err
+	}
+
+	return ta == tb, nil
+}
+
+func equalDir(a, b billy.Filesystem, path string, o *EqualOptions) (bool, error) {
+	la, err := a.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+
+	lb, err := b.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+
+	if len(la) != len(lb) {
+		return false, nil
+	}
+
+	names := make(map[string]bool, len(lb))
+	for _, fi := range lb {
+		names[fi.Name()] = true
+	}
+
+	for _, fi := range la {
+		if !names[fi.Name()] {
+			return false, nil
+		}
+
+		eq, err := equal(a, b, a.Join(path, fi.Name()), o)
+		if err != nil || !eq {
+			return eq, err
+		}
+	}
+
+	return true, nil
+}
+
+func equalFile(a, b billy.Filesystem, path string, fa, fb os.FileInfo, o *EqualOptions) (bool, error) {
+	if fa.Size() != fb.Size() {
+		return false, nil
+	}
+
+	if o.SizeAndModTime && fa.ModTime().Equal(fb.ModTime()) {
+		return true, nil
+	}
+
+	if o.Digest {
+		da, oka := a.(Digester)
+		db, okb := b.(Digester)
+		if oka && okb {
+			ha, err := da.Digest(path)
+			if err != nil {
+				return false, err
+			}
+
+			hb, err := db.Digest(path)
+			if err != nil {
+				return false, err
+			}
+
+			return bytes.Equal(ha, hb), nil
+		}
+	}
+
+	return equalContent(a, b, path)
+}
+
+func equalContent(a, b billy.Filesystem, path string) (bool, error) {
+	fa, err := a.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+
+	fb, err := b.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufa := make([]byte, defaultBufferSize)
+	bufb := make([]byte, defaultBufferSize)
+	for {
+		na, erra := io.ReadFull(fa, bufa)
+		nb, errb := io.ReadFull(fb, bufb)
+		if !bytes.Equal(bufa[:na], bufb[:nb]) {
+			return false, nil
+		}
+
+		aDone := erra == io.EOF || erra == io.ErrUnexpectedEOF
+		bDone := errb == io.EOF || errb == io.ErrUnexpectedEOF
+		if erra != nil && !aDone {
+			return false, erra
+		}
+
+		if errb != nil && !bDone {
+			return false, errb
+		}
+
+		if aDone || bDone {
+			return aDone == bDone, nil
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package util_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestEqual(t *testing.T) {
+	a := newTree(t)
+	b := newTree(t)
+
+	eq, err := util.Equal(a, b, "/")
+	if err != nil || !eq {
+		t.Errorf("Equal() = %v, %v, want true", eq, err)
+	}
+
+	if err := util.WriteFile(b, "foo/bar", []byte("baz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	eq, err = util.Equal(a, b, "/")
+	if err != nil || eq {
+		t.Errorf("Equal() = %v, %v, want false", eq, err)
+	}
+
+	eq, err = util.Equal(a, b, "qux")
+	if err != nil || !eq {
+		t.Errorf("Equal(qux) = %v, %v, want true", eq, err)
+	}
+}
+
+func TestEqualMissingEntry(t *testing.T) {
+	a := newTree(t)
+	b := newTree(t)
+
+	if err := b.Remove("qux/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	eq, err := util.Equal(a, b, "/")
+	if err != nil || eq {
+		t.Errorf("Equal() = %v, %v, want false", eq, err)
+	}
+}
+
+func TestEqualWithDigest(t *testing.T) {
+	a := &digestFS{Filesystem: newTree(t)}
+	b := &digestFS{Filesystem: newTree(t)}
+
+	eq, err := util.EqualWithOptions(a, b, "/", &util.EqualOptions{Digest: true})
+	if err != nil || !eq {
+		t.Errorf("EqualWithOptions() = %v, %v, want true", eq, err)
+	}
+
+	if a.calls == 0 || b.calls == 0 {
+		t.Errorf("Digest was not called")
+	}
+}
+
+func TestEqualSizeAndModTime(t *testing.T) {
+	a := &openCountFS{Filesystem: newTree(t)}
+	b := &openCountFS{Filesystem: newTree(t)}
+
+	if err := util.WriteFile(b.Filesystem, "foo/bar", []byte("baz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, fs := range []*openCountFS{a, b} {
+		if err := fs.Filesystem.(billy.Chtimes).Chtimes("foo/bar", mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o := &util.EqualOptions{SizeAndModTime: true}
+	eq, err := util.EqualWithOptions(a, b, "foo", o)
+	if err != nil || !eq {
+		t.Errorf("EqualWithOptions(foo) = %v, %v, want true", eq, err)
+	}
+
+	if a.opens != 0 || b.opens != 0 {
+		t.Errorf("the content was read, %d and %d opens", a.opens, b.opens)
+	}
+
+	eq, err = util.EqualWithOptions(a, b, "foo", nil)
+	if err != nil || eq {
+		t.Errorf("EqualWithOptions(foo, nil) = %v, %v, want false", eq, err)
+	}
+
+	if err := b.Filesystem.(billy.Chtimes).Chtimes("foo/bar", mtime, mtime.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	eq, err = util.EqualWithOptions(a, b, "foo", o)
+	if err != nil || eq {
+		t.Errorf("EqualWithOptions(foo) with different times = %v, %v, want false", eq, err)
+	}
+}
+
+func TestEqualDifferentSize(t *testing.T) {
+	a := &openCountFS{Filesystem: newTree(t)}
+	b := &openCountFS{Filesystem: newTree(t)}
+
+	if err := util.WriteFile(b.Filesystem, "foo/bar", []byte("barbar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, o := range []*util.EqualOptions{nil, {SizeAndModTime: true}} {
+		eq, err := util.EqualWithOptions(a, b, "foo", o)
+		if err != nil || eq {
+			t.Errorf("EqualWithOptions(%+v) = %v, %v, want false", o, eq, err)
+		}
+	}
+
+	if a.opens != 0 || b.opens != 0 {
+		t.Errorf("the content was read, %d and %d opens", a.opens, b.opens)
+	}
+}
+
+func newTree(t *testing.T) billy.Filesystem {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "foo/bar", []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.WriteFile(fs, "qux/baz", []byte("baz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Symlink("baz", "qux/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	return fs
+}
+
+type digestFS struct {
+	billy.Filesystem
+	calls int
+}
+
+func (fs *digestFS) Digest(filename string) ([]byte, error) {
+	fs.calls++
+	return []byte(filename), nil
+}
+
+// openCountFS counts the files opened, to tell if the content was read.
+type openCountFS struct {
+	billy.Filesystem
+	opens int
+}
+
+func (fs *openCountFS) Open(filename string) (billy.File, error) {
+	fs.opens++
+	return fs.Filesystem.Open(filename)
+}
+
+func (fs *openCountFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	fs.opens++
+	return fs.Filesystem.OpenFile(filename, flag, perm)
+}
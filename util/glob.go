@@ -11,7 +11,9 @@ import (
 // Glob returns the names of all files matching pattern or nil
 // if there is no matching file. The syntax of patterns is the same
 // as in Match. The pattern may describe hierarchical names such as
-// /usr/*/bin/ed (assuming the Separator is '/').
+// /usr/*/bin/ed (assuming the Separator is '/'). As in Match, "**" is the
+// same as "*" and doesn't cross the path separators, see GlobStar for
+// matching any number of directories.
 //
 // Glob ignores file system errors such as I/O errors reading directories.
 // The only possible returned error is ErrBadPattern, when pattern
@@ -32,3 +32,22 @@ func (s *UtilSuite) TestCreate(c *C) {
 	})
 
 }
+
+func (s *UtilSuite) TestGlobDoubleStar(c *C) {
+	fs := memfs.New()
+	util.WriteFile(fs, "src/foo.go", nil, 0644)
+	util.WriteFile(fs, "src/bar/baz.go", nil, 0644)
+
+	names, err := util.Glob(fs, "src/**/*.go")
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{
+		filepath.Join("src", "bar", "baz.go"),
+	})
+
+	names, err = util.GlobStar(fs, "src/**/*.go")
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{
+		filepath.Join("src", "bar", "baz.go"),
+		filepath.Join("src", "foo.go"),
+	})
+}
@@ -0,0 +1,121 @@
+package util
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// GlobStar returns the sorted names of all files matching pattern, like
+// Glob, but with support for "**": a path element "**" matches any number of
+// directories, including none, so "src/**/*.go" matches "src/foo.go" and
+// "src/foo/bar/baz.go". A "**" at the end of the pattern matches all the files
+// and directories below. Any other "*" doesn't cross the path separators.
+//
+// The symlinks to directories are not followed by "**", to avoid loops. As
+// Glob, it ignores file system errors, the only possible returned error is
+// filepath.ErrBadPattern.
+func GlobStar(fs billy.Filesystem, pattern string) ([]string, error) {
+	pattern = filepath.FromSlash(pattern)
+	parts := strings.Split(pattern, string(filepath.Separator))
+
+	dir := ""
+	if parts[0] == "" {
+		dir = string(filepath.Separator)
+	}
+
+	var elems []string
+	for _, p := range parts {
+		if p == "" || p == "." {
+			continue
+		}
+
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, err
+		}
+
+		// consecutive "**" are the same as a single one.
+		if p == "**" && len(elems) > 0 && elems[len(elems)-1] == "**" {
+			continue
+		}
+
+		elems = append(elems, p)
+	}
+
+	found := make(map[string]bool)
+	globStar(fs, dir, elems, found)
+
+	matches := make([]string, 0, len(found))
+	for m := range found {
+		matches = append(matches, m)
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func globStar(fs billy.Filesystem, dir string, elems []string, found map[string]bool) {
+	if len(elems) == 0 {
+		if dir != "" {
+			found[dir] = true
+		}
+
+		return
+	}
+
+	elem, rest := elems[0], elems[1:]
+	if !hasMeta(elem) {
+		path := joinGlob(fs, dir, elem)
+		if _, err := fs.Lstat(path); err == nil {
+			globStar(fs, path, rest, found)
+		}
+
+		return
+	}
+
+	files, err := fs.ReadDir(readDirPath(dir))
+	if err != nil {
+		return
+	}
+
+	if elem != "**" {
+		for _, fi := range files {
+			if matched, _ := filepath.Match(elem, fi.Name()); matched {
+				globStar(fs, joinGlob(fs, dir, fi.Name()), rest, found)
+			}
+		}
+
+		return
+	}
+
+	// "**" matching no directory.
+	globStar(fs, dir, rest, found)
+
+	for _, fi := range files {
+		path := joinGlob(fs, dir, fi.Name())
+		if fi.IsDir() {
+			globStar(fs, path, elems, found)
+		} else if len(rest) == 0 {
+			found[path] = true
+		}
+	}
+}
+
+// joinGlob joins dir and name, dir being "" for the current directory.
+func joinGlob(fs billy.Filesystem, dir, name string) string {
+	if dir == "" {
+		return name
+	}
+
+	return fs.Join(dir, name)
+}
+
+func readDirPath(dir string) string {
+	if dir == "" {
+		return "."
+	}
+
+	return dir
+}
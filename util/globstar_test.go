@@ -0,0 +1,66 @@
+package util_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestGlobStar(t *testing.T) {
+	fs := memfs.New()
+	for _, name := range []string{
+		"main.go",
+		"src/foo.go",
+		"src/foo.txt",
+		"src/bar/bar.go",
+		"src/bar/qux/qux.go",
+		"srcgo/baz.go",
+	} {
+		if err := util.WriteFile(fs, name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fs.Symlink("src", "src/bar/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		pattern  string
+		expected []string
+	}{
+		{"src/**/*.go", []string{"src/bar/bar.go", "src/bar/qux/qux.go", "src/foo.go"}},
+		{"**/*.go", []string{"main.go", "src/bar/bar.go", "src/bar/qux/qux.go", "src/foo.go", "srcgo/baz.go"}},
+		{"src/**/qux", []string{"src/bar/qux"}},
+		{"src/*.go", []string{"src/foo.go"}},
+		{"src/**/**/*.txt", []string{"src/foo.txt"}},
+		{"src/bar/**", []string{"src/bar", "src/bar/bar.go", "src/bar/link", "src/bar/qux", "src/bar/qux/qux.go"}},
+		{"src**/*.go", []string{"src/foo.go", "srcgo/baz.go"}},
+		{"missing/**/*.go", []string{}},
+	} {
+		matches, err := util.GlobStar(fs, tc.pattern)
+		if err != nil {
+			t.Errorf("GlobStar(%q) = %v", tc.pattern, err)
+			continue
+		}
+
+		expected := make([]string, len(tc.expected))
+		for i, e := range tc.expected {
+			expected[i] = filepath.FromSlash(e)
+		}
+
+		if !reflect.DeepEqual(matches, expected) {
+			t.Errorf("GlobStar(%q) = %q, want %q", tc.pattern, matches, expected)
+		}
+	}
+}
+
+func TestGlobStarBadPattern(t *testing.T) {
+	_, err := util.GlobStar(memfs.New(), "src/**/[")
+	if err != filepath.ErrBadPattern {
+		t.Errorf("GlobStar() = %v, want %v", err, filepath.ErrBadPattern)
+	}
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// Lines returns an iterator over the lines of f, read from its current
+// position through a buffer, so the file is never loaded at once, eg.: to
+// parse gitignore or config files. Each call returns the next line, without
+// its "\n" or "\r\n" ending, and io.EOF once there are no more lines. The last
+// line is returned even if it doesn't end with a newline.
+//
+// An error is returned if f is known to be opened as write-only.
+func Lines(f billy.File) (func() (string, error), error) {
+	if ff, ok := unwrapFile(f).(flagsFile); ok && ff.Flags()&os.O_WRONLY != 0 {
+		return nil, &os.PathError{Op: "read", Path: f.Name(), Err: billy.ErrWriteOnly}
+	}
+
+	r := bufio.NewReader(f)
+	return func() (string, error) {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		return strings.TrimSuffix(line, "\r"), nil
+	}, nil
+}
@@ -0,0 +1,76 @@
+package util_test
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestLines(t *testing.T) {
+	fs := memfs.New()
+	for content, want := range map[string][]string{
+		"":                   nil,
+		"foo":                {"foo"},
+		"foo\n":              {"foo"},
+		"foo\r\nbar\n\nqux":  {"foo", "bar", "", "qux"},
+		"foo\r\nbar\r\n\r\n": {"foo", "bar", ""},
+		"a\rb\nc":            {"a\rb", "c"},
+	} {
+		if err := util.WriteString(fs, "foo", content, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := fs.Open("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, f := range []billy.File{f, plainFile{f}} {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+
+			next, err := util.Lines(f)
+			if err != nil {
+				t.Fatalf("Lines(%q): %v", content, err)
+			}
+
+			var lines []string
+			for {
+				line, err := next()
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					t.Fatalf("Lines(%q): %v", content, err)
+				}
+
+				lines = append(lines, line)
+			}
+
+			if !reflect.DeepEqual(lines, want) {
+				t.Errorf("Lines(%q) = %q, want %q", content, lines, want)
+			}
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := fs.OpenFile("foo", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := util.Lines(f); err == nil {
+		t.Errorf("Lines(write-only) = _, nil, want error")
+	}
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// LinkOrCopy creates newname as a hard link to the oldname file if fs
+// implements billy.Link, falling back to a copy of it keeping its permissions
+// if it doesn't, or if the link fails, eg.: when links aren't supported by
+// the underlying storage. It's meant to materialize many trees sharing the
+// same files, where a link is cheaper than a copy.
+//
+// As with Link, newname must not exist. If both the link and the copy fail,
+// the error tells the reason of both.
+func LinkOrCopy(fs billy.Filesystem, oldname, newname string) error {
+	if _, err := fs.Lstat(newname); err == nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+
+	var linkErr error
+	if l, ok := fs.(billy.Link); ok {
+		if linkErr = l.Link(oldname, newname); linkErr == nil {
+			return nil
+		}
+	}
+
+	err := copyLinked(fs, oldname, newname)
+	if err == nil {
+		return nil
+	}
+
+	if linkErr != nil {
+		return fmt.Errorf("link failed: %v, copy failed: %w", linkErr, err)
+	}
+
+	return err
+}
+
+// copyLinked copies oldname to newname, keeping its permissions, removing
+// newname if the copy fails.
+func copyLinked(fs billy.Filesystem, oldname, newname string) error {
+	fi, err := fs.Stat(oldname)
+	if err != nil {
+		return err
+	}
+
+	if err := CopyFile(fs, fs, newname, oldname, fi.Mode().Perm()); err != nil {
+		_ = fs.Remove(newname)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package util_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// noLinkFS hides the Link method of the filesystem.
+type noLinkFS struct {
+	billy.Filesystem
+}
+
+// failingLinkFS fails to create hard links.
+type failingLinkFS struct {
+	billy.Filesystem
+}
+
+func (fs *failingLinkFS) Link(string, string) error {
+	return billy.ErrNotSupported
+}
+
+func TestLinkOrCopy(t *testing.T) {
+	for name, tc := range map[string]struct {
+		fs     func(billy.Filesystem) billy.Filesystem
+		linked bool
+	}{
+		"link":    {func(fs billy.Filesystem) billy.Filesystem { return fs }, true},
+		"no link": {func(fs billy.Filesystem) billy.Filesystem { return &noLinkFS{fs} }, false},
+		"failing": {func(fs billy.Filesystem) billy.Filesystem { return &failingLinkFS{fs} }, false},
+	} {
+		fs := tc.fs(memfs.New())
+		if err := util.WriteFile(fs, "foo", []byte("foo"), 0640); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := util.LinkOrCopy(fs, "foo", "bar/qux"); err != nil {
+			t.Fatalf("%s: LinkOrCopy() = %v", name, err)
+		}
+
+		assertFile(t, fs, "bar/qux", "foo")
+
+		fi, err := fs.Stat("bar/qux")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fi.Mode() != 0640 {
+			t.Errorf("%s: mode = %v, want %v", name, fi.Mode(), os.FileMode(0640))
+		}
+
+		n, _ := util.LinkCount(fi)
+		if linked := n == 2; linked != tc.linked {
+			t.Errorf("%s: links = %d, want linked %v", name, n, tc.linked)
+		}
+	}
+}
+
+func TestLinkOrCopyErrors(t *testing.T) {
+	fs := memfs.New()
+	for _, name := range []string{"foo", "bar"} {
+		if err := util.WriteFile(fs, name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := util.LinkOrCopy(fs, "foo", "bar"); !os.IsExist(err) {
+		t.Errorf("LinkOrCopy() = %v, want exist error", err)
+	}
+
+	assertFile(t, fs, "bar", "bar")
+
+	err := util.LinkOrCopy(&failingLinkFS{fs}, "qux", "baz")
+	if !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "link failed") {
+		t.Errorf("LinkOrCopy() = %v, want the link and copy errors", err)
+	}
+
+	if _, err := fs.Lstat("baz"); !os.IsNotExist(err) {
+		t.Errorf("Lstat(baz) = %v, want not exist", err)
+	}
+}
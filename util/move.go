@@ -0,0 +1,121 @@
+package util
+
+import (
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// Move moves srcPath from the src filesystem to dstPath at the dst filesystem,
+// being srcPath a file, a directory or a symlink. If dst and src are the same
+// filesystem, Move is just a Rename, so it is atomic.
+//
+// Otherwise, srcPath and all its descendants are copied to dst and removed
+// from src once the copy is completed. In this case dstPath must not exist,
+// and if the copy fails, anything already copied to dst is removed.
+func Move(dst, src billy.Filesystem, dstPath, srcPath string) error {
+	if dst == src {
+		return src.Rename(srcPath, dstPath)
+	}
+
+	if _, err := dst.Lstat(dstPath); err == nil {
+		return &os.LinkError{Op: "move", Old: srcPath, New: dstPath, Err: os.ErrExist}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := copyTree(dst, src, dstPath, srcPath, nil); err != nil {
+		_ = RemoveAll(dst, dstPath)
+		return err
+	}
+
+	return RemoveAll(src, srcPath)
+}
+
+// CopyFile copies the content of srcPath from the src filesystem to dstPath at
+// the dst filesystem, creating or truncating dstPath with the given perm. The
+// filesystems may be different, eg.: from memfs to osfs.
+func CopyFile(dst, src billy.Basic, dstPath, srcPath string, perm os.FileMode) error {
+	return copyFile(dst, src, dstPath, srcPath, perm, nil)
+}
+
+// CopyTree copies srcPath from the src filesystem to dstPath at the dst
+// filesystem, being srcPath a file, a directory or a symlink, recreating the
+// directories with ReadDir and the symlinks without following them. The files
+// keep their permissions. Unlike Move, the existing files at dst are
+// overwritten, and nothing is removed if the copy fails.
+func CopyTree(dst, src billy.Filesystem, dstPath, srcPath string) error {
+	return copyTree(dst, src, dstPath, srcPath, nil)
+}
+
+// copyTree copies srcPath from src to dstPath at dst, recursing into the
+// directories. Symlinks are copied as symlinks, without following them. The
+// content of the files is copied through buf, as in CopyBuffer.
+func copyTree(dst, src billy.Filesystem, dstPath, srcPath string, buf []byte) error {
+	fi, err := src.Lstat(srcPath)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := src.Readlink(srcPath)
+		if err != nil {
+			return err
+		}
+
+		return dst.Symlink(target, dstPath)
+	case fi.IsDir():
+		if err := dst.MkdirAll(dstPath, fi.Mode().Perm()); err != nil {
+			return err
+		}
+
+		fis, err := src.ReadDir(srcPath)
+		if err != nil {
+			return err
+		}
+
+		for _, fi := range fis {
+			err := copyTree(dst, src,
+				dst.Join(dstPath, fi.Name()),
+				src.Join(srcPath, fi.Name()),
+				buf,
+			)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		return copyFile(dst, src, dstPath, srcPath, fi.Mode().Perm(), buf)
+	}
+}
+
+// copyFile copies the content of srcPath from src to dstPath at dst, creating
+// or truncating dstPath with the given perm. The content is copied through
+// buf, as in CopyBuffer.
+func copyFile(dst, src billy.Basic, dstPath, srcPath string, perm os.FileMode, buf []byte) error {
+	sf, err := src.Open(srcPath)
+	if err != nil {
+		return err
+	}
+
+	df, err := dst.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		_ = sf.Close()
+		return err
+	}
+
+	_, err = CopyBuffer(df, sf, buf)
+	if err1 := df.Close(); err == nil {
+		err = err1
+	}
+
+	if err1 := sf.Close(); err == nil {
+		err = err1
+	}
+
+	return err
+}
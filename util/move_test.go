@@ -0,0 +1,161 @@
+package util_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestMoveSameFilesystem(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "foo/bar", []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.Move(fs, fs, "qux", "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Stat("foo"); !os.IsNotExist(err) {
+		t.Errorf("Stat(foo) = %v, want not exist", err)
+	}
+
+	assertFile(t, fs, "qux/bar", "bar")
+}
+
+func TestMoveAcrossFilesystems(t *testing.T) {
+	src := memfs.New()
+	dst := memfs.New()
+
+	if err := util.WriteFile(src, "foo/bar", []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.WriteFile(src, "foo/baz/qux", []byte("qux"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.Symlink("bar", "foo/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.Move(dst, src, "moved", "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := src.Stat("foo"); !os.IsNotExist(err) {
+		t.Errorf("Stat(foo) = %v, want not exist", err)
+	}
+
+	assertFile(t, dst, "moved/bar", "bar")
+	assertFile(t, dst, "moved/baz/qux", "qux")
+
+	target, err := dst.Readlink("moved/link")
+	if err != nil || target != "bar" {
+		t.Errorf("Readlink(moved/link) = %q, %v", target, err)
+	}
+
+	fi, err := dst.Stat("moved/baz/qux")
+	if err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Stat(moved/baz/qux) = %v, %v", fi, err)
+	}
+}
+
+func TestMoveAcrossFilesystemsExistingDestination(t *testing.T) {
+	src := memfs.New()
+	dst := memfs.New()
+
+	if err := util.WriteFile(src, "foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.WriteFile(dst, "foo", []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.Move(dst, src, "foo", "foo"); !os.IsExist(err) {
+		t.Errorf("Move() = %v, want exist error", err)
+	}
+
+	assertFile(t, src, "foo", "foo")
+	assertFile(t, dst, "foo", "bar")
+}
+
+func assertFile(t *testing.T, fs billy.Basic, filename, expected string) {
+	t.Helper()
+
+	f, err := fs.Open(filename)
+	if err != nil {
+		t.Fatalf("Open(%q) = %v", filename, err)
+	}
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != expected {
+		t.Errorf("content of %q = %q, want %q", filename, content, expected)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	src, dst := memfs.New(), memfs.New()
+	if err := util.WriteFile(src, "foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.WriteFile(dst, "bar", []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.CopyFile(dst, src, "bar", "foo", 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, dst, "bar", "foo")
+	assertFile(t, src, "foo", "foo")
+
+	if err := util.CopyFile(dst, src, "qux", "missing", 0600); !os.IsNotExist(err) {
+		t.Errorf("CopyFile of a missing file = %v, want not exist", err)
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src, dst := memfs.New(), memfs.New()
+	for name, content := range map[string]string{
+		"foo/bar":     "bar",
+		"foo/baz/qux": "qux",
+		"foo/empty":   "",
+	} {
+		if err := util.WriteFile(src, name, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := src.MkdirAll("foo/dir", 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.Symlink("bar", "foo/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.CopyTree(dst, src, "foo", "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	equal, err := util.Equal(dst, src, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal {
+		t.Error("the tree copied differs from the source")
+	}
+}
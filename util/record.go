@@ -0,0 +1,158 @@
+package util
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// recordHeaderSize is the size of the header of a record: the length of its
+// data and their CRC-32, as big-endian uint32.
+const recordHeaderSize = 8
+
+// ErrCorruptRecord is returned by ReadRecordAt when the data of a record
+// don't match its checksum.
+var ErrCorruptRecord = errors.New("corrupt record")
+
+// recordLocks holds the locks serializing AppendRecord on the files with the
+// same name in the process, the file lock serializing it with the other
+// processes where supported. The lock of a name is dropped once unused.
+//
+// The files are told apart by the name returned by billy.File.Name, since
+// there is no portable identity of a billy file, so the same file opened
+// under two names, eg.: through a chroot and its underlying filesystem, isn't
+// serialized by it.
+var recordLocks = struct {
+	sync.Mutex
+	m map[string]*recordLock
+}{m: make(map[string]*recordLock)}
+
+type recordLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lockRecords takes the lock of the given file name, returning the function
+// releasing it.
+func lockRecords(name string) (unlock func()) {
+	recordLocks.Lock()
+	l, ok := recordLocks.m[name]
+	if !ok {
+		l = &recordLock{}
+		recordLocks.m[name] = l
+	}
+
+	l.refs++
+	recordLocks.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		recordLocks.Lock()
+		defer recordLocks.Unlock()
+
+		l.refs--
+		if l.refs == 0 {
+			delete(recordLocks.m, name)
+		}
+	}
+}
+
+// AppendRecord appends a record holding data at the end of f, framed with its
+// length and checksum, returning the offset where it starts, to be read with
+// ReadRecordAt. It's the building block of an append-only log over any billy
+// file.
+//
+// The record is written with a single write, holding the lock of f, and the
+// one of its name in the process, since the lock of f may be a no-op, as in
+// memfs. So the appends to a file must go through the same name, the ones
+// through another name, as a chroot of its filesystem, being serialized only
+// by the lock of f. If the write fails f is truncated back to its previous size, so a
+// failed append doesn't leave a partial record behind.
+func AppendRecord(f billy.File, data []byte) (offset int64, err error) {
+	if uint64(len(data)) > uint64(^uint32(0)) {
+		return 0, errors.New("record too large")
+	}
+
+	defer lockRecords(f.Name())()
+
+	if err := f.Lock(); err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if uerr := f.Unlock(); err == nil {
+			err = uerr
+		}
+	}()
+
+	offset, err = f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	frame := make([]byte, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	binary.BigEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(data))
+	copy(frame[recordHeaderSize:], data)
+
+	n, err := f.Write(frame)
+	if err == nil && n < len(frame) {
+		err = io.ErrShortWrite
+	}
+
+	if err != nil {
+		if n > 0 {
+			_ = f.Truncate(offset)
+		}
+
+		return 0, err
+	}
+
+	return offset, nil
+}
+
+// ReadRecordAt reads the data of the record written by AppendRecord at the
+// given offset of f. io.EOF is returned if there is no record at offset, and
+// io.ErrUnexpectedEOF if it's cut short, as a partial record at the end of a
+// log, and ErrCorruptRecord if its data don't match their checksum.
+func ReadRecordAt(f billy.File, offset int64) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	n, err := f.ReadAt(header, offset)
+	if n == len(header) {
+		err = nil
+	}
+
+	if err == io.EOF && n != 0 {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the data are read as they come, instead of allocating the length from
+	// the header up front, so a corrupt length can't allocate more than the
+	// rest of the file.
+	length := int64(binary.BigEndian.Uint32(header))
+	data, err := ioutil.ReadAll(io.NewSectionReader(f, offset+recordHeaderSize, length))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) < length {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, ErrCorruptRecord
+	}
+
+	return data, nil
+}
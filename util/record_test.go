@@ -0,0 +1,262 @@
+package util_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestRecord(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.OpenFile("log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	records := [][]byte{[]byte("foo"), {}, []byte("qux")}
+	var offsets []int64
+	for _, data := range records {
+		off, err := util.AppendRecord(f, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		offsets = append(offsets, off)
+	}
+
+	for i, off := range offsets {
+		data, err := util.ReadRecordAt(f, off)
+		if err != nil {
+			t.Fatalf("ReadRecordAt(%d): %v", off, err)
+		}
+
+		if !bytes.Equal(data, records[i]) {
+			t.Errorf("ReadRecordAt(%d) = %q, want %q", off, data, records[i])
+		}
+	}
+
+	fi, err := fs.Stat("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := util.ReadRecordAt(f, fi.Size()); err != io.EOF {
+		t.Errorf("ReadRecordAt at the end: %v, want io.EOF", err)
+	}
+}
+
+func TestRecordPartial(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.Create("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	off, err := util.AppendRecord(f, []byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int64{4, 10} {
+		if err := f.Truncate(size); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := util.ReadRecordAt(f, off); err != io.ErrUnexpectedEOF {
+			t.Errorf("ReadRecordAt cut at %d: %v, want io.ErrUnexpectedEOF", size, err)
+		}
+	}
+
+	if err := f.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+
+	off, err = util.AppendRecord(f, []byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Seek(off+8, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := util.ReadRecordAt(f, off); !errors.Is(err, util.ErrCorruptRecord) {
+		t.Errorf("ReadRecordAt corrupt: %v, want ErrCorruptRecord", err)
+	}
+}
+
+func TestRecordCorruptLength(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.Create("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	// a header claiming 4 GiB of data, followed by only 3 bytes.
+	if _, err := f.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 'f', 'o', 'o'}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := util.ReadRecordAt(f, 0); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadRecordAt corrupt length: %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestRecordConcurrent(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.Create("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	var wg sync.WaitGroup
+	offsets := make([]int64, 50)
+	for i := range offsets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			off, err := util.AppendRecord(f, bytes.Repeat([]byte{byte(i)}, i))
+			if err != nil {
+				t.Error(err)
+			}
+
+			offsets[i] = off
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, off := range offsets {
+		data, err := util.ReadRecordAt(f, off)
+		if err != nil {
+			t.Fatalf("ReadRecordAt(%d): %v", off, err)
+		}
+
+		if !bytes.Equal(data, bytes.Repeat([]byte{byte(i)}, i)) {
+			t.Errorf("record %d = %v", i, data)
+		}
+	}
+}
+
+func TestRecordConcurrentHandles(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "log", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	offsets := make([]int64, 20)
+	for i := range offsets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f, err := fs.OpenFile("log", os.O_RDWR, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			defer f.Close()
+
+			offsets[i], err = util.AppendRecord(f, bytes.Repeat([]byte{byte(i)}, i))
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	f, err := fs.Open("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	for i, off := range offsets {
+		data, err := util.ReadRecordAt(f, off)
+		if err != nil || !bytes.Equal(data, bytes.Repeat([]byte{byte(i)}, i)) {
+			t.Errorf("ReadRecordAt(%d) = %v, %v", off, data, err)
+		}
+	}
+}
+
+func TestRecordOtherFile(t *testing.T) {
+	fs := memfs.New()
+	a, err := fs.Create("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer a.Close()
+
+	b, err := fs.Create("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer b.Close()
+
+	// the append to a is blocked in Write, holding its lock, until released.
+	blocked := &blockingFile{File: a, writing: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		_, err := util.AppendRecord(blocked, []byte("foo"))
+		done <- err
+	}()
+
+	<-blocked.writing
+	other := make(chan error, 1)
+	go func() {
+		_, err := util.AppendRecord(b, []byte("bar"))
+		other <- err
+	}()
+
+	select {
+	case err := <-other:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("AppendRecord(b) blocked by the append to a")
+	}
+
+	close(blocked.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blockingFile blocks in Write until release is closed, once writing is
+// closed.
+type blockingFile struct {
+	billy.File
+	writing, release chan struct{}
+}
+
+func (f *blockingFile) Write(p []byte) (int, error) {
+	close(f.writing)
+	<-f.release
+	return f.File.Write(p)
+}
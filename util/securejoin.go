@@ -0,0 +1,87 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// maxSymlinks is the number of symlinks followed by SecureJoin before giving
+// up, as the limit on the number of links followed resolving a path on Linux.
+const maxSymlinks = 40
+
+var errTooManySymlinks = errors.New("too many levels of symbolic links")
+
+// SecureJoin joins root with the untrusted path unsafe, resolving the
+// symlinks found along it, and returns the resulting path, guaranteed to be
+// inside root. billy.ErrCrossedBoundary is returned if any ".." or symlink
+// points outside of root. The absolute symlink targets are resolved from the
+// root of the filesystem, as any other billy path.
+//
+// The path is resolved up to the first component that doesn't exist, the
+// rest is joined as is, so it can be used to create new files. The result is
+// only guaranteed as long as the symlinks aren't changed meanwhile.
+func SecureJoin(fs billy.Filesystem, root, unsafe string) (string, error) {
+	base := relToRoot(root)
+
+	var resolved []string
+	pending := splitPath(unsafe)
+	for links := 0; len(pending) > 0; {
+		elem := pending[0]
+		pending = pending[1:]
+
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return "", billy.ErrCrossedBoundary
+			}
+
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		path := filepath.Join(base, filepath.Join(resolved...), elem)
+		fi, err := fs.Lstat(path)
+		if os.IsNotExist(err) || (err == nil && fi.Mode()&os.ModeSymlink == 0) {
+			resolved = append(resolved, elem)
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if links++; links > maxSymlinks {
+			return "", &os.PathError{Op: "securejoin", Path: unsafe, Err: errTooManySymlinks}
+		}
+
+		target, err := fs.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+
+		target = filepath.FromSlash(target)
+		if isAbs(target) {
+			rel, err := filepath.Rel(base, relToRoot(target))
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return "", billy.ErrCrossedBoundary
+			}
+
+			resolved = nil
+			target = rel
+		}
+
+		pending = append(splitPath(target), pending...)
+	}
+
+	return fs.Join(append([]string{root}, resolved...)...), nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(filepath.FromSlash(path), string(filepath.Separator))
+}
@@ -0,0 +1,56 @@
+package util_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestSecureJoin(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "srv/dir/foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		"srv/rel":    "dir",
+		"srv/abs":    "/srv/dir",
+		"srv/up":     "dir/../..",
+		"srv/escape": "/etc",
+		"srv/loop":   "loop",
+	}
+
+	for link, target := range links {
+		if err := fs.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		unsafe, expected string
+		err              error
+	}{
+		{"dir/foo", "srv/dir/foo", nil},
+		{"rel/foo", "srv/dir/foo", nil},
+		{"abs/foo", "srv/dir/foo", nil},
+		{"/abs/../dir/./foo", "srv/dir/foo", nil},
+		{"dir/missing/../bar", "srv/dir/bar", nil},
+		{"", "srv", nil},
+		{"..", "", billy.ErrCrossedBoundary},
+		{"dir/../../foo", "", billy.ErrCrossedBoundary},
+		{"up/foo", "", billy.ErrCrossedBoundary},
+		{"escape/passwd", "", billy.ErrCrossedBoundary},
+	} {
+		path, err := util.SecureJoin(fs, "srv", tc.unsafe)
+		if err != tc.err || path != filepath.FromSlash(tc.expected) {
+			t.Errorf("SecureJoin(%q) = %q, %v, want %q, %v", tc.unsafe, path, err, tc.expected, tc.err)
+		}
+	}
+
+	if _, err := util.SecureJoin(fs, "srv", "loop/foo"); err == nil {
+		t.Errorf("SecureJoin(loop/foo) succeeded on a symlink loop")
+	}
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"os"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// birthTimer is implemented by the os.FileInfo, or the values returned by its
+// Sys method, able to tell the birth time of a file. A zero time means that
+// the birth time is unknown.
+type birthTimer interface {
+	BirthTime() time.Time
+}
+
+// BirthTime returns the birth time of a file, the time when it was created,
+// and true if it is known. It supports the FileInfo returned by memfs and
+// osfs, on the systems where the birth time is part of the result of stat,
+// see StatBirthTime for the others.
+func BirthTime(info os.FileInfo) (time.Time, bool) {
+	if bt, ok := info.(birthTimer); ok {
+		return knownTime(bt.BirthTime())
+	}
+
+	sys := info.Sys()
+	if bt, ok := sys.(birthTimer); ok {
+		return knownTime(bt.BirthTime())
+	}
+
+	return sysBirthTime(sys)
+}
+
+// StatBirthTime returns the birth time of the named file, following the
+// symlinks, and true if it is known. It's read with the BirthTime method of
+// fs, if implemented, as osfs does on Linux, otherwise from the FileInfo
+// returned by Stat, see BirthTime.
+func StatBirthTime(fs billy.Basic, filename string) (time.Time, bool) {
+	if b, ok := fs.(billy.BirthTime); ok {
+		btime, err := b.BirthTime(filename)
+		if err == nil {
+			return knownTime(btime)
+		}
+
+		if err != billy.ErrNotSupported {
+			return time.Time{}, false
+		}
+	}
+
+	info, err := fs.Stat(filename)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return BirthTime(info)
+}
+
+// accessTimer is implemented by the os.FileInfo, or the values returned by its
+// Sys method, able to tell the access time of a file. A zero time means that
+// the access time is unknown.
+type accessTimer interface {
+	AccessTime() time.Time
+}
+
+// StatTimes returns the access, modification and birth times of a file, from
+// its FileInfo. The times not tracked by the filesystem are returned as the
+// zero time. ok is false if the filesystem only tracks the modification time,
+// the one returned by ModTime, like the filesystems without a Sys value.
+func StatTimes(info os.FileInfo) (atime, mtime, btime time.Time, ok bool) {
+	atime, hasAtime := accessTime(info)
+	btime, hasBtime := BirthTime(info)
+	return atime, info.ModTime(), btime, hasAtime || hasBtime
+}
+
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	if at, ok := info.(accessTimer); ok {
+		return knownTime(at.AccessTime())
+	}
+
+	sys := info.Sys()
+	if at, ok := sys.(accessTimer); ok {
+		return knownTime(at.AccessTime())
+	}
+
+	return sysAccessTime(sys)
+}
+
+// linkCounter is implemented by the os.FileInfo, or the values returned by
+// its Sys method, able to tell the number of hard links to a file.
+type linkCounter interface {
+	LinkCount() uint64
+}
+
+// LinkCount returns the number of hard links to a file, and true if it is
+// known, eg.: to tell the files linked more than once when archiving them. It
+// supports the FileInfo returned by memfs and osfs, on the Unix systems.
+func LinkCount(info os.FileInfo) (uint64, bool) {
+	if lc, ok := info.(linkCounter); ok {
+		return lc.LinkCount(), true
+	}
+
+	sys := info.Sys()
+	if lc, ok := sys.(linkCounter); ok {
+		return lc.LinkCount(), true
+	}
+
+	return sysLinkCount(sys)
+}
+
+func knownTime(t time.Time) (time.Time, bool) {
+	return t, !t.IsZero()
+}
@@ -0,0 +1,35 @@
+// +build darwin freebsd netbsd
+
+package util
+
+import (
+	"syscall"
+	"time"
+)
+
+func sysBirthTime(sys interface{}) (time.Time, bool) {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(st.Birthtimespec.Unix()), true
+}
+
+func sysAccessTime(sys interface{}) (time.Time, bool) {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(st.Atimespec.Unix()), true
+}
+
+func sysLinkCount(sys interface{}) (uint64, bool) {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(st.Nlink), true
+}
@@ -0,0 +1,32 @@
+// +build linux
+
+package util
+
+import (
+	"syscall"
+	"time"
+)
+
+// sysBirthTime always fails on Linux, since the birth time isn't part of the
+// result of stat, osfs reads it with statx instead, see StatBirthTime.
+func sysBirthTime(sys interface{}) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func sysAccessTime(sys interface{}) (time.Time, bool) {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(st.Atim.Unix()), true
+}
+
+func sysLinkCount(sys interface{}) (uint64, bool) {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(st.Nlink), true
+}
@@ -0,0 +1,17 @@
+// +build !darwin,!freebsd,!linux,!netbsd,!windows
+
+package util
+
+import "time"
+
+func sysBirthTime(sys interface{}) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func sysAccessTime(sys interface{}) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func sysLinkCount(sys interface{}) (uint64, bool) {
+	return 0, false
+}
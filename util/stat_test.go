@@ -0,0 +1,238 @@
+package util_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestBirthTime(t *testing.T) {
+	fs := memfs.New()
+	before := time.Now()
+	if err := util.WriteFile(fs, "foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	btime := assertBirthTime(t, fs, "foo", before)
+
+	if err := fs.Rename("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := fs.Stat("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renamed, _ := util.BirthTime(fi); !renamed.Equal(btime) {
+		t.Errorf("BirthTime(bar) = %v, want %v", renamed, btime)
+	}
+}
+
+func TestBirthTimeOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "billy-birth-time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := osfs.New(dir)
+
+	// some filesystems only keep the time with a one second resolution.
+	before := time.Now().Truncate(time.Second)
+	if err := util.WriteFile(fs, "foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := util.StatBirthTime(fs, "foo"); !ok {
+		t.Skip("birth time not supported")
+	}
+
+	assertBirthTime(t, fs, "foo", before)
+}
+
+func assertBirthTime(t *testing.T, fs billy.Filesystem, filename string, before time.Time) time.Time {
+	t.Helper()
+
+	btime, ok := util.StatBirthTime(fs, filename)
+	if !ok || btime.Before(before) || btime.After(time.Now()) {
+		t.Errorf("BirthTime(%s) = %v, %v, want a time after %v", filename, btime, ok, before)
+	}
+
+	return btime
+}
+
+func TestSameFileOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "billy-same-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := osfs.New(dir)
+	for _, filename := range []string{"foo", "bar"} {
+		if err := util.WriteFile(fs, filename, []byte("foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fs.Symlink("foo", "qux"); err != nil {
+		t.Fatal(err)
+	}
+
+	foo, err := fs.Stat("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		filename string
+		stat     func(string) (os.FileInfo, error)
+		want     bool
+	}{
+		{"foo", fs.Stat, true},
+		{"foo", fs.Lstat, true},
+		{"qux", fs.Stat, true},
+		{"qux", fs.Lstat, false},
+		{"bar", fs.Stat, false},
+	} {
+		fi, err := tc.stat(tc.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := os.SameFile(foo, fi); got != tc.want {
+			t.Errorf("os.SameFile(foo, %s) = %v, want %v", tc.filename, got, tc.want)
+		}
+	}
+
+	fi, err := os.Stat(filepath.Join(dir, "foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !os.SameFile(foo, fi) {
+		t.Errorf("os.SameFile(foo, %s) = false, want true", fi.Name())
+	}
+}
+
+func TestStatTimes(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := fs.Stat("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atime, mtime, btime, ok := util.StatTimes(fi)
+	if !ok || !atime.IsZero() || mtime.IsZero() || btime.IsZero() {
+		t.Errorf("StatTimes() = %v, %v, %v, %v", atime, mtime, btime, ok)
+	}
+}
+
+func TestStatTimesOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "billy-stat-times")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := osfs.New(dir)
+	if err := util.WriteFile(fs, "foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := fs.Stat("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atime, mtime, _, ok := util.StatTimes(fi)
+	if !ok {
+		t.Skip("times not supported")
+	}
+
+	if atime.IsZero() || !mtime.Equal(fi.ModTime()) {
+		t.Errorf("StatTimes() = %v, %v, _, %v", atime, mtime, ok)
+	}
+}
+
+func TestStatTimesUnknown(t *testing.T) {
+	fi := &timeInfo{mtime: time.Unix(42, 0)}
+
+	atime, mtime, btime, ok := util.StatTimes(fi)
+	if ok || !atime.IsZero() || !mtime.Equal(fi.mtime) || !btime.IsZero() {
+		t.Errorf("StatTimes() = %v, %v, %v, %v", atime, mtime, btime, ok)
+	}
+}
+
+// timeInfo is an os.FileInfo only tracking the modification time.
+type timeInfo struct {
+	os.FileInfo
+	mtime time.Time
+}
+
+func (fi *timeInfo) ModTime() time.Time {
+	return fi.mtime
+}
+
+func (fi *timeInfo) Sys() interface{} {
+	return nil
+}
+
+func TestLinkCount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "billy-link-count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, fs := range []billy.Filesystem{memfs.New(), osfs.New(dir)} {
+		if err := util.WriteFile(fs, "foo", []byte("foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		assertLinkCount(t, fs, "foo", 1)
+
+		if err := fs.(billy.Link).Link("foo", "bar"); err != nil {
+			t.Fatal(err)
+		}
+
+		assertLinkCount(t, fs, "foo", 2)
+		assertLinkCount(t, fs, "bar", 2)
+
+		if err := fs.Remove("foo"); err != nil {
+			t.Fatal(err)
+		}
+
+		assertLinkCount(t, fs, "bar", 1)
+	}
+}
+
+func assertLinkCount(t *testing.T, fs billy.Filesystem, filename string, want uint64) {
+	t.Helper()
+
+	fi, err := fs.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, ok := util.LinkCount(fi)
+	if !ok {
+		t.Skip("link count not supported")
+	}
+
+	if n != want {
+		t.Errorf("LinkCount(%s) = %d, want %d", filename, n, want)
+	}
+}
@@ -0,0 +1,32 @@
+// +build windows
+
+package util
+
+import (
+	"syscall"
+	"time"
+)
+
+func sysBirthTime(sys interface{}) (time.Time, bool) {
+	d, ok := sys.(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, d.CreationTime.Nanoseconds()), true
+}
+
+func sysAccessTime(sys interface{}) (time.Time, bool) {
+	d, ok := sys.(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, d.LastAccessTime.Nanoseconds()), true
+}
+
+// sysLinkCount always fails on Windows, since the number of links isn't part
+// of the file attributes.
+func sysLinkCount(sys interface{}) (uint64, bool) {
+	return 0, false
+}
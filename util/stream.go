@@ -0,0 +1,144 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// StreamReader reads a stream that can only be read sequentially, as the
+// content of a compressed entry in an archive, supporting Seek and ReadAt on
+// it. Seeking forward discards the content up to the new position, seeking
+// backward reopens the stream and reads it again from the start, so backward
+// seeks are expensive.
+//
+// It is safe for concurrent use, the calls are serialized.
+type StreamReader struct {
+	open func() (io.ReadCloser, error)
+	size int64
+
+	m sync.Mutex
+	// rc is the stream currently open, if any, at offset off.
+	rc  io.ReadCloser
+	off int64
+	// pos is the position of Read and Seek.
+	pos int64
+}
+
+// NewStreamReader returns a StreamReader over the stream returned by open,
+// which is called every time the stream needs to be read from the start. The
+// size is the length of the decoded stream, required to seek from the end.
+func NewStreamReader(open func() (io.ReadCloser, error), size int64) *StreamReader {
+	return &StreamReader{open: open, size: size}
+}
+
+// Read implements the io.Reader interface.
+func (r *StreamReader) Read(p []byte) (int, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if err := r.seekStream(r.pos); err != nil {
+		return 0, err
+	}
+
+	n, err := r.rc.Read(p)
+	r.off += int64(n)
+	r.pos += int64(n)
+	return n, err
+}
+
+// ReadAt implements the io.ReaderAt interface, the position of Read isn't
+// changed.
+func (r *StreamReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if err := r.seekStream(off); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(r.rc, p)
+	r.off += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// Seek implements the io.Seeker interface, the stream is only read on the
+// next Read.
+func (r *StreamReader) Seek(offset int64, whence int) (int64, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return r.pos, os.ErrInvalid
+	}
+
+	if pos < 0 {
+		return r.pos, errors.New("negative position")
+	}
+
+	r.pos = pos
+	return pos, nil
+}
+
+// Close closes the stream currently open, if any.
+func (r *StreamReader) Close() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.closeStream()
+}
+
+// seekStream moves the stream to the given offset, reopening it if the offset
+// was already read.
+func (r *StreamReader) seekStream(off int64) error {
+	if r.rc != nil && off < r.off {
+		if err := r.closeStream(); err != nil {
+			return err
+		}
+	}
+
+	if r.rc == nil {
+		rc, err := r.open()
+		if err != nil {
+			return err
+		}
+
+		r.rc, r.off = rc, 0
+	}
+
+	n, err := io.CopyN(ioutil.Discard, r.rc, off-r.off)
+	r.off += n
+	if err == io.EOF {
+		return nil
+	}
+
+	return err
+}
+
+func (r *StreamReader) closeStream() error {
+	if r.rc == nil {
+		return nil
+	}
+
+	err := r.rc.Close()
+	r.rc = nil
+	return err
+}
@@ -0,0 +1,66 @@
+package util_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestStreamReader(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	content = append(content, "trailer"...)
+
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var opens int
+	r := util.NewStreamReader(func() (io.ReadCloser, error) {
+		opens++
+		return gzip.NewReader(bytes.NewReader(compressed.Bytes()))
+	}, int64(len(content)))
+	defer r.Close()
+
+	pos, err := r.Seek(-7, io.SeekEnd)
+	if err != nil || pos != int64(len(content)-7) {
+		t.Fatalf("Seek(-7, io.SeekEnd) = %d, %v, want %d", pos, err, len(content)-7)
+	}
+
+	trailer, err := ioutil.ReadAll(r)
+	if err != nil || string(trailer) != "trailer" {
+		t.Fatalf("ReadAll() = %q, %v, want %q", trailer, err, "trailer")
+	}
+
+	buf := make([]byte, 4)
+	n, err := r.ReadAt(buf, 12)
+	if err != nil || string(buf[:n]) != "2345" {
+		t.Errorf("ReadAt(12) = %q, %v, want %q", buf[:n], err, "2345")
+	}
+
+	n, err = r.ReadAt(buf, int64(len(content)-2))
+	if err != io.EOF || string(buf[:n]) != "er" {
+		t.Errorf("ReadAt(len-2) = %q, %v, want %q, io.EOF", buf[:n], err, "er")
+	}
+
+	if opens != 2 {
+		t.Errorf("stream opened %d times, want 2", opens)
+	}
+
+	if pos, _ := r.Seek(0, io.SeekCurrent); pos != int64(len(content)) {
+		t.Errorf("position = %d, want %d", pos, len(content))
+	}
+
+	if _, err := r.Seek(0, 99); err == nil {
+		t.Errorf("Seek with an invalid whence succeeded")
+	}
+}
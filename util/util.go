@@ -1,16 +1,23 @@
 package util
 
 import (
+	"bytes"
+	"errors"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-git/go-billy/v5"
 )
 
+// ErrTooLarge is returned by ReadAtMost when the file exceeds the size given.
+var ErrTooLarge = errors.New("file too large")
+
 // RemoveAll removes path and any children it contains. It removes everything it
 // can but returns the first error it encounters. If the path does not exist,
 // RemoveAll returns nil (no error).
@@ -114,6 +121,332 @@ func WriteFile(fs billy.Basic, filename string, data []byte, perm os.FileMode) e
 	return err
 }
 
+// WriteString writes the string content to a file named by filename in the
+// given filesystem, the same way WriteFile does.
+func WriteString(fs billy.Basic, filename, content string, perm os.FileMode) error {
+	return WriteFile(fs, filename, []byte(content), perm)
+}
+
+// WriteFileIfChanged writes data to the named file, as WriteFile does, only if
+// its content differs, so the modification time is kept and the watchers are
+// not triggered on no-op writes. It returns true if the file was written,
+// including when it didn't exist.
+func WriteFileIfChanged(fs billy.Filesystem, filename string, data []byte, perm os.FileMode) (bool, error) {
+	same, err := hasContent(fs, filename, data)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	if same {
+		return false, nil
+	}
+
+	if err := WriteFile(fs, filename, data, perm); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// hasContent returns true if the content of the named file is data.
+func hasContent(fs billy.Filesystem, filename string, data []byte) (bool, error) {
+	fi, err := fs.Stat(filename)
+	if err != nil {
+		return false, err
+	}
+
+	if fi.Mode().IsRegular() && fi.Size() != int64(len(data)) {
+		return false, nil
+	}
+
+	content, err := ReadAtMost(fs, filename, int64(len(data)))
+	if err == ErrTooLarge {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(content, data), nil
+}
+
+// fileReader is implemented by the filesystems reading a whole file their own
+// way, eg.: coalescing the concurrent reads of the same file, as
+// singleflightfs does.
+type fileReader interface {
+	ReadFile(filename string) ([]byte, error)
+}
+
+// ReadFile reads the file named by filename in the given filesystem and
+// returns its content, as ioutil.ReadFile. The error reading the file is
+// returned over the one closing it. The ReadFile method of fs is used if
+// implemented.
+func ReadFile(fs billy.Basic, filename string) ([]byte, error) {
+	if r, ok := fs.(fileReader); ok {
+		return r.ReadFile(filename)
+	}
+
+	f, err := fs.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if fi, err := fs.Stat(filename); err == nil && fi.Mode().IsRegular() {
+		buf.Grow(int(fi.Size()) + bytes.MinRead)
+	}
+
+	_, err = buf.ReadFrom(f)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ReadFileString reads the file named by filename in the given filesystem
+// and returns its content as a string.
+func ReadFileString(fs billy.Basic, filename string) (string, error) {
+	content, err := ReadFile(fs, filename)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// ReadAtMost reads the file named by filename in the given filesystem, as
+// ReadFileString, up to max bytes. If the file is larger, ErrTooLarge is
+// returned without reading it when Stat reports its size, or once read past
+// max otherwise, so untrusted files can be read without exhausting the memory.
+func ReadAtMost(fs billy.Filesystem, filename string, max int64) ([]byte, error) {
+	if fi, err := fs.Stat(filename); err == nil && fi.Mode().IsRegular() && fi.Size() > max {
+		return nil, ErrTooLarge
+	}
+
+	f, err := fs.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(f, max+1))
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(content)) > max {
+		return nil, ErrTooLarge
+	}
+
+	return content, nil
+}
+
+// maxReadStatAttempts is the number of times ReadFileStat reads a file being
+// changed meanwhile, before giving up.
+const maxReadStatAttempts = 5
+
+var errChangedWhileReading = errors.New("file changed while being read")
+
+// ReadFileStat reads the named file and returns its content along with its
+// FileInfo, as a consistent snapshot, eg.: to validate a cache with the size
+// or the modification time of the content read.
+//
+// The files able to read their content along with their FileInfo at once,
+// like the ones from memfs, are read that way. Otherwise both come from the
+// same opened file, stated before and after reading it, and the file is read
+// again if its size or its modification time changed meanwhile, which can't
+// tell the writes keeping both unchanged. If the file can't be stated itself,
+// the FileInfo is the one returned by fs.Stat once the file is read.
+func ReadFileStat(fs billy.Basic, filename string) ([]byte, os.FileInfo, error) {
+	f, err := fs.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer f.Close()
+
+	if rs, ok := unwrapFile(f).(readStatFile); ok {
+		return rs.ReadStat()
+	}
+
+	sf, ok := unwrapFile(f).(statFile)
+	if !ok {
+		content, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fi, err := fs.Stat(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return content, fi, nil
+	}
+
+	for i := 0; i < maxReadStatAttempts; i++ {
+		before, err := sf.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+
+		content, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		after, err := sf.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if int64(len(content)) == after.Size() && before.Size() == after.Size() &&
+			before.ModTime().Equal(after.ModTime()) {
+			return content, after, nil
+		}
+	}
+
+	return nil, nil, &os.PathError{Op: "read", Path: filename, Err: errChangedWhileReading}
+}
+
+// statFile is implemented by the files able to stat themselves, like the ones
+// from memfs and osfs.
+type statFile interface {
+	Stat() (os.FileInfo, error)
+}
+
+// readStatFile is implemented by the files able to read their whole content
+// along with their FileInfo at once, like the ones from memfs.
+type readStatFile interface {
+	ReadStat() ([]byte, os.FileInfo, error)
+}
+
+// flagsFile is implemented by the files able to tell the flags used to open
+// them, like the ones from memfs and osfs.
+type flagsFile interface {
+	Flags() int
+}
+
+// Reopen closes f and opens again the file with its name, eg.: to follow a
+// file replaced by another one, like a rotated log. The file is opened with
+// the same flags used to open f, but os.O_CREATE, os.O_EXCL and os.O_TRUNC,
+// so the file must exist and its content is kept. The new file is positioned
+// at its start, or at its end if opened with os.O_APPEND.
+//
+// If the flags of f can't be known, billy.ErrNotSupported is returned and f is
+// not closed.
+func Reopen(fs billy.Basic, f billy.File) (billy.File, error) {
+	ff, ok := unwrapFile(f).(flagsFile)
+	if !ok {
+		return nil, billy.ErrNotSupported
+	}
+
+	flag := ff.Flags() &^ (os.O_CREATE | os.O_EXCL | os.O_TRUNC)
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return fs.OpenFile(f.Name(), flag, 0)
+}
+
+// OpenFileHint opens the named file as fs.OpenFile does, advising fs of how
+// it is going to be accessed if it implements billy.OpenFileHint, otherwise
+// the hint is ignored.
+func OpenFileHint(fs billy.Basic, filename string, flag int, perm os.FileMode, hint billy.AccessHint) (billy.File, error) {
+	if h, ok := fs.(billy.OpenFileHint); ok {
+		f, err := h.OpenFileHint(filename, flag, perm, hint)
+		if err != billy.ErrNotSupported {
+			return f, err
+		}
+	}
+
+	return fs.OpenFile(filename, flag, perm)
+}
+
+// WriteBuffers writes the content of bufs, in order, to f. If f implements
+// billy.BuffersWriter the buffers are written in a single operation, otherwise
+// they are written one by one with Write.
+func WriteBuffers(f billy.File, bufs [][]byte) (int64, error) {
+	if bw, ok := unwrapFile(f).(billy.BuffersWriter); ok {
+		return bw.WriteBuffers(bufs)
+	}
+
+	var written int64
+	for _, b := range bufs {
+		n, err := f.Write(b)
+		written += int64(n)
+		if err == nil && n < len(b) {
+			err = io.ErrShortWrite
+		}
+
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// CheckWritable checks if files can be written in the given directory, by
+// creating a temporary file in it, writing a byte and removing it. The error
+// found on the first failing step is returned. The temporary file is removed
+// even if writing to it fails.
+func CheckWritable(fs billy.Filesystem, dir string) (err error) {
+	f, err := fs.TempFile(dir, ".billy-writable")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if rerr := fs.Remove(f.Name()); err == nil {
+			err = rerr
+		}
+	}()
+
+	_, err = f.Write([]byte{0})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// SupportsSymlinks returns true if symlinks can be created in the given
+// filesystem, probing it by creating one at its root, pointing to a missing
+// target, and removing it. So it returns false if the root is not writable,
+// or, eg.: for osfs on Windows, if the process lacks the privilege to create
+// symlinks. It can be used to copy files, instead of linking them, beforehand.
+func SupportsSymlinks(fs billy.Filesystem) bool {
+	for try := 0; try < 10; try++ {
+		link := ".billy-symlink" + nextSuffix()
+		err := fs.Symlink(link+".target", link)
+		if os.IsExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return false
+		}
+
+		return fs.Remove(link) == nil
+	}
+
+	return false
+}
+
 // Random number state.
 // We generate random temporary file names so that there's a good
 // chance the file doesn't exist yet - keeps the number of tries in
@@ -145,6 +478,18 @@ func nextSuffix() string {
 // f.Name() to find the pathname of the file. It is the caller's responsibility
 // to remove the file when no longer needed.
 func TempFile(fs billy.Basic, dir, prefix string) (f billy.File, err error) {
+	return tempFile(fs, dir, prefix, "")
+}
+
+// TempFileSuffix creates a new temporary file in the directory dir with a name
+// beginning with prefix and ending with suffix, opens the file for reading and
+// writing, and returns the resulting file. It behaves as TempFile regarding the
+// dir and the uniqueness of the name.
+func TempFileSuffix(fs billy.Basic, dir, prefix, suffix string) (f billy.File, err error) {
+	return tempFile(fs, dir, prefix, suffix)
+}
+
+func tempFile(fs billy.Basic, dir, prefix, suffix string) (f billy.File, err error) {
 	// This implementation is based on stdlib ioutil.TempFile.
 
 	if dir == "" {
@@ -153,7 +498,7 @@ func TempFile(fs billy.Basic, dir, prefix string) (f billy.File, err error) {
 
 	nconflict := 0
 	for i := 0; i < 10000; i++ {
-		name := filepath.Join(dir, prefix+nextSuffix())
+		name := filepath.Join(dir, prefix+nextSuffix()+suffix)
 		f, err = fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
 		if os.IsExist(err) {
 			if nconflict++; nconflict > 10 {
@@ -207,10 +552,117 @@ func TempDir(fs billy.Dir, dir, prefix string) (name string, err error) {
 	return
 }
 
+// ReadDirDots reads the directory named by path, as fs.ReadDir does, and
+// returns its entries after the synthesized "." and ".." ones, describing the
+// directory itself and its parent, eg.: to port code expecting them as in the
+// listings of getdents. The ".." entry of the root describes the root.
+func ReadDirDots(fs billy.Filesystem, path string) ([]os.FileInfo, error) {
+	path = filepath.Clean(path)
+	dot, err := fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dotdot, err := fs.Stat(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]os.FileInfo{
+		&namedFileInfo{FileInfo: dot, name: "."},
+		&namedFileInfo{FileInfo: dotdot, name: ".."},
+	}, entries...), nil
+}
+
+// namedFileInfo is an os.FileInfo with a different name.
+type namedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi *namedFileInfo) Name() string {
+	return fi.name
+}
+
+// ReadlinkAbs returns the target of the given link, resolved relative to the
+// directory containing the link, as a clean path relative to the root of the
+// filesystem. If the target points outside of the root of the filesystem,
+// billy.ErrCrossedBoundary is returned.
+func ReadlinkAbs(fs billy.Filesystem, link string) (string, error) {
+	target, err := fs.Readlink(link)
+	if err != nil {
+		return "", err
+	}
+
+	target = filepath.FromSlash(target)
+	if !isAbs(target) {
+		target = filepath.Join(filepath.Dir(relToRoot(link)), target)
+	}
+
+	target = relToRoot(target)
+	if target == ".." || strings.HasPrefix(target, ".."+string(filepath.Separator)) {
+		return "", billy.ErrCrossedBoundary
+	}
+
+	return target, nil
+}
+
+// relToRoot returns the given path cleaned and relative to the root of the
+// filesystem, without any leading separator.
+func relToRoot(path string) string {
+	path = filepath.Clean(filepath.FromSlash(path))
+	if isAbs(path) {
+		path = strings.TrimPrefix(path, filepath.VolumeName(path))
+		path = strings.TrimLeft(path, string(filepath.Separator))
+		if path == "" {
+			path = "."
+		}
+	}
+
+	return path
+}
+
+// isAbs returns true if the path is absolute, or if it starts with a
+// separator, since both mean the root of a billy filesystem.
+func isAbs(path string) bool {
+	return filepath.IsAbs(path) || strings.HasPrefix(path, string(filepath.Separator))
+}
+
 type underlying interface {
 	Underlying() billy.Basic
 }
 
+// inMemory is implemented by the filesystems telling if they hold their
+// content in memory, like the one from memfs.
+type inMemory interface {
+	InMemory() bool
+}
+
+// IsInMemory returns true if the content of the filesystem is held in memory,
+// as with memfs, so the I/O is cheap, eg.: to skip buffering the reads. The
+// wrappers are looked through with their Underlying method, any other
+// filesystem is considered on disk. A filesystem made of several ones, as an
+// union, can implement InMemory to tell if all of them are in memory.
+func IsInMemory(fs billy.Basic) bool {
+	for {
+		if m, ok := fs.(inMemory); ok {
+			return m.InMemory()
+		}
+
+		u, ok := fs.(underlying)
+		if !ok {
+			return false
+		}
+
+		fs = u.Underlying()
+	}
+}
+
 func getUnderlyingAndPath(fs billy.Basic, path string) (billy.Basic, string) {
 	u, ok := fs.(underlying)
 	if !ok {
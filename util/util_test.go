@@ -1,12 +1,19 @@
 package util_test
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"testing"
 
+	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-billy/v5/util"
 )
 
@@ -41,3 +48,479 @@ func TestTempDir(t *testing.T) {
 		}
 	}
 }
+
+func TestReadlinkAbs(t *testing.T) {
+	fs := memfs.New()
+
+	links := []struct {
+		target, link, expected string
+	}{
+		{"bar", "foo/link", filepath.Join("foo", "bar")},
+		{"../bar", "foo/qux/link", filepath.Join("foo", "bar")},
+		{"./bar/../baz", "foo/link2", filepath.Join("foo", "baz")},
+		{"/bar", "foo/link3", "bar"},
+		{"..", "link", ""},
+		{"../../bar", "foo/link4", ""},
+	}
+
+	for _, l := range links {
+		if err := fs.Symlink(l.target, l.link); err != nil {
+			t.Fatal(err)
+		}
+
+		target, err := util.ReadlinkAbs(fs, l.link)
+		if l.expected == "" {
+			if err != billy.ErrCrossedBoundary {
+				t.Errorf("ReadlinkAbs(%q) = %q, %v, want ErrCrossedBoundary", l.link, target, err)
+			}
+
+			continue
+		}
+
+		if err != nil || target != l.expected {
+			t.Errorf("ReadlinkAbs(%q) = %q, %v, want %q", l.link, target, err, l.expected)
+		}
+	}
+
+	if _, err := util.ReadlinkAbs(fs, "missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadlinkAbs(missing) = %v, want not exist", err)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	fs := memfs.New()
+	if err := fs.MkdirAll("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.CheckWritable(fs, "foo"); err != nil {
+		t.Errorf("CheckWritable(foo) = %v, want nil", err)
+	}
+
+	fis, err := fs.ReadDir("foo")
+	if err != nil || len(fis) != 0 {
+		t.Errorf("ReadDir(foo) = %v, %v, want no entries", fis, err)
+	}
+}
+
+func TestCheckWritableFailedWrite(t *testing.T) {
+	fs := &failingWriteFS{Filesystem: memfs.New()}
+	if err := util.CheckWritable(fs, "foo"); err != errWriteFailed {
+		t.Errorf("CheckWritable(foo) = %v, want %v", err, errWriteFailed)
+	}
+
+	fis, err := fs.ReadDir("foo")
+	if err != nil || len(fis) != 0 {
+		t.Errorf("ReadDir(foo) = %v, %v, want no entries", fis, err)
+	}
+}
+
+var errWriteFailed = errors.New("write failed")
+
+type failingWriteFS struct {
+	billy.Filesystem
+}
+
+func (fs *failingWriteFS) TempFile(dir, prefix string) (billy.File, error) {
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+	return &failingWriteFile{File: f}, err
+}
+
+type failingWriteFile struct {
+	billy.File
+}
+
+func (f *failingWriteFile) Write(p []byte) (int, error) {
+	return 0, errWriteFailed
+}
+
+func TestReadFileString(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteString(fs, "foo/bar", "bar", 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := util.ReadFileString(fs, "foo/bar")
+	if content != "bar" || err != nil {
+		t.Errorf("ReadFileString(foo/bar) = %q, %v, want %q, nil", content, err, "bar")
+	}
+
+	_, err = util.ReadFileString(fs, "missing")
+	if !os.IsNotExist(err) {
+		t.Errorf("ReadFileString(missing) = _, %v, want a not exist error", err)
+	}
+}
+
+func TestSupportsSymlinks(t *testing.T) {
+	fs := memfs.New()
+	if !util.SupportsSymlinks(fs) {
+		t.Errorf("SupportsSymlinks(memfs) = false, want true")
+	}
+
+	if util.SupportsSymlinks(&noSymlinkFS{fs}) {
+		t.Errorf("SupportsSymlinks(noSymlinkFS) = true, want false")
+	}
+
+	fis, err := fs.ReadDir("/")
+	if err != nil || len(fis) != 0 {
+		t.Errorf("ReadDir(/) = %d entries, %v, want the probe removed", len(fis), err)
+	}
+}
+
+// noSymlinkFS fails to create symlinks.
+type noSymlinkFS struct {
+	billy.Filesystem
+}
+
+func (fs *noSymlinkFS) Symlink(string, string) error {
+	return billy.ErrNotSupported
+}
+
+func TestWriteFileIfChanged(t *testing.T) {
+	fs := memfs.New()
+
+	for _, tc := range []struct {
+		content string
+		changed bool
+	}{
+		{"foo", true},
+		{"foo", false},
+		{"bar", true},
+		{"", true},
+		{"", false},
+	} {
+		changed, err := util.WriteFileIfChanged(fs, "foo/bar", []byte(tc.content), 0644)
+		if changed != tc.changed || err != nil {
+			t.Errorf("WriteFileIfChanged(%q) = %v, %v, want %v, nil", tc.content, changed, err, tc.changed)
+		}
+
+		assertFile(t, fs, "foo/bar", tc.content)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteString(fs, "foo", "foo", 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fs := range []billy.Filesystem{fs, &noStatFS{fs}} {
+		content, err := util.ReadFile(fs, "foo")
+		if string(content) != "foo" || err != nil {
+			t.Errorf("ReadFile(foo) = %q, %v, want %q, nil", content, err, "foo")
+		}
+	}
+
+	if _, err := util.ReadFile(fs, "missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile(missing) = _, %v, want not exist", err)
+	}
+}
+
+func TestReadDirDots(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteString(fs, "foo/bar/baz", "baz", 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string][]string{
+		"foo/bar": {".", "..", "baz"},
+		"foo":     {".", "..", "bar"},
+		"/":       {".", "..", "foo"},
+	} {
+		entries, err := util.ReadDirDots(fs, path)
+		if err != nil {
+			t.Fatalf("ReadDirDots(%s): %v", path, err)
+		}
+
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("ReadDirDots(%s) = %v, want %v", path, names, want)
+		}
+
+		for _, e := range entries[:2] {
+			if !e.IsDir() || !e.Mode().IsDir() {
+				t.Errorf("ReadDirDots(%s): %s is not a directory", path, e.Name())
+			}
+		}
+	}
+
+	if _, err := util.ReadDirDots(fs, "missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadDirDots(missing) = _, %v, want not exist", err)
+	}
+}
+
+func TestOpenFileHint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "util_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, fs := range []billy.Filesystem{memfs.New(), osfs.New(dir)} {
+		if err := util.WriteString(fs, "foo", "foo", 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := util.OpenFileHint(fs, "foo", os.O_RDONLY, 0, billy.SequentialHint)
+		if err != nil {
+			t.Fatalf("OpenFileHint(foo): %v", err)
+		}
+
+		content, err := ioutil.ReadAll(f)
+		if string(content) != "foo" || err != nil {
+			t.Errorf("ReadAll(foo) = %q, %v, want %q, nil", content, err, "foo")
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = util.OpenFileHint(fs, "missing", os.O_RDONLY, 0, billy.RandomHint)
+		if !os.IsNotExist(err) {
+			t.Errorf("OpenFileHint(missing) = _, %v, want not exist", err)
+		}
+	}
+}
+
+func TestReadAtMost(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteString(fs, "foo", "foo", 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fs := range []billy.Filesystem{fs, &noStatFS{fs}} {
+		content, err := util.ReadAtMost(fs, "foo", 3)
+		if string(content) != "foo" || err != nil {
+			t.Errorf("ReadAtMost(foo, 3) = %q, %v, want %q, nil", content, err, "foo")
+		}
+
+		_, err = util.ReadAtMost(fs, "foo", 2)
+		if err != util.ErrTooLarge {
+			t.Errorf("ReadAtMost(foo, 2) = _, %v, want ErrTooLarge", err)
+		}
+	}
+}
+
+func TestReadFileStat(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteString(fs, "foo/bar", "bar", 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := fs.Stat("foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fs := range []billy.Filesystem{fs, &plainFileFS{fs}} {
+		content, fi, err := util.ReadFileStat(fs, "foo/bar")
+		if string(content) != "bar" || err != nil {
+			t.Fatalf("ReadFileStat(foo/bar) = %q, _, %v, want %q, _, nil", content, err, "bar")
+		}
+
+		if fi.Name() != "bar" || fi.Size() != 3 || !fi.ModTime().Equal(want.ModTime()) {
+			t.Errorf("ReadFileStat(foo/bar) FileInfo = %s, %d, %s, want bar, 3, %s",
+				fi.Name(), fi.Size(), fi.ModTime(), want.ModTime())
+		}
+	}
+
+	if _, _, err := util.ReadFileStat(fs, "missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadFileStat(missing) = _, _, %v, want not exist", err)
+	}
+}
+
+func TestReadFileStatWhileWritten(t *testing.T) {
+	const rounds = 200
+
+	fs := memfs.New()
+	if err := util.WriteString(fs, "foo", "000", 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.OpenFile("foo", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// written holds the content written by modification time, the times
+	// shared by several writes being left empty.
+	written := make(map[int64]string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < rounds; i++ {
+			content := fmt.Sprintf("%03d", i)
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if _, err := f.Write([]byte(content)); err != nil {
+				t.Error(err)
+				return
+			}
+
+			fi, err := fs.Stat("foo")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mtime := fi.ModTime().UnixNano()
+			if _, ok := written[mtime]; ok {
+				content = ""
+			}
+
+			written[mtime] = content
+		}
+	}()
+
+	type read struct {
+		content string
+		mtime   int64
+	}
+
+	var reads []read
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+
+		content, fi, err := util.ReadFileStat(fs, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reads = append(reads, read{string(content), fi.ModTime().UnixNano()})
+	}
+
+	for _, r := range reads {
+		if want := written[r.mtime]; want != "" && r.content != want {
+			t.Errorf("ReadFileStat(foo) = %q, with the time of %q", r.content, want)
+		}
+	}
+}
+
+// plainFileFS opens the files as plainFile, hiding their optional interfaces.
+type plainFileFS struct {
+	billy.Filesystem
+}
+
+func (fs *plainFileFS) Open(filename string) (billy.File, error) {
+	f, err := fs.Filesystem.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return plainFile{f}, nil
+}
+
+func TestIsInMemory(t *testing.T) {
+	fs := memfs.New()
+	chroot, err := fs.Chroot("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		fs   billy.Basic
+		want bool
+	}{
+		{"memfs", fs, true},
+		{"memfs chroot", chroot, true},
+		{"osfs", osfs.New(os.TempDir()), false},
+		{"opaque wrapper", &noStatFS{fs}, false},
+	} {
+		if got := util.IsInMemory(tc.fs); got != tc.want {
+			t.Errorf("IsInMemory(%s) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// noStatFS fails on Stat, so the size of the files is unknown.
+type noStatFS struct {
+	billy.Filesystem
+}
+
+func (fs *noStatFS) Stat(string) (os.FileInfo, error) {
+	return nil, billy.ErrNotSupported
+}
+
+func TestReopen(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteString(fs, "foo.log", "foo", 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.OpenFile("foo.log", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Rename("foo.log", "foo.log.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := util.WriteString(fs, "foo.log", "bar", 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = util.Reopen(fs, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
+	if string(content) != "bar" || err != nil {
+		t.Errorf("ReadAll() = %q, %v, want %q, nil", content, err, "bar")
+	}
+}
+
+func TestReopenKeepsContent(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.OpenFile("foo", os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = util.Reopen(fs, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, fs, "foo", "foobar")
+}
+
+func TestReopenNotSupported(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := util.Reopen(fs, plainFile{f}); err != billy.ErrNotSupported {
+		t.Errorf("Reopen() = _, %v, want %v", err, billy.ErrNotSupported)
+	}
+}
@@ -0,0 +1,139 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// ErrWriteVerifyFailed is returned by the files from NewVerifyingFile when the
+// bytes read back differ from the ones written.
+var ErrWriteVerifyFailed = errors.New("write verification failed")
+
+// NewVerifyingFile returns a file wrapping f which verifies the bytes written,
+// reading them back with ReadAt on Sync and Close and comparing them to a copy
+// kept in memory, eg.: to detect the silent corruption of a flaky storage. A
+// mismatch fails with an *os.PathError wrapping ErrWriteVerifyFailed. f must
+// be opened for reading too.
+//
+// It's opt-in paranoia with a cost: every byte written is read again,
+// doubling the I/O, and the bytes written are held in memory until verified.
+func NewVerifyingFile(f billy.File) billy.File {
+	return &verifyingFile{File: f}
+}
+
+type verifyingFile struct {
+	billy.File
+	// written are the ranges written since the last verification, without
+	// overlaps, the later writes replacing the earlier ones.
+	written []extent
+}
+
+type extent struct {
+	off  int64
+	data []byte
+}
+
+func (f *verifyingFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if n == 0 {
+		return n, err
+	}
+
+	// the offset is taken after writing, to support os.O_APPEND.
+	pos, serr := f.File.Seek(0, io.SeekCurrent)
+	if serr != nil {
+		return n, serr
+	}
+
+	f.add(pos-int64(n), p[:n])
+	return n, err
+}
+
+func (f *verifyingFile) Truncate(size int64) error {
+	if err := f.File.Truncate(size); err != nil {
+		return err
+	}
+
+	f.cut(size, -1)
+	return nil
+}
+
+// Sync commits the file to stable storage, and verifies the bytes written
+// since the last verification.
+func (f *verifyingFile) Sync() error {
+	if err := f.File.Sync(); err != nil {
+		return err
+	}
+
+	return f.verify()
+}
+
+// Close verifies the bytes written since the last verification and closes
+// the file, the file is closed even if the verification fails.
+func (f *verifyingFile) Close() error {
+	err := f.verify()
+	if cerr := f.File.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (f *verifyingFile) verify() error {
+	for _, e := range f.written {
+		got := make([]byte, len(e.data))
+		n, err := f.File.ReadAt(got, e.off)
+		if n == len(got) {
+			err = nil
+		}
+
+		if err == io.EOF {
+			err = ErrWriteVerifyFailed
+		}
+
+		if err == nil && !bytes.Equal(got, e.data) {
+			err = ErrWriteVerifyFailed
+		}
+
+		if err != nil {
+			return &os.PathError{Op: "verify", Path: f.Name(), Err: err}
+		}
+	}
+
+	f.written = nil
+	return nil
+}
+
+// add records the bytes written at off, replacing the ones already recorded
+// in the same range.
+func (f *verifyingFile) add(off int64, p []byte) {
+	f.cut(off, off+int64(len(p)))
+	f.written = append(f.written, extent{off: off, data: append([]byte(nil), p...)})
+}
+
+// cut removes the range from start to end of the bytes recorded, to the end
+// if end is -1.
+func (f *verifyingFile) cut(start, end int64) {
+	var kept []extent
+	for _, e := range f.written {
+		eEnd := e.off + int64(len(e.data))
+		if eEnd <= start || (end != -1 && e.off >= end) {
+			kept = append(kept, e)
+			continue
+		}
+
+		if e.off < start {
+			kept = append(kept, extent{off: e.off, data: e.data[:start-e.off]})
+		}
+
+		if end != -1 && eEnd > end {
+			kept = append(kept, extent{off: end, data: e.data[end-e.off:]})
+		}
+	}
+
+	f.written = kept
+}
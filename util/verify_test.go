@@ -0,0 +1,83 @@
+package util_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestVerifyingFile(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vf := util.NewVerifyingFile(f)
+	for _, s := range []string{"foo", "bar", "qux"} {
+		if _, err := vf.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := vf.Seek(2, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vf.Write([]byte("XY")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vf.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if _, err := vf.Write([]byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vf.Truncate(5); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vf.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	assertFile(t, fs, "foo", "foXYb")
+}
+
+func TestVerifyingFileMismatch(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.OpenFile("foo", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vf := util.NewVerifyingFile(f)
+	if _, err := vf.Write([]byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	// another writer corrupting the content behind the back of vf.
+	if err := util.WriteFile(fs, "foo", []byte("fox"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = vf.Close()
+	if !errors.Is(err, util.ErrWriteVerifyFailed) {
+		t.Errorf("Close = %v, want ErrWriteVerifyFailed", err)
+	}
+
+	if _, ok := err.(*os.PathError); !ok {
+		t.Errorf("Close = %T, want *os.PathError", err)
+	}
+
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Error("the file wasn't closed on a failed verification")
+	}
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// Walk walks the file tree rooted at root, calling walkFn for each file or
+// directory in the tree, including root, as filepath.Walk does. The files are
+// walked in lexical order, the directories before their content, and the
+// paths given to walkFn are joined with fs.Join. If walkFn returns
+// filepath.SkipDir on a directory, its content is skipped.
+//
+// Walk doesn't follow the symlinks, the files are read with Lstat.
+func Walk(fs billy.Filesystem, root string, walkFn filepath.WalkFunc) error {
+	info, err := fs.Lstat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walk(fs, root, info, walkFn)
+	}
+
+	if err == filepath.SkipDir {
+		return nil
+	}
+
+	return err
+}
+
+// walk recursively descends path, calling walkFn, adapted from
+// https://golang.org/src/path/filepath/path.go
+func walk(fs billy.Filesystem, path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+
+	names, err := readdirnames(fs, path)
+	err1 := walkFn(path, info, err)
+	// If err != nil, walk can't walk into this directory. err1 != nil means
+	// walkFn wants walk to skip this directory or stop walking. In both cases
+	// walk returns whatever walkFn returned, SkipDir being handled by the
+	// caller.
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		filename := fs.Join(path, name)
+		fileInfo, err := fs.Lstat(filename)
+		if err != nil {
+			if err := walkFn(filename, fileInfo, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+
+			continue
+		}
+
+		err = walk(fs, filename, fileInfo, walkFn)
+		if err != nil && (!fileInfo.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package util_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestWalk(t *testing.T) {
+	fs := memfs.New()
+	for _, name := range []string{"foo/b", "foo/a/qux", "foo/c/baz", "bar"} {
+		if err := util.WriteFile(fs, name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fs.Symlink("foo", "foo/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	var walked []string
+	err := util.Walk(fs, "foo", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Name() != filepath.Base(path) {
+			t.Errorf("%s: got info of %s", path, info.Name())
+		}
+
+		walked = append(walked, path)
+		if path == fs.Join("foo", "c") {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"foo",
+		fs.Join("foo", "a"),
+		fs.Join("foo", "a", "qux"),
+		fs.Join("foo", "b"),
+		fs.Join("foo", "c"),
+		fs.Join("foo", "link"),
+	}
+
+	if !reflect.DeepEqual(walked, want) {
+		t.Errorf("walked %q, want %q", walked, want)
+	}
+}
+
+func TestWalkErrors(t *testing.T) {
+	fs := memfs.New()
+	if err := util.WriteFile(fs, "foo/bar", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := util.Walk(fs, "qux", func(path string, info os.FileInfo, err error) error {
+		if info != nil {
+			t.Errorf("%s: got info of a missing file", path)
+		}
+
+		return err
+	})
+
+	if !os.IsNotExist(err) {
+		t.Errorf("Walk of a missing root: %v, want not exist", err)
+	}
+
+	errStop := errors.New("stop")
+	var walked int
+	err = util.Walk(fs, "foo", func(path string, info os.FileInfo, err error) error {
+		walked++
+		if !info.IsDir() {
+			return errStop
+		}
+
+		return nil
+	})
+
+	if err != errStop || walked != 2 {
+		t.Errorf("Walk stopped with %v after %d files, want %v after 2", err, walked, errStop)
+	}
+
+	err = util.Walk(fs, "foo", func(path string, info os.FileInfo, err error) error {
+		return filepath.SkipDir
+	})
+
+	if err != nil {
+		t.Errorf("Walk skipping the root: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package util_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestWriteBuffers(t *testing.T) {
+	testWriteBuffers(t, memfs.New())
+}
+
+func TestWriteBuffersOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "billy-write-buffers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testWriteBuffers(t, osfs.New(dir))
+}
+
+func TestWriteBuffersFallback(t *testing.T) {
+	fs := memfs.New()
+	f, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := util.WriteBuffers(plainFile{f}, [][]byte{[]byte("foo"), []byte("bar")})
+	if n != 6 || err != nil {
+		t.Errorf("WriteBuffers() = %d, %v, want 6, nil", n, err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, fs, "foo", "foobar")
+}
+
+func testWriteBuffers(t *testing.T, fs billy.Filesystem) {
+	f, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bufs := [][]byte{[]byte("header"), nil, []byte("body"), []byte("trailer")}
+	n, err := util.WriteBuffers(f, bufs)
+	if n != 17 || err != nil {
+		t.Errorf("WriteBuffers() = %d, %v, want 17, nil", n, err)
+	}
+
+	if string(bufs[0]) != "header" {
+		t.Errorf("WriteBuffers() modified the given buffers")
+	}
+
+	if _, err := f.Write([]byte("!")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, fs, "foo", "headerbodytrailer!")
+}
+
+// plainFile hides any optional interface implemented by the wrapped file.
+type plainFile struct {
+	billy.File
+}
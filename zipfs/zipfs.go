@@ -0,0 +1,425 @@
+// Package zipfs provides a read-only billy filesystem presenting the content
+// of a zip archive, without extracting it.
+package zipfs // import "github.com/go-git/go-billy/v5/zipfs"
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/helper/chroot"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+const separator = filepath.Separator
+
+var (
+	errIsDir   = errors.New("is a directory")
+	errNotDir  = errors.New("not a directory")
+	errNotLink = errors.New("not a link")
+)
+
+// Zip is a read-only filesystem presenting the content of a zip archive. The
+// directories holding the files are presented even if the archive doesn't
+// list them. The names escaping the root of the archive, with "..", are left
+// out.
+//
+// The symlinks stored in the archive are presented as such, and read with
+// Readlink, but they aren't followed. The files are read from the archive as
+// needed, the compressed ones are decompressed as they are read, seeking
+// backward in them decompressing them again from the start.
+type Zip struct {
+	r        io.ReaderAt
+	entries  map[string]*entry
+	children map[string][]string
+}
+
+// entry is a file or a directory of the archive, file is nil for the
+// directories not listed in the archive.
+type entry struct {
+	name string
+	dir  bool
+	file *zip.File
+}
+
+// New returns a new read-only filesystem with the content of the zip archive
+// read from r, of the given size.
+func New(r io.ReaderAt, size int64) (billy.Filesystem, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &Zip{
+		r:        r,
+		entries:  map[string]*entry{"": {dir: true}},
+		children: make(map[string][]string),
+	}
+
+	for _, f := range zr.File {
+		fs.add(f)
+	}
+
+	for _, names := range fs.children {
+		sort.Strings(names)
+	}
+
+	return chroot.New(fs, string(separator)), nil
+}
+
+// add adds the file of the archive, and its parent directories if missing.
+func (fs *Zip) add(f *zip.File) {
+	name := strings.TrimPrefix(path.Clean(f.Name), "/")
+	if name == "." || name == "" || name == ".." || strings.HasPrefix(name, "../") {
+		return
+	}
+
+	p := filepath.FromSlash(name)
+	isDir := strings.HasSuffix(f.Name, "/") || f.Mode().IsDir()
+	if e, ok := fs.entries[p]; ok {
+		if e.dir && isDir {
+			e.file = f
+		}
+
+		return
+	}
+
+	fs.addParents(p)
+	fs.entries[p] = &entry{name: p, dir: isDir, file: f}
+	fs.addChild(p)
+}
+
+func (fs *Zip) addParents(p string) {
+	dir := filepath.Dir(p)
+	if dir == "." {
+		return
+	}
+
+	if e, ok := fs.entries[dir]; ok {
+		e.dir = true
+		return
+	}
+
+	fs.addParents(dir)
+	fs.entries[dir] = &entry{name: dir, dir: true}
+	fs.addChild(dir)
+}
+
+func (fs *Zip) addChild(p string) {
+	dir := filepath.Dir(p)
+	if dir == "." {
+		dir = ""
+	}
+
+	fs.children[dir] = append(fs.children[dir], filepath.Base(p))
+}
+
+func (fs *Zip) Create(filename string) (billy.File, error) {
+	return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Zip) Open(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (fs *Zip) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: billy.ErrReadOnly}
+	}
+
+	e, err := fs.lookup(filename)
+	if err == nil && e.dir {
+		err = errIsDir
+	}
+
+	var r content
+	if err == nil {
+		r, err = fs.open(e.file)
+	}
+
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: err}
+	}
+
+	return &file{name: filename, content: r}, nil
+}
+
+// content is the content of a file, read from the archive or decompressed.
+type content interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+}
+
+// open returns the content of the file, read straight from the archive if
+// stored, decompressed as it is read otherwise. The decompressed content
+// can't exceed the size recorded in the archive, f.Open failing with
+// zip.ErrFormat past it.
+func (fs *Zip) open(f *zip.File) (content, error) {
+	if f.Method == zip.Store {
+		off, err := f.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+
+		return io.NewSectionReader(fs.r, off, int64(f.UncompressedSize64)), nil
+	}
+
+	return util.NewStreamReader(f.Open, int64(f.UncompressedSize64)), nil
+}
+
+func (fs *Zip) Stat(filename string) (os.FileInfo, error) {
+	return fs.stat("stat", filename)
+}
+
+// Lstat returns the FileInfo of the file, as Stat, the symlinks not being
+// followed.
+func (fs *Zip) Lstat(filename string) (os.FileInfo, error) {
+	return fs.stat("lstat", filename)
+}
+
+func (fs *Zip) stat(op, filename string) (os.FileInfo, error) {
+	e, err := fs.lookup(filename)
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: filename, Err: err}
+	}
+
+	return e.fileInfo(), nil
+}
+
+func (fs *Zip) ReadDir(path string) ([]os.FileInfo, error) {
+	e, err := fs.lookup(path)
+	if err == nil && !e.dir {
+		err = errNotDir
+	}
+
+	if err != nil {
+		return nil, &os.PathError{Op: "readdirent", Path: path, Err: err}
+	}
+
+	names := fs.children[e.name]
+	fis := make([]os.FileInfo, 0, len(names))
+	for _, name := range names {
+		fis = append(fis, fs.entries[filepath.Join(e.name, name)].fileInfo())
+	}
+
+	return fis, nil
+}
+
+func (fs *Zip) Readlink(link string) (string, error) {
+	e, err := fs.lookup(link)
+	if err == nil && (e.file == nil || e.file.Mode()&os.ModeSymlink == 0) {
+		err = errNotLink
+	}
+
+	var r content
+	if err == nil {
+		r, err = fs.open(e.file)
+	}
+
+	var target []byte
+	if err == nil {
+		target, err = ioutil.ReadAll(r)
+		closeContent(r)
+	}
+
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: link, Err: err}
+	}
+
+	return string(target), nil
+}
+
+// lookup returns the entry at the given path, the root being "".
+func (fs *Zip) lookup(path string) (*entry, error) {
+	p := clean(path)
+	if e, ok := fs.entries[p]; ok {
+		return e, nil
+	}
+
+	for dir := filepath.Dir(p); dir != "."; dir = filepath.Dir(dir) {
+		if e, ok := fs.entries[dir]; ok && !e.dir {
+			return nil, errNotDir
+		}
+	}
+
+	return nil, os.ErrNotExist
+}
+
+func (fs *Zip) Rename(from, to string) error {
+	return &os.LinkError{Op: "rename", Old: from, New: to, Err: billy.ErrReadOnly}
+}
+
+func (fs *Zip) Remove(filename string) error {
+	return &os.PathError{Op: "remove", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Zip) MkdirAll(filename string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: filename, Err: billy.ErrReadOnly}
+}
+
+func (fs *Zip) TempFile(dir, prefix string) (billy.File, error) {
+	return nil, &os.PathError{Op: "createtemp", Path: dir, Err: billy.ErrReadOnly}
+}
+
+func (fs *Zip) Symlink(target, link string) error {
+	return &os.LinkError{Op: "symlink", Old: target, New: link, Err: billy.ErrReadOnly}
+}
+
+func (fs *Zip) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+// Capabilities implements the Capable interface.
+func (fs *Zip) Capabilities() billy.Capability {
+	return billy.ReadCapability |
+		billy.SeekCapability
+}
+
+func (e *entry) fileInfo() os.FileInfo {
+	fi := &fileInfo{name: name(e.name), mode: os.ModeDir | 0555}
+	if e.file == nil {
+		return fi
+	}
+
+	fi.modTime = e.file.Modified
+	if !e.dir {
+		fi.mode = e.file.Mode()
+		fi.size = int64(e.file.UncompressedSize64)
+	}
+
+	return fi
+}
+
+type file struct {
+	content
+	name     string
+	isClosed bool
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Read(b []byte) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.content.Read(b)
+}
+
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.content.ReadAt(b, off)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.isClosed {
+		return 0, os.ErrClosed
+	}
+
+	return f.content.Seek(offset, whence)
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: billy.ErrReadOnly}
+}
+
+func (f *file) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.name, Err: billy.ErrReadOnly}
+}
+
+// Sync is a no-op, the file being read-only.
+func (f *file) Sync() error {
+	return nil
+}
+
+func (f *file) Close() error {
+	if f.isClosed {
+		return os.ErrClosed
+	}
+
+	f.isClosed = true
+	return closeContent(f.content)
+}
+
+// closeContent closes the content if it holds a decompressed stream.
+func closeContent(r content) error {
+	if c, ok := r.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// Lock is a no-op in zipfs.
+func (f *file) Lock() error {
+	return nil
+}
+
+// Unlock is a no-op in zipfs.
+func (f *file) Unlock() error {
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.mode.IsDir()
+}
+
+func (*fileInfo) Sys() interface{} {
+	return nil
+}
+
+// clean returns the path relative to the root, the root being "".
+func clean(path string) string {
+	path = filepath.Clean(filepath.FromSlash(path))
+	path = strings.TrimPrefix(path, string(separator))
+	if path == "." {
+		return ""
+	}
+
+	return path
+}
+
+func name(path string) string {
+	if path == "" {
+		return string(separator)
+	}
+
+	return filepath.Base(path)
+}
@@ -0,0 +1,209 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ZipSuite struct {
+	FS billy.Filesystem
+}
+
+var _ = Suite(&ZipSuite{})
+
+func (s *ZipSuite) SetUpTest(c *C) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name    string
+		mode    os.FileMode
+		method  uint16
+		content string
+	}{
+		{"README", 0644, zip.Deflate, "hello world"},
+		{"cmd/main.go", 0755, zip.Store, "package main"},
+		{"docs/", os.ModeDir | 0755, zip.Store, ""},
+		{"link", os.ModeSymlink | 0777, zip.Store, "README"},
+		{"../evil", 0644, zip.Store, "evil"},
+	} {
+		h := &zip.FileHeader{Name: f.name, Method: f.method}
+		h.SetMode(f.mode)
+
+		fw, err := w.CreateHeader(h)
+		c.Assert(err, IsNil)
+		_, err = fw.Write([]byte(f.content))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(w.Close(), IsNil)
+
+	fs, err := New(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	s.FS = fs
+}
+
+func (s *ZipSuite) TestOpen(c *C) {
+	s.assertContent(c, "README", "hello world")
+	s.assertContent(c, "/cmd/main.go", "package main")
+	s.assertContent(c, "cmd/../README", "hello world")
+
+	_, err := s.FS.Open("missing")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = s.FS.Open("evil")
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	_, err = s.FS.Open("README/foo")
+	c.Assert(err, NotNil)
+
+	_, err = s.FS.Open("cmd")
+	c.Assert(err, NotNil)
+}
+
+func (s *ZipSuite) TestOpenLongerThanRecorded(c *C) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	fw, err := w.Create("bomb")
+	c.Assert(err, IsNil)
+	_, err = fw.Write(bytes.Repeat([]byte("0"), 1024))
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+
+	// records a size of 4 bytes in the central directory
+	data := buf.Bytes()
+	i := bytes.LastIndex(data, []byte("PK\x01\x02"))
+	c.Assert(i, Not(Equals), -1)
+	binary.LittleEndian.PutUint32(data[i+24:], 4)
+
+	fs, err := New(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+
+	fi, err := fs.Stat("bomb")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Size(), Equals, int64(4))
+
+	f, err := fs.Open("bomb")
+	c.Assert(err, IsNil)
+	_, err = ioutil.ReadAll(f)
+	c.Assert(err, Equals, zip.ErrFormat)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *ZipSuite) TestSeek(c *C) {
+	for _, name := range []string{"README", "cmd/main.go"} {
+		f, err := s.FS.Open(name)
+		c.Assert(err, IsNil)
+
+		_, err = f.Seek(-4, io.SeekEnd)
+		c.Assert(err, IsNil)
+
+		content, err := ioutil.ReadAll(f)
+		c.Assert(err, IsNil)
+		c.Assert(content, HasLen, 4)
+
+		buf := make([]byte, 3)
+		_, err = f.ReadAt(buf, 1)
+		c.Assert(err, IsNil)
+		c.Assert(string(buf), Matches, "ell|ack")
+
+		c.Assert(f.Close(), IsNil)
+	}
+}
+
+func (s *ZipSuite) TestStat(c *C) {
+	fi, err := s.FS.Stat("README")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Name(), Equals, "README")
+	c.Assert(fi.Size(), Equals, int64(11))
+	c.Assert(fi.Mode(), Equals, os.FileMode(0644))
+
+	fi, err = s.FS.Stat("cmd")
+	c.Assert(err, IsNil)
+	c.Assert(fi.IsDir(), Equals, true)
+
+	fi, err = s.FS.Lstat("link")
+	c.Assert(err, IsNil)
+	c.Assert(fi.Mode()&os.ModeSymlink, Not(Equals), os.FileMode(0))
+
+	target, err := s.FS.Readlink("link")
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "README")
+
+	_, err = s.FS.Readlink("README")
+	c.Assert(err, NotNil)
+}
+
+func (s *ZipSuite) TestReadDir(c *C) {
+	fis, err := s.FS.ReadDir("/")
+	c.Assert(err, IsNil)
+
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+
+	c.Assert(names, DeepEquals, []string{"README", "cmd", "docs", "link"})
+	c.Assert(fis[1].IsDir(), Equals, true)
+	c.Assert(fis[2].IsDir(), Equals, true)
+
+	fis, err = s.FS.ReadDir("cmd")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 1)
+	c.Assert(fis[0].Name(), Equals, "main.go")
+	c.Assert(fis[0].Size(), Equals, int64(12))
+
+	fis, err = s.FS.ReadDir("docs")
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 0)
+
+	_, err = s.FS.ReadDir("README")
+	c.Assert(err, NotNil)
+
+	_, err = s.FS.ReadDir("missing")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *ZipSuite) TestRootAndJoin(c *C) {
+	c.Assert(s.FS.Root(), Equals, string(separator))
+	c.Assert(s.FS.Join("cmd", "main.go"), Equals, "cmd"+string(separator)+"main.go")
+}
+
+func (s *ZipSuite) TestReadOnly(c *C) {
+	_, err := s.FS.Create("foo")
+	c.Assert(err, NotNil)
+
+	_, err = s.FS.OpenFile("README", os.O_RDWR, 0)
+	c.Assert(err, NotNil)
+
+	c.Assert(s.FS.Remove("README"), NotNil)
+	c.Assert(s.FS.Rename("README", "foo"), NotNil)
+	c.Assert(s.FS.MkdirAll("foo", 0755), NotNil)
+	c.Assert(s.FS.Symlink("README", "foo"), NotNil)
+
+	f, err := s.FS.Open("README")
+	c.Assert(err, IsNil)
+	_, err = f.Write([]byte("foo"))
+	c.Assert(err, NotNil)
+	c.Assert(f.Close(), IsNil)
+}
+
+func (s *ZipSuite) assertContent(c *C, filename, expected string) {
+	f, err := s.FS.Open(filename)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadAll(f)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, expected)
+	c.Assert(f.Close(), IsNil)
+}